@@ -0,0 +1,162 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go-inventory/objects"
+	"go-inventory/outbox"
+	"go-inventory/util/logger"
+)
+
+// Alert a stock whose availability fell below one of its thresholds
+type Alert struct {
+	TenantID string `json:"tenant_id"`
+	StockID  string `json:"stock_id"`
+	Name     string `json:"name"`
+	// objects.LevelReorderPoint or objects.LevelSafetyStock
+	Level        string    `json:"level"`
+	Availability int       `json:"availability"`
+	Threshold    int       `json:"threshold"`
+	RaisedOn     time.Time `json:"raised_on"`
+}
+
+// Notifier tells people about low stock alerts
+type Notifier interface {
+	Notify(ctx context.Context, a *Alert) error
+}
+
+// Topics outbox topics raising an alert
+var Topics = []string{objects.EventStockBelowReorderPoint, objects.EventStockBelowSafetyStock}
+
+// FromEvent returns the alert raised by e, nil when e is no low stock event
+func FromEvent(e *objects.StockEvent) *Alert {
+	if e.Stock == nil {
+		return nil
+	}
+	a := &Alert{
+		TenantID:     e.TenantID,
+		StockID:      e.StockID,
+		Name:         e.Stock.Name,
+		Availability: e.Stock.Availability,
+		RaisedOn:     e.CreatedOn,
+	}
+	switch e.Type {
+	case objects.EventStockBelowReorderPoint:
+		a.Level, a.Threshold = objects.LevelReorderPoint, e.Stock.ReorderPoint
+	case objects.EventStockBelowSafetyStock:
+		a.Level, a.Threshold = objects.LevelSafetyStock, e.Stock.SafetyStock
+	default:
+		return nil
+	}
+	return a
+}
+
+// Handler returns an outbox handler notifying n of the alerts raised by the messages
+func Handler(n Notifier) outbox.Handler {
+	return func(ctx context.Context, msg *objects.OutboxMessage) error {
+		e := &objects.StockEvent{}
+		if err := json.Unmarshal([]byte(msg.Payload), e); err != nil {
+			return err
+		}
+		e.TenantID = msg.TenantID
+		a := FromEvent(e)
+		if a == nil {
+			return nil
+		}
+		return n.Notify(ctx, a)
+	}
+}
+
+// Subscribe notifies n of the alerts published by pub
+func Subscribe(pub *outbox.InProcess, n Notifier) {
+	for _, topic := range Topics {
+		pub.Subscribe(topic, Handler(n))
+	}
+}
+
+// LogNotifier writes alerts to a log
+type LogNotifier struct {
+	log *logger.Logger
+}
+
+// NewLogNotifier returns a Notifier writing to log
+func NewLogNotifier(log *logger.Logger) *LogNotifier {
+	return &LogNotifier{log: log}
+}
+
+// Notify logs a
+func (n *LogNotifier) Notify(_ context.Context, a *Alert) error {
+	n.log.Warn().
+		Str("tenant_id", a.TenantID).
+		Str("stock_id", a.StockID).
+		Str("level", a.Level).
+		Int("availability", a.Availability).
+		Int("threshold", a.Threshold).
+		Msg("low stock")
+	return nil
+}
+
+// WebhookNotifier posts alerts as json to a url, e.g a chat webhook
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier returns a Notifier posting to url
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Notify posts a, failing on non 2xx answers
+func (n *WebhookNotifier) Notify(ctx context.Context, a *Alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("alert webhook responded %s", res.Status)
+	}
+	return nil
+}
+
+// Memory keeps alerts in memory, for tests
+type Memory struct {
+	mu     sync.Mutex
+	alerts []*Alert
+}
+
+// NewMemoryNotifier returns an empty Memory
+func NewMemoryNotifier() *Memory {
+	return &Memory{}
+}
+
+// Notify keeps a
+func (m *Memory) Notify(_ context.Context, a *Alert) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.alerts = append(m.alerts, a)
+	return nil
+}
+
+// Alerts returns the alerts notified so far
+func (m *Memory) Alerts() []*Alert {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Alert(nil), m.alerts...)
+}
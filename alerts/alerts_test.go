@@ -0,0 +1,64 @@
+package alerts
+
+import (
+	"context"
+	"testing"
+
+	"go-inventory/objects"
+	"go-inventory/outbox"
+	"go-inventory/store"
+	"go-inventory/tenant"
+	"go-inventory/util/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlerts(t *testing.T) {
+	stores := store.NewMemoryStores()
+	ctx := tenant.NewContext(context.Background(), "acme")
+	pub := outbox.NewInProcess()
+	n := NewMemoryNotifier()
+	Subscribe(pub, n)
+	relay := outbox.NewRelay(stores.Outbox, pub, outbox.Config{}, logger.New(false))
+
+	evt := &objects.Stock{Name: "a", Price: 1, Availability: 20, ReorderPoint: 10, SafetyStock: 5}
+	assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: evt}))
+	update := func(availability int) {
+		assert.Nil(t, stores.Stocks.UpdateDetails(ctx, &objects.UpdateDetailsRequest{
+			ID: evt.ID, Name: "a", Price: 1, Availability: availability, ReorderPoint: 10, SafetyStock: 5,
+		}))
+	}
+
+	tests := []struct {
+		name         string
+		availability int
+		levels       []string
+	}{
+		{"Above", 15, nil},
+		{"Below reorder point", 9, []string{objects.LevelReorderPoint}},
+		{"Still below", 8, nil},
+		{"Below safety stock", 4, []string{objects.LevelSafetyStock}},
+		{"Back above", 12, nil},
+		{"Both at once", 0, []string{objects.LevelReorderPoint, objects.LevelSafetyStock}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(n.Alerts())
+			update(tt.availability)
+			_, err := relay.Relay(ctx)
+			assert.Nil(t, err)
+			var levels []string
+			for _, a := range n.Alerts()[before:] {
+				assert.Equal(t, "acme", a.TenantID)
+				assert.Equal(t, evt.ID, a.StockID)
+				assert.Equal(t, tt.availability, a.Availability)
+				levels = append(levels, a.Level)
+			}
+			assert.Equal(t, tt.levels, levels)
+		})
+	}
+
+	low, err := stores.Stocks.ListLow(ctx, &objects.ListLowRequest{Level: objects.LevelSafetyStock})
+	assert.Nil(t, err)
+	assert.Len(t, low, 1)
+}
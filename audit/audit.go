@@ -0,0 +1,78 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go-inventory/util/logger"
+)
+
+// Outcomes of an audited action
+const (
+	OutcomeAllowed = "allowed"
+	OutcomeDenied  = "denied"
+)
+
+// Entry a single record of the audit trail
+type Entry struct {
+	Time time.Time `json:"time"`
+	// principal id, empty for anonymous callers
+	Principal string `json:"principal"`
+	// action attempted, e.g "UpdateDetails"
+	Action string `json:"action"`
+	// resource the action applies to, e.g a stock id
+	Resource string `json:"resource,omitempty"`
+	Outcome  string `json:"outcome"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Recorder writes entries to the audit trail
+type Recorder interface {
+	Record(ctx context.Context, e Entry)
+}
+
+type logRecorder struct {
+	log *logger.Logger
+}
+
+// NewLogRecorder returns a Recorder writing entries as structured log lines
+func NewLogRecorder(log *logger.Logger) Recorder {
+	return &logRecorder{log: log}
+}
+
+func (l *logRecorder) Record(_ context.Context, e Entry) {
+	l.log.Info().
+		Str("type", "audit").
+		Time("time", e.Time).
+		Str("principal", e.Principal).
+		Str("action", e.Action).
+		Str("resource", e.Resource).
+		Str("outcome", e.Outcome).
+		Str("reason", e.Reason).
+		Msg("audit")
+}
+
+// Memory keeps entries in memory, used in tests
+type Memory struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// NewMemoryRecorder returns an empty in memory Recorder
+func NewMemoryRecorder() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Record(_ context.Context, e Entry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, e)
+}
+
+// Entries returns a copy of the recorded entries
+func (m *Memory) Entries() []Entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Entry(nil), m.entries...)
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// APIKeyHeader header holding the api key
+const APIKeyHeader = "X-API-Key"
+
+// MethodAPIKey principal method for api keys
+const MethodAPIKey = "api_key"
+
+// APIKey a static api key, only the hash of the key is kept
+type APIKey struct {
+	// Name of the client owning the key, used as principal id
+	Name string `json:"name"`
+	// Hex encoded sha256 of the key
+	Hash string `json:"hash"`
+	// Roles granted to the key
+	Roles []string `json:"roles"`
+	// Tenant the key is bound to, optional, AnyTenant lets it pick one
+	Tenant string `json:"tenant"`
+}
+
+type apiKeys struct {
+	keys []APIKey
+}
+
+// NewAPIKeyAuthenticator authenticate requests by the `X-API-Key` header
+func NewAPIKeyAuthenticator(keys []APIKey) Authenticator {
+	return &apiKeys{keys: keys}
+}
+
+// LoadAPIKeys reads a json array of APIKey from path
+func LoadAPIKeys(path string) ([]APIKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys := []APIKey{}
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("invalid api keys file %s: %w", path, err)
+	}
+	for _, k := range keys {
+		if b, err := hex.DecodeString(k.Hash); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("invalid hash for api key %q", k.Name)
+		}
+	}
+	return keys, nil
+}
+
+// HashAPIKey returns the hash to store for key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (a *apiKeys) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		return nil, nil
+	}
+	hash := HashAPIKey(key)
+	for _, k := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(strings.ToLower(k.Hash))) == 1 {
+			return &Principal{ID: k.Name, Method: MethodAPIKey, Roles: k.Roles, Tenant: k.Tenant}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown api key")
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"go-inventory/errors"
+
+	"github.com/gorilla/mux"
+)
+
+// Principal identity of the caller of a request
+type Principal struct {
+	// Subject, e.g the API key name or the JWT `sub` claim
+	ID string `json:"id"`
+	// Method used to authenticate, e.g "api_key" or "jwt"
+	Method string `json:"method"`
+	// Roles granted to the caller, e.g "warehouse"
+	Roles []string `json:"roles"`
+	// Tenant the caller is bound to, the default one when empty, AnyTenant when
+	// it may pick one
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// AnyTenant tenant of the principals allowed to switch tenants, e.g back office tools
+const AnyTenant = "*"
+
+// Authenticator resolves the principal of a request.
+// It returns (nil, nil) when the request holds no credentials it understands,
+// and an error when it does but they are not valid.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying p
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx, if any
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// Middleware rejects requests that none of the authenticators accept and
+// attaches the resolved principal to the request context otherwise.
+func Middleware(authenticators ...Authenticator) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return Resolve(authenticators...)(Require(next))
+	}
+}
+
+type failureKey struct{}
+
+// Resolve attaches the principal of the first authenticator accepting the request to
+// its context. Requests without one are passed on, for Require to reject them once
+// e.g rate limited.
+func Resolve(authenticators ...Authenticator) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, a := range authenticators {
+				p, err := a.Authenticate(r)
+				if err != nil {
+					next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), failureKey{}, errors.ErrInvalidCredentials)))
+					return
+				}
+				if p != nil {
+					next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), p)))
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Require rejects the requests Resolve attached no principal to
+func Require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := FromContext(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err, ok := r.Context().Value(failureKey{}).(*errors.Error); ok {
+			unauthorized(w, err)
+			return
+		}
+		unauthorized(w, errors.ErrUnauthorized)
+	})
+}
+
+func unauthorized(w http.ResponseWriter, err *errors.Error) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="go-inventory"`)
+	err.Write(w)
+}
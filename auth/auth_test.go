@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-inventory/errors"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"n":   base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadJWKS(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(method jwt.SigningMethod, key interface{}, sub string, exp time.Duration) string {
+		tok := jwt.NewWithClaims(method, jwt.RegisteredClaims{
+			Subject:   sub,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(exp)),
+		})
+		tok.Header["kid"] = "k1"
+		s, err := tok.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	router := mux.NewRouter()
+	router.Use(Middleware(
+		NewAPIKeyAuthenticator([]APIKey{{Name: "cart", Hash: HashAPIKey("k3y")}}),
+		NewJWTAuthenticator(JWTConfig{Secret: secret, Keys: keys}),
+	))
+	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		p, _ := FromContext(r.Context())
+		_, _ = w.Write([]byte(p.Method + ":" + p.ID))
+	})
+
+	tests := []struct {
+		name    string
+		header  string
+		value   string
+		code    int
+		body    string
+		message string
+	}{
+		{
+			name:    "Anonymous",
+			code:    http.StatusUnauthorized,
+			message: errors.ErrUnauthorized.Message,
+		},
+		{
+			name:   "APIKey",
+			header: APIKeyHeader,
+			value:  "k3y",
+			code:   http.StatusOK,
+			body:   "api_key:cart",
+		},
+		{
+			name:    "WrongAPIKey",
+			header:  APIKeyHeader,
+			value:   "nope",
+			code:    http.StatusUnauthorized,
+			message: errors.ErrInvalidCredentials.Message,
+		},
+		{
+			name:   "HS256",
+			header: "Authorization",
+			value:  "Bearer " + sign(jwt.SigningMethodHS256, secret, "alice", time.Hour),
+			code:   http.StatusOK,
+			body:   "jwt:alice",
+		},
+		{
+			name:   "RS256",
+			header: "Authorization",
+			value:  "Bearer " + sign(jwt.SigningMethodRS256, rsaKey, "bob", time.Hour),
+			code:   http.StatusOK,
+			body:   "jwt:bob",
+		},
+		{
+			name:    "Expired",
+			header:  "Authorization",
+			value:   "Bearer " + sign(jwt.SigningMethodHS256, secret, "alice", -time.Hour),
+			code:    http.StatusUnauthorized,
+			message: errors.ErrInvalidCredentials.Message,
+		},
+		{
+			name:    "WrongSecret",
+			header:  "Authorization",
+			value:   "Bearer " + sign(jwt.SigningMethodHS256, []byte("other"), "alice", time.Hour),
+			code:    http.StatusUnauthorized,
+			message: errors.ErrInvalidCredentials.Message,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.code, w.Code)
+			if tt.message != "" {
+				got := &errors.Error{}
+				assert.Nil(t, json.Unmarshal(w.Body.Bytes(), got))
+				assert.Equal(t, tt.message, got.Message)
+				assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+			} else {
+				assert.Equal(t, tt.body, w.Body.String())
+			}
+		})
+	}
+}
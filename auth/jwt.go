@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// MethodJWT principal method for bearer tokens
+const MethodJWT = "jwt"
+
+// JWTConfig configuration of the bearer token authenticator
+type JWTConfig struct {
+	// shared secret for HS256 tokens
+	Secret []byte
+	// RS256 public keys by key id, see LoadJWKS
+	Keys map[string]*rsa.PublicKey
+	// expected `iss` claim, ignored when empty
+	Issuer string
+	// expected `aud` claim, ignored when empty
+	Audience string
+}
+
+// claims accepted in bearer tokens
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Roles  []string `json:"roles"`
+	Tenant string   `json:"tenant"`
+}
+
+type bearer struct {
+	cfg    JWTConfig
+	parser *jwt.Parser
+}
+
+// NewJWTAuthenticator authenticate requests by an `Authorization: Bearer` JWT
+func NewJWTAuthenticator(cfg JWTConfig) Authenticator {
+	return &bearer{
+		cfg:    cfg,
+		parser: jwt.NewParser(jwt.WithValidMethods([]string{"HS256", "RS256"})),
+	}
+}
+
+// LoadJWKS reads the RSA keys of the JSON Web Key Set at path
+func LoadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	set := struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}{}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid jwks file %s: %w", path, err)
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus for key %q: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent for key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+func (b *bearer) Authenticate(r *http.Request) (*Principal, error) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return nil, nil
+	}
+	claims := &tokenClaims{}
+	if _, err := b.parser.ParseWithClaims(strings.TrimPrefix(header, "Bearer "), claims, b.key); err != nil {
+		return nil, err
+	}
+	if b.cfg.Issuer != "" && !claims.VerifyIssuer(b.cfg.Issuer, true) {
+		return nil, fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if b.cfg.Audience != "" && !claims.VerifyAudience(b.cfg.Audience, true) {
+		return nil, fmt.Errorf("unexpected audience %v", claims.Audience)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("missing subject")
+	}
+	return &Principal{ID: claims.Subject, Method: MethodJWT, Roles: claims.Roles, Tenant: claims.Tenant}, nil
+}
+
+// key selects the verification key for token
+func (b *bearer) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case "HS256":
+		if len(b.cfg.Secret) == 0 {
+			return nil, fmt.Errorf("HS256 tokens are not accepted")
+		}
+		return b.cfg.Secret, nil
+	case "RS256":
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := b.cfg.Keys[kid]; ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+}
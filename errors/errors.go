@@ -2,6 +2,7 @@ package errors
 
 import (
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"net/http"
 )
@@ -9,53 +10,395 @@ import (
 var (
 	// ErrInternal HTTP 500
 	ErrInternal = &Error{
-		Code:    http.StatusInternalServerError,
-		Message: "Something went wrong",
+		Code:      http.StatusInternalServerError,
+		Message:   "Something went wrong",
+		ErrorCode: "internal",
 	}
 	// ErrUnprocessableEntity HTTP 422
 	ErrUnprocessableEntity = &Error{
-		Code:    http.StatusUnprocessableEntity,
-		Message: "Unprocessable Entity",
+		Code:      http.StatusUnprocessableEntity,
+		Message:   "Unprocessable Entity",
+		ErrorCode: "unprocessable_entity",
 	}
 	// ErrBadRequest HTTP 400
 	ErrBadRequest = &Error{
-		Code:    http.StatusBadRequest,
-		Message: "Error invalid argument",
+		Code:      http.StatusBadRequest,
+		Message:   "Error invalid argument",
+		ErrorCode: "bad_request",
 	}
 	// ErrStockNotFound HTTP 404
 	ErrStockNotFound = &Error{
-		Code:    http.StatusNotFound,
-		Message: "Event not found",
+		Code:      http.StatusNotFound,
+		Message:   "Event not found",
+		ErrorCode: "stock_not_found",
 	}
 	// ErrObjectIsRequired HTTP 400
 	ErrObjectIsRequired = &Error{
-		Code:    http.StatusBadRequest,
-		Message: "Request object should be provided",
+		Code:      http.StatusBadRequest,
+		Message:   "Request object should be provided",
+		ErrorCode: "object_required",
 	}
 	// ErrValidStockIDIsRequired HTTP 400
 	ErrValidStockIDIsRequired = &Error{
-		Code:    http.StatusBadRequest,
-		Message: "A valid event id is required",
+		Code:      http.StatusBadRequest,
+		Message:   "A valid event id is required",
+		ErrorCode: "invalid_stock_id",
+		Errors:    []FieldError{{Field: "id", Code: "invalid_stock_id"}},
 	}
-	ErrValidPriceIsRequired = &Error{
-		Code:    http.StatusBadRequest,
-		Message: "A valid price is required",
+	// ErrValidation HTTP 400, the violations are listed in Errors
+	ErrValidation = &Error{
+		Code:      http.StatusBadRequest,
+		Message:   "Request validation failed",
+		ErrorCode: "validation_failed",
 	}
-	ErrValidAvailibiltyIsRequired = &Error{
-		Code:    http.StatusBadRequest,
-		Message: "A valid avaibility is required",
+	// ErrRequestTooLarge HTTP 413
+	ErrRequestTooLarge = &Error{
+		Code:      http.StatusRequestEntityTooLarge,
+		Message:   "Request body is too large",
+		ErrorCode: "request_too_large",
+	}
+	// ErrUnsupportedMediaType HTTP 415
+	ErrUnsupportedMediaType = &Error{
+		Code:      http.StatusUnsupportedMediaType,
+		Message:   "Content-Type should be application/json",
+		ErrorCode: "unsupported_media_type",
+	}
+	// ErrMalformedJSON HTTP 400
+	ErrMalformedJSON = &Error{
+		Code:      http.StatusBadRequest,
+		Message:   "Request body is not valid JSON",
+		ErrorCode: "malformed_json",
+	}
+	// ErrBatchAborted HTTP 409, an item left untouched because another one of its atomic batch failed
+	ErrBatchAborted = &Error{
+		Code:      http.StatusConflict,
+		Message:   "Not applied, another item of the atomic batch failed",
+		ErrorCode: "batch_aborted",
+	}
+	// ErrInvalidEventID HTTP 400
+	ErrInvalidEventID = &Error{
+		Code:      http.StatusBadRequest,
+		Message:   "Last-Event-ID should be an event sequence number",
+		ErrorCode: "invalid_event_id",
+		Errors:    []FieldError{{Field: "Last-Event-ID", Code: "invalid_event_id"}},
+	}
+	// ErrWebhookNotFound HTTP 404
+	ErrWebhookNotFound = &Error{
+		Code:      http.StatusNotFound,
+		Message:   "Webhook not found",
+		ErrorCode: "webhook_not_found",
+	}
+	// ErrDeliveryNotFound HTTP 404
+	ErrDeliveryNotFound = &Error{
+		Code:      http.StatusNotFound,
+		Message:   "Webhook delivery not found",
+		ErrorCode: "delivery_not_found",
+	}
+	// ErrInvalidWebhookURL HTTP 400
+	ErrInvalidWebhookURL = &Error{
+		Code:      http.StatusBadRequest,
+		Message:   "A valid http or https url is required",
+		ErrorCode: "invalid_webhook_url",
+		Errors:    []FieldError{{Field: "url", Code: "invalid_webhook_url"}},
+	}
+	// ErrSupplierNotFound HTTP 404
+	ErrSupplierNotFound = &Error{
+		Code:      http.StatusNotFound,
+		Message:   "Supplier not found",
+		ErrorCode: "supplier_not_found",
+	}
+	// ErrPurchaseOrderNotFound HTTP 404
+	ErrPurchaseOrderNotFound = &Error{
+		Code:      http.StatusNotFound,
+		Message:   "Purchase order not found",
+		ErrorCode: "purchase_order_not_found",
+	}
+	// ErrInvalidTransition HTTP 409, the current status does not allow the operation
+	ErrInvalidTransition = &Error{
+		Code:      http.StatusConflict,
+		Message:   "Operation not allowed in the current status",
+		ErrorCode: "invalid_status_transition",
+	}
+	// ErrOverReceipt HTTP 422
+	ErrOverReceipt = &Error{
+		Code:      http.StatusUnprocessableEntity,
+		Message:   "Received quantity exceeds the outstanding quantity",
+		ErrorCode: "over_receipt",
+	}
+	// ErrSalesOrderNotFound HTTP 404
+	ErrSalesOrderNotFound = &Error{
+		Code:      http.StatusNotFound,
+		Message:   "Sales order not found",
+		ErrorCode: "sales_order_not_found",
+	}
+	// ErrAllocationFailed HTTP 409, the shortfall of each line is listed in Errors
+	ErrAllocationFailed = &Error{
+		Code:      http.StatusConflict,
+		Message:   "Some lines cannot be allocated, nothing was allocated",
+		ErrorCode: "allocation_failed",
+	}
+	// ErrReturnNotFound HTTP 404
+	ErrReturnNotFound = &Error{
+		Code:      http.StatusNotFound,
+		Message:   "Return not found",
+		ErrorCode: "return_not_found",
+	}
+	// ErrOverInspection HTTP 422
+	ErrOverInspection = &Error{
+		Code:      http.StatusUnprocessableEntity,
+		Message:   "Inspected quantity exceeds the uninspected quantity",
+		ErrorCode: "over_inspection",
+	}
+	// ErrLotNotFound HTTP 404
+	ErrLotNotFound = &Error{
+		Code:      http.StatusNotFound,
+		Message:   "Lot not found",
+		ErrorCode: "lot_not_found",
+	}
+	// ErrLotExists HTTP 409
+	ErrLotExists = &Error{
+		Code:      http.StatusConflict,
+		Message:   "The stock already has a lot with this number",
+		ErrorCode: "lot_exists",
+		Errors:    []FieldError{{Field: "lot_number", Code: "lot_exists"}},
+	}
+	// ErrSerialNotFound HTTP 404
+	ErrSerialNotFound = &Error{
+		Code:      http.StatusNotFound,
+		Message:   "Serial number not found",
+		ErrorCode: "serial_not_found",
+	}
+	// ErrSerialsRequired HTTP 422, the availability of a serialized stock
+	// only changes by receiving or selling serial numbers
+	ErrSerialsRequired = &Error{
+		Code:      http.StatusUnprocessableEntity,
+		Message:   "The availability of a serialized stock changes with serial numbers only",
+		ErrorCode: "serials_required",
+	}
+	// ErrSerialExists HTTP 409
+	ErrSerialExists = &Error{
+		Code:      http.StatusConflict,
+		Message:   "Serial number already received",
+		ErrorCode: "serial_exists",
+	}
+	// ErrProductNotFound HTTP 404
+	ErrProductNotFound = &Error{
+		Code:      http.StatusNotFound,
+		Message:   "Product not found",
+		ErrorCode: "product_not_found",
+	}
+	// ErrVariantExists HTTP 409
+	ErrVariantExists = &Error{
+		Code:      http.StatusConflict,
+		Message:   "The product already has a variant with these attributes",
+		ErrorCode: "variant_exists",
+	}
+	// ErrBundleNotFound HTTP 404
+	ErrBundleNotFound = &Error{
+		Code:      http.StatusNotFound,
+		Message:   "Bundle not found",
+		ErrorCode: "bundle_not_found",
+	}
+	// ErrSKUExists HTTP 409
+	ErrSKUExists = &Error{
+		Code:      http.StatusConflict,
+		Message:   "Another stock has this SKU",
+		ErrorCode: "sku_exists",
+		Errors:    []FieldError{{Field: "sku", Code: "sku_exists"}},
+	}
+	// ErrBarcodeExists HTTP 409
+	ErrBarcodeExists = &Error{
+		Code:      http.StatusConflict,
+		Message:   "Another stock has this barcode",
+		ErrorCode: "barcode_exists",
+		Errors:    []FieldError{{Field: "barcode", Code: "barcode_exists"}},
+	}
+	// ErrExternalIDNotFound HTTP 404
+	ErrExternalIDNotFound = &Error{
+		Code:      http.StatusNotFound,
+		Message:   "External identifier not found",
+		ErrorCode: "external_id_not_found",
+	}
+	// ErrCategoryNotFound HTTP 404
+	ErrCategoryNotFound = &Error{
+		Code:      http.StatusNotFound,
+		Message:   "Category not found",
+		ErrorCode: "category_not_found",
+	}
+	// ErrCategoryExists HTTP 409
+	ErrCategoryExists = &Error{
+		Code:      http.StatusConflict,
+		Message:   "The parent already has a category with this name",
+		ErrorCode: "category_exists",
+		Errors:    []FieldError{{Field: "name", Code: "category_exists"}},
+	}
+	// ErrCategoryNotEmpty HTTP 409
+	ErrCategoryNotEmpty = &Error{
+		Code:      http.StatusConflict,
+		Message:   "The category still has subcategories or stocks",
+		ErrorCode: "category_not_empty",
+	}
+	// ErrExternalIDExists HTTP 409
+	ErrExternalIDExists = &Error{
+		Code:      http.StatusConflict,
+		Message:   "The external identifier is already mapped to a stock",
+		ErrorCode: "external_id_exists",
+	}
+	// ErrCountNotFound HTTP 404
+	ErrCountNotFound = &Error{
+		Code:      http.StatusNotFound,
+		Message:   "Count session not found",
+		ErrorCode: "count_not_found",
+	}
+	// ErrStaleCount HTTP 409, units left the stock since the count sheet was made
+	// and its variance would make availability negative
+	ErrStaleCount = &Error{
+		Code:      http.StatusConflict,
+		Message:   "The availability changed too much since the count, recount the stock",
+		ErrorCode: "stale_count",
+	}
+	// ErrUnauthorized HTTP 401
+	ErrUnauthorized = &Error{
+		Code:      http.StatusUnauthorized,
+		Message:   "Authentication is required",
+		ErrorCode: "unauthorized",
+	}
+	// ErrInvalidCredentials HTTP 401
+	ErrInvalidCredentials = &Error{
+		Code:      http.StatusUnauthorized,
+		Message:   "Invalid or expired credentials",
+		ErrorCode: "invalid_credentials",
+	}
+	// ErrForbidden HTTP 403
+	ErrForbidden = &Error{
+		Code:      http.StatusForbidden,
+		Message:   "Permission denied",
+		ErrorCode: "forbidden",
+	}
+	// ErrInvalidTenant HTTP 400
+	ErrInvalidTenant = &Error{
+		Code:      http.StatusBadRequest,
+		Message:   "A valid tenant id is required",
+		ErrorCode: "invalid_tenant",
+	}
+	// ErrTenantMismatch HTTP 403
+	ErrTenantMismatch = &Error{
+		Code:      http.StatusForbidden,
+		Message:   "Tenant does not match the credentials",
+		ErrorCode: "tenant_mismatch",
+	}
+	// ErrTooManyRequests HTTP 429
+	ErrTooManyRequests = &Error{
+		Code:      http.StatusTooManyRequests,
+		Message:   "Too many requests, retry later",
+		ErrorCode: "rate_limited",
 	}
 	// ErrInvalidLimit HTTP 400
 	ErrInvalidLimit = &Error{
-		Code:    http.StatusBadRequest,
-		Message: "Limit should be an integral value",
+		Code:      http.StatusBadRequest,
+		Message:   "Limit should be an integral value",
+		ErrorCode: "invalid_limit",
+		Errors:    []FieldError{{Field: "limit", Code: "invalid_limit"}},
 	}
 )
 
-// Error main object for error
+// ContentType media type of error responses
+const ContentType = "application/problem+json"
+
+// RequestIDHeader response header holding the request id,
+// reported as the `instance` of the problem
+const RequestIDHeader = "X-Request-ID"
+
+// Error main object for error, serialized as an RFC 7807 problem
 type Error struct {
-	Code    int
-	Message string
+	// HTTP status
+	Code int `json:"status"`
+	// Human readable explanation
+	Message string `json:"detail"`
+	// Stable machine readable identifier, e.g "stock_not_found"
+	ErrorCode string `json:"code"`
+	// Request the error occurred in
+	Instance string `json:"instance,omitempty"`
+	// Violations of individual fields
+	Errors []FieldError `json:"errors,omitempty"`
+
+	cause error
+}
+
+// FieldError a violation on a single field of the request
+type FieldError struct {
+	// json name of the field, e.g "price"
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// problem wire format of Error
+type problem struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	*alias
+}
+
+type alias Error
+
+// New returns an error with status code, machine readable code and message
+func New(status int, code, message string) *Error {
+	return &Error{Code: status, ErrorCode: code, Message: message}
+}
+
+// Wrap returns a copy of base caused by cause
+func Wrap(cause error, base *Error) *Error {
+	e := base.clone()
+	e.cause = cause
+	return e
+}
+
+// From returns the *Error in the chain of err, errors which are not
+// an *Error are wrapped in ErrInternal
+func From(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if stderrors.As(err, &e) {
+		return e
+	}
+	return Wrap(err, ErrInternal)
+}
+
+// WithField returns a copy of err with a field violation added
+func (err *Error) WithField(field, code, message string) *Error {
+	e := err.clone()
+	e.Errors = append(e.Errors, FieldError{Field: field, Code: code, Message: message})
+	return e
+}
+
+// WithMessage returns a copy of err with message as detail
+func (err *Error) WithMessage(message string) *Error {
+	e := err.clone()
+	e.Message = message
+	return e
+}
+
+// Unwrap returns the cause of err
+func (err *Error) Unwrap() error {
+	if err == nil {
+		return nil
+	}
+	return err.cause
+}
+
+// Is errors are the same when they share their machine readable code
+func (err *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && err != nil && t != nil && err.ErrorCode == t.ErrorCode
+}
+
+func (err *Error) clone() *Error {
+	e := *err
+	e.Errors = append([]FieldError(nil), err.Errors...)
+	return &e
 }
 
 func (err *Error) Error() string {
@@ -66,6 +409,9 @@ func (err *Error) String() string {
 	if err == nil {
 		return ""
 	}
+	if err.cause != nil {
+		return fmt.Sprintf("error: code=%s message=%s cause=%v", http.StatusText(err.Code), err.Message, err.cause)
+	}
 	return fmt.Sprintf("error: code=%s message=%s", http.StatusText(err.Code), err.Message)
 }
 
@@ -74,7 +420,11 @@ func (err *Error) JSON() []byte {
 	if err == nil {
 		return []byte("{}")
 	}
-	res, _ := json.Marshal(err)
+	res, _ := json.Marshal(problem{
+		Type:  "/problems/" + err.ErrorCode,
+		Title: http.StatusText(err.Code),
+		alias: (*alias)(err),
+	})
 	return res
 }
 
@@ -85,3 +435,16 @@ func (err *Error) StatusCode() int {
 	}
 	return err.Code
 }
+
+// Write writes err as a problem+json response, the request id
+// set on the response is used as instance
+func (err *Error) Write(w http.ResponseWriter) {
+	e := err
+	if id := w.Header().Get(RequestIDHeader); id != "" && e.Instance == "" {
+		e = e.clone()
+		e.Instance = id
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(e.StatusCode())
+	_, _ = w.Write(e.JSON())
+}
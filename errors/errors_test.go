@@ -0,0 +1,49 @@
+package errors
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrap(t *testing.T) {
+	cause := fmt.Errorf("connection reset")
+	err := Wrap(cause, ErrInternal)
+
+	assert.True(t, stderrors.Is(err, ErrInternal))
+	assert.True(t, stderrors.Is(err, cause))
+	assert.False(t, stderrors.Is(err, ErrStockNotFound))
+	assert.Nil(t, ErrInternal.Unwrap(), "sentinel must not be modified")
+
+	assert.Equal(t, ErrStockNotFound, From(fmt.Errorf("get: %w", ErrStockNotFound)))
+	assert.True(t, stderrors.Is(From(cause), ErrInternal))
+	assert.Nil(t, From(nil))
+}
+
+func TestWrite(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set(RequestIDHeader, "req-1")
+	ErrBadRequest.WithField("price", "min", "must be greater than 0").Write(w)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	got := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, map[string]interface{}{
+		"type":     "/problems/bad_request",
+		"title":    "Bad Request",
+		"status":   float64(http.StatusBadRequest),
+		"detail":   ErrBadRequest.Message,
+		"code":     "bad_request",
+		"instance": "req-1",
+		"errors": []interface{}{
+			map[string]interface{}{"field": "price", "code": "min", "message": "must be greater than 0"},
+		},
+	}, got)
+	assert.Empty(t, ErrBadRequest.Errors, "sentinel must not be modified")
+}
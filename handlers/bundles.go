@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go-inventory/objects"
+	"go-inventory/rbac"
+	"go-inventory/store"
+
+	"github.com/gorilla/mux"
+)
+
+// IBundleHandler handlers of bundles
+type IBundleHandler interface {
+	CreateBundle(w http.ResponseWriter, r *http.Request)
+	GetBundle(w http.ResponseWriter, r *http.Request)
+	ListBundles(w http.ResponseWriter, r *http.Request)
+	SellBundle(w http.ResponseWriter, r *http.Request)
+}
+
+type bundleHandler struct {
+	handler
+	bundles store.IBundleStore
+}
+
+// NewBundleHandler return current IBundleHandler implementation
+func NewBundleHandler(st store.IBundleStore, opts ...Option) IBundleHandler {
+	h := &bundleHandler{handler: handler{maxBody: DefaultMaxBodySize}, bundles: st}
+	for _, opt := range opts {
+		opt(&h.handler)
+	}
+	return h
+}
+
+func (h *bundleHandler) CreateBundle(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "CreateBundle", "", rbac.PermStockCreate) {
+		return
+	}
+	req := &objects.CreateBundleRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
+		return
+	}
+	if Validate(w, req) != nil {
+		return
+	}
+	bundle, err := h.bundles.CreateBundle(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.BundlesResponseWrapper{Bundle: bundle, Code: http.StatusCreated})
+}
+
+func (h *bundleHandler) GetBundle(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "GetBundle", id, rbac.PermStockRead) {
+		return
+	}
+	req := &objects.GetRequest{ID: id}
+	if Validate(w, req) != nil {
+		return
+	}
+	bundle, err := h.bundles.GetBundle(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.BundlesResponseWrapper{Bundle: bundle})
+}
+
+func (h *bundleHandler) ListBundles(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "ListBundles", "", rbac.PermStockRead) {
+		return
+	}
+	values := r.URL.Query()
+	limit, err := IntFromString(w, values.Get("limit"))
+	if err != nil {
+		return
+	}
+	req := &objects.ListRequest{Limit: limit, After: values.Get("after"), Name: values.Get("name")}
+	if Validate(w, req) != nil {
+		return
+	}
+	list, err := h.bundles.ListBundles(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.BundlesResponseWrapper{Bundles: list})
+}
+
+func (h *bundleHandler) SellBundle(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "SellBundle", id, rbac.PermOrdersManage) {
+		return
+	}
+	req := &objects.SellBundleRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
+		return
+	}
+	req.ID = id
+	if Validate(w, req) != nil {
+		return
+	}
+	order, err := h.bundles.SellBundle(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.BundlesResponseWrapper{SalesOrder: order, Code: http.StatusCreated})
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go-inventory/objects"
+	"go-inventory/rbac"
+	"go-inventory/store"
+
+	"github.com/gorilla/mux"
+)
+
+// ICategoryHandler handlers of the category tree and the tags of stocks
+type ICategoryHandler interface {
+	CreateCategory(w http.ResponseWriter, r *http.Request)
+	GetCategory(w http.ResponseWriter, r *http.Request)
+	ListCategories(w http.ResponseWriter, r *http.Request)
+	UpdateCategory(w http.ResponseWriter, r *http.Request)
+	DeleteCategory(w http.ResponseWriter, r *http.Request)
+	ListCategoryStocks(w http.ResponseWriter, r *http.Request)
+	ListTagStocks(w http.ResponseWriter, r *http.Request)
+	SetClassification(w http.ResponseWriter, r *http.Request)
+}
+
+type categoryHandler struct {
+	handler
+	categories store.ICategoryStore
+}
+
+// NewCategoryHandler return current ICategoryHandler implementation
+func NewCategoryHandler(st store.ICategoryStore, opts ...Option) ICategoryHandler {
+	h := &categoryHandler{handler: handler{maxBody: DefaultMaxBodySize}, categories: st}
+	for _, opt := range opts {
+		opt(&h.handler)
+	}
+	return h
+}
+
+func (h *categoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "CreateCategory", "", rbac.PermStockDetails) {
+		return
+	}
+	req := &objects.CreateCategoryRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
+		return
+	}
+	if Validate(w, req) != nil {
+		return
+	}
+	category, err := h.categories.CreateCategory(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.CategoriesResponseWrapper{Category: category, Code: http.StatusCreated})
+}
+
+func (h *categoryHandler) GetCategory(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "GetCategory", id, rbac.PermStockRead) {
+		return
+	}
+	req := &objects.GetRequest{ID: id}
+	if Validate(w, req) != nil {
+		return
+	}
+	category, err := h.categories.GetCategory(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.CategoriesResponseWrapper{Category: category})
+}
+
+func (h *categoryHandler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "ListCategories", "", rbac.PermStockRead) {
+		return
+	}
+	values := r.URL.Query()
+	limit, err := IntFromString(w, values.Get("limit"))
+	if err != nil {
+		return
+	}
+	req := &objects.ListCategoriesRequest{Limit: limit, After: values.Get("after"), ParentID: values.Get("parent_id")}
+	if Validate(w, req) != nil {
+		return
+	}
+	list, err := h.categories.ListCategories(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.CategoriesResponseWrapper{Categories: list})
+}
+
+func (h *categoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "UpdateCategory", id, rbac.PermStockDetails) {
+		return
+	}
+	req := &objects.UpdateCategoryRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
+		return
+	}
+	req.ID = id
+	if Validate(w, req) != nil {
+		return
+	}
+	category, err := h.categories.UpdateCategory(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.CategoriesResponseWrapper{Category: category})
+}
+
+func (h *categoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "DeleteCategory", id, rbac.PermStockDetails) {
+		return
+	}
+	req := &objects.DeleteRequest{ID: id}
+	if Validate(w, req) != nil {
+		return
+	}
+	if err := h.categories.DeleteCategory(r.Context(), req); err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.CategoriesResponseWrapper{})
+}
+
+func (h *categoryHandler) ListCategoryStocks(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "ListCategoryStocks", id, rbac.PermStockRead) {
+		return
+	}
+	values := r.URL.Query()
+	limit, err := IntFromString(w, values.Get("limit"))
+	if err != nil {
+		return
+	}
+	req := &objects.ListCategoryStocksRequest{CategoryID: id, Limit: limit, After: values.Get("after"), Tag: values.Get("tag")}
+	if Validate(w, req) != nil {
+		return
+	}
+	list, err := h.categories.ListCategoryStocks(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.CategoriesResponseWrapper{Stocks: list})
+}
+
+func (h *categoryHandler) ListTagStocks(w http.ResponseWriter, r *http.Request) {
+	tag := mux.Vars(r)["tag"]
+	if !h.authorize(w, r, "ListTagStocks", tag, rbac.PermStockRead) {
+		return
+	}
+	values := r.URL.Query()
+	limit, err := IntFromString(w, values.Get("limit"))
+	if err != nil {
+		return
+	}
+	req := &objects.ListTagStocksRequest{Tag: tag, Limit: limit, After: values.Get("after")}
+	if Validate(w, req) != nil {
+		return
+	}
+	list, err := h.categories.ListTagStocks(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.CategoriesResponseWrapper{Stocks: list})
+}
+
+func (h *categoryHandler) SetClassification(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "SetClassification", id, rbac.PermStockDetails) {
+		return
+	}
+	req := &objects.SetClassificationRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
+		return
+	}
+	req.ID = id
+	if Validate(w, req) != nil {
+		return
+	}
+	evt, err := h.categories.SetClassification(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.CategoriesResponseWrapper{Stock: evt})
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/rbac"
+	"go-inventory/store"
+
+	"github.com/gorilla/mux"
+)
+
+// ICountHandler handlers of cycle counts
+type ICountHandler interface {
+	CreateCount(w http.ResponseWriter, r *http.Request)
+	GetCount(w http.ResponseWriter, r *http.Request)
+	ListCounts(w http.ResponseWriter, r *http.Request)
+	SubmitCount(w http.ResponseWriter, r *http.Request)
+	ApproveCount(w http.ResponseWriter, r *http.Request)
+	CancelCount(w http.ResponseWriter, r *http.Request)
+}
+
+type countHandler struct {
+	handler
+	counts store.ICountStore
+}
+
+// countStatuses statuses accepted by the `status` filter
+var countStatuses = map[string]bool{
+	objects.CountOpen:      true,
+	objects.CountSubmitted: true,
+	objects.CountApproved:  true,
+	objects.CountCancelled: true,
+}
+
+// NewCountHandler return current ICountHandler implementation
+func NewCountHandler(st store.ICountStore, opts ...Option) ICountHandler {
+	h := &countHandler{handler: handler{maxBody: DefaultMaxBodySize}, counts: st}
+	for _, opt := range opts {
+		opt(&h.handler)
+	}
+	return h
+}
+
+func (h *countHandler) CreateCount(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "CreateCount", "", rbac.PermCountsManage) {
+		return
+	}
+	req := &objects.CreateCountRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
+		return
+	}
+	if Validate(w, req) != nil {
+		return
+	}
+	if (len(req.StockIDs) == 0) == (req.CategoryID == "") {
+		WriteError(w, errors.ErrValidation.WithField("stock_ids", "stocks_or_category", "either stock_ids or category_id is required"))
+		return
+	}
+	count, err := h.counts.CreateCount(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.CountsResponseWrapper{Count: count, Code: http.StatusCreated})
+}
+
+func (h *countHandler) GetCount(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "GetCount", id, rbac.PermCountsRead) {
+		return
+	}
+	req := &objects.GetRequest{ID: id}
+	if Validate(w, req) != nil {
+		return
+	}
+	count, err := h.counts.GetCount(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.CountsResponseWrapper{Count: count})
+}
+
+func (h *countHandler) ListCounts(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "ListCounts", "", rbac.PermCountsRead) {
+		return
+	}
+	values := r.URL.Query()
+	limit, err := IntFromString(w, values.Get("limit"))
+	if err != nil {
+		return
+	}
+	req := &objects.ListCountsRequest{
+		Limit:  limit,
+		After:  values.Get("after"),
+		Status: values.Get("status"),
+	}
+	if req.Status != "" && !countStatuses[req.Status] {
+		WriteError(w, errors.ErrValidation.WithField("status", "unknown_status", "unknown count status "+req.Status))
+		return
+	}
+	if Validate(w, req) != nil {
+		return
+	}
+	list, err := h.counts.ListCounts(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.CountsResponseWrapper{Counts: list})
+}
+
+// SubmitCount records counted quantities, the response shows their variance
+func (h *countHandler) SubmitCount(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "SubmitCount", id, rbac.PermCountsManage) {
+		return
+	}
+	req := &objects.SubmitCountRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
+		return
+	}
+	req.ID = id
+	if Validate(w, req) != nil {
+		return
+	}
+	count, err := h.counts.SubmitCount(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.CountsResponseWrapper{Count: count})
+}
+
+// ApproveCount posts the variances of a submitted count as adjustments
+func (h *countHandler) ApproveCount(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "ApproveCount", id, rbac.PermCountsApprove) {
+		return
+	}
+	req := &objects.GetRequest{ID: id}
+	if Validate(w, req) != nil {
+		return
+	}
+	count, movements, err := h.counts.ApproveCount(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.CountsResponseWrapper{Count: count, Movements: movements})
+}
+
+func (h *countHandler) CancelCount(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "CancelCount", id, rbac.PermCountsManage) {
+		return
+	}
+	req := &objects.GetRequest{ID: id}
+	if Validate(w, req) != nil {
+		return
+	}
+	count, err := h.counts.CancelCount(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.CountsResponseWrapper{Count: count})
+}
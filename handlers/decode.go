@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"go-inventory/errors"
+)
+
+// DefaultMaxBodySize default limit of request bodies, 1MB
+const DefaultMaxBodySize int64 = 1 << 20
+
+// Decode strictly decodes the json body of r into v: the content type must be
+// json, the body at most maxBytes long, and neither unknown fields nor
+// trailing data are accepted. The error is written to w.
+func Decode(w http.ResponseWriter, r *http.Request, v interface{}, maxBytes int64) error {
+	err := decode(r, v, maxBytes)
+	if err != nil {
+		WriteError(w, err)
+	}
+	return err
+}
+
+func decode(r *http.Request, v interface{}, maxBytes int64) error {
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != "application/json" {
+		return errors.ErrUnsupportedMediaType
+	}
+	if r.ContentLength > maxBytes {
+		return errors.ErrRequestTooLarge
+	}
+	body := &limitedReader{r: r.Body, n: maxBytes}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		if body.exceeded {
+			return errors.ErrRequestTooLarge
+		}
+		return errors.Wrap(err, errors.ErrUnprocessableEntity)
+	}
+	if d := string(bytes.TrimSpace(data)); d == "null" || d == "" {
+		return errors.ErrObjectIsRequired
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return decodeError(err, dec.InputOffset())
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.ErrMalformedJSON.
+			WithMessage(fmt.Sprintf("Unexpected data after the JSON object at offset %d", dec.InputOffset()))
+	}
+	return nil
+}
+
+// decodeError maps a json decoding error to the field and offset it occurred at
+func decodeError(err error, offset int64) error {
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		return errors.Wrap(err, errors.ErrMalformedJSON).
+			WithMessage(fmt.Sprintf("Malformed JSON at offset %d", e.Offset))
+	case *json.UnmarshalTypeError:
+		return errors.Wrap(err, errors.ErrMalformedJSON).
+			WithMessage(fmt.Sprintf("Invalid value for %s at offset %d", e.Field, e.Offset)).
+			WithField(e.Field, "invalid_type", "should be "+e.Type.String())
+	}
+	if err == io.ErrUnexpectedEOF {
+		return errors.Wrap(err, errors.ErrMalformedJSON).
+			WithMessage(fmt.Sprintf("Unexpected end of JSON at offset %d", offset))
+	}
+	// the decoder has no typed error for unknown fields
+	if field := strings.TrimPrefix(err.Error(), "json: unknown field "); field != err.Error() {
+		field = strings.Trim(field, `"`)
+		return errors.Wrap(err, errors.ErrMalformedJSON).
+			WithMessage(fmt.Sprintf("Unknown field %q at offset %d", field, offset)).
+			WithField(field, "unknown_field", "is not a known field")
+	}
+	return errors.Wrap(err, errors.ErrMalformedJSON)
+}
+
+// limitedReader reads at most n bytes and fails past them
+type limitedReader struct {
+	r        io.Reader
+	n        int64
+	exceeded bool
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		l.exceeded = true
+		return 0, fmt.Errorf("body larger than limit")
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		l.exceeded = true
+		return n, fmt.Errorf("body larger than limit")
+	}
+	return n, err
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		code        string
+		field       string
+		detail      string
+	}{
+		{
+			name:        "Ok",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"name":"Meat Ball","price":100,"availability":3}`,
+		},
+		{
+			name:        "Wrong content type",
+			contentType: "text/plain",
+			body:        `{"name":"Meat Ball"}`,
+			code:        errors.ErrUnsupportedMediaType.ErrorCode,
+		},
+		{
+			name:        "Empty",
+			contentType: "application/json",
+			code:        errors.ErrObjectIsRequired.ErrorCode,
+		},
+		{
+			name:        "Too large",
+			contentType: "application/json",
+			body:        `{"name":"` + strings.Repeat("a", 80) + `"}`,
+			code:        errors.ErrRequestTooLarge.ErrorCode,
+		},
+		{
+			name:        "Unknown field",
+			contentType: "application/json",
+			body:        `{"name":"Meat Ball","availabilty":3}`,
+			code:        errors.ErrMalformedJSON.ErrorCode,
+			field:       "availabilty",
+			detail:      `Unknown field "availabilty" at offset 36`,
+		},
+		{
+			name:        "Wrong type",
+			contentType: "application/json",
+			body:        `{"price":"cheap"}`,
+			code:        errors.ErrMalformedJSON.ErrorCode,
+			field:       "price",
+			detail:      "Invalid value for price at offset 16",
+		},
+		{
+			name:        "Syntax",
+			contentType: "application/json",
+			body:        `{"name":}`,
+			code:        errors.ErrMalformedJSON.ErrorCode,
+			detail:      "Malformed JSON at offset 9",
+		},
+		{
+			name:        "Trailing data",
+			contentType: "application/json",
+			body:        `{"name":"a"} {"name":"b"}`,
+			code:        errors.ErrMalformedJSON.ErrorCode,
+			detail:      "Unexpected data after the JSON object at offset 14",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			err := Decode(w, req, &objects.Stock{}, 64)
+			if tt.code == "" {
+				assert.Nil(t, err)
+				return
+			}
+			got := &errors.Error{}
+			assert.Nil(t, json.Unmarshal(w.Body.Bytes(), got))
+			assert.Equal(t, tt.code, got.ErrorCode)
+			if tt.detail != "" {
+				assert.Equal(t, tt.detail, got.Message)
+			}
+			if tt.field != "" && assert.Len(t, got.Errors, 1) {
+				assert.Equal(t, tt.field, got.Errors[0].Field)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/rbac"
+)
+
+var (
+	// eventsPollInterval how often a stream polls the change log
+	eventsPollInterval = time.Second
+	// eventsHeartbeat how often an idle stream sends a keep alive comment
+	eventsHeartbeat = 15 * time.Second
+)
+
+// eventTypes types accepted by the `type` filter
+var eventTypes = map[string]bool{
+	objects.EventStockCreated:             true,
+	objects.EventStockUpdated:             true,
+	objects.EventStockAvailabilityChanged: true,
+	objects.EventStockDeactivated:         true,
+	objects.EventStockBelowReorderPoint:   true,
+	objects.EventStockBelowSafetyStock:    true,
+}
+
+// Events streams the change log as Server-Sent Events, resuming after the
+// `Last-Event-ID` header and optionally filtered by `stock_id` and `type`
+func (h *handler) Events(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "Events", "", rbac.PermStockRead) {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		WriteError(w, fmt.Errorf("streaming unsupported by %T", w))
+		return
+	}
+	values := r.URL.Query()
+	req := &objects.ListEventsRequest{
+		StockIDs: values["stock_id"],
+		Types:    values["type"],
+	}
+	for _, t := range req.Types {
+		if !eventTypes[t] {
+			WriteError(w, errors.ErrValidation.WithField("type", "unknown_type", "unknown event type "+t))
+			return
+		}
+	}
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = values.Get("last_event_id")
+	}
+	if lastID != "" {
+		after, err := strconv.ParseInt(lastID, 10, 64)
+		if err != nil || after < 0 {
+			WriteError(w, errors.ErrInvalidEventID)
+			return
+		}
+		req.After = after
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// disable proxy buffering
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	poll := time.NewTicker(eventsPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		req.Limit = objects.MaxEventsLimit
+		list, err := h.store.ListEvents(r.Context(), req)
+		if err != nil {
+			if r.Context().Err() == nil {
+				log.Println(err)
+			}
+			return
+		}
+		for _, e := range list {
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+			req.After = e.Seq
+		}
+		if len(list) > 0 {
+			flusher.Flush()
+			heartbeat.Reset(eventsHeartbeat)
+		}
+		if len(list) == objects.MaxEventsLimit {
+			// more are waiting
+			continue
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-poll.C:
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent writes e in the text/event-stream format
+func writeEvent(w http.ResponseWriter, e *objects.StockEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, data)
+	return err
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-inventory/objects"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvents(t *testing.T) {
+	eventsPollInterval = 10 * time.Millisecond
+	_, st, stocks := newRouter(t, "One", "Two")
+	hnd := NewEventHandler(st)
+	srv := httptest.NewServer(http.HandlerFunc(hnd.Events))
+	defer srv.Close()
+
+	// change the availability of the first stock
+	err := st.UpdateDetails(context.TODO(), &objects.UpdateDetailsRequest{
+		ID: stocks[0].ID, Name: "One", Price: 1, Availability: 0, IsActive: false,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		lastID string
+		events []string
+	}{
+		{
+			name:   "All",
+			events: []string{"1 stock.created", "2 stock.created", "3 stock.updated", "4 stock.availability_changed"},
+		},
+		{
+			name:   "Resume",
+			lastID: "2",
+			events: []string{"3 stock.updated", "4 stock.availability_changed"},
+		},
+		{
+			name:   "Filtered",
+			query:  "?type=stock.created&stock_id=" + stocks[1].ID,
+			events: []string{"2 stock.created"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.lastID != "" {
+				req.Header.Set("Last-Event-ID", tt.lastID)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+			var got []string
+			id := ""
+			scanner := bufio.NewScanner(res.Body)
+			for len(got) < len(tt.events) && scanner.Scan() {
+				line := scanner.Text()
+				if strings.HasPrefix(line, "id: ") {
+					id = strings.TrimPrefix(line, "id: ")
+				}
+				if strings.HasPrefix(line, "event: ") {
+					got = append(got, id+" "+strings.TrimPrefix(line, "event: "))
+				}
+			}
+			assert.Equal(t, tt.events, got)
+		})
+	}
+
+	// new events reach open streams
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?last_event_id=4", nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if err := st.Create(context.TODO(), &objects.CreateRequest{Stock: &objects.Stock{Name: "Three", Price: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "event: ") {
+			assert.Equal(t, "event: stock.created", line)
+			break
+		}
+	}
+}
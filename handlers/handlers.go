@@ -2,12 +2,14 @@ package handlers
 
 import (
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"time"
 
 	"go-inventory/errors"
 	"go-inventory/objects"
+	"go-inventory/rbac"
 	"go-inventory/store"
+	"go-inventory/validation"
 
 	"github.com/gorilla/mux"
 )
@@ -16,17 +18,62 @@ import (
 type IStockHandler interface {
 	Get(w http.ResponseWriter, r *http.Request)
 	List(w http.ResponseWriter, r *http.Request)
+	ListLow(w http.ResponseWriter, r *http.Request)
 	Create(w http.ResponseWriter, r *http.Request)
 	UpdateDetails(w http.ResponseWriter, r *http.Request)
+	BatchGet(w http.ResponseWriter, r *http.Request)
+	BatchUpdate(w http.ResponseWriter, r *http.Request)
+	Events(w http.ResponseWriter, r *http.Request)
 }
 
 type handler struct {
 	store store.IStockStore
+	authz *rbac.Authorizer
+	// limit of request bodies in bytes
+	maxBody int64
+}
+
+// Option configures the handler
+type Option func(h *handler)
+
+// WithAuthorizer checks the permissions of the caller in every handler
+func WithAuthorizer(authz *rbac.Authorizer) Option {
+	return func(h *handler) {
+		h.authz = authz
+	}
+}
+
+// WithMaxBodySize limits request bodies to n bytes, DefaultMaxBodySize otherwise
+func WithMaxBodySize(n int64) Option {
+	return func(h *handler) {
+		h.maxBody = n
+	}
 }
 
 // NewEventHandler return current IStockHandler implementation
-func NewEventHandler(store store.IStockStore) IStockHandler {
-	return &handler{store: store}
+func NewEventHandler(store store.IStockStore, opts ...Option) IStockHandler {
+	h := &handler{store: store, maxBody: DefaultMaxBodySize}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// authorize checks the caller holds perms, without an authorizer everything is allowed
+func (h *handler) authorize(w http.ResponseWriter, r *http.Request, action, resource string, perms ...rbac.Permission) bool {
+	if err := h.permitted(r, action, resource, perms...); err != nil {
+		WriteError(w, err)
+		return false
+	}
+	return true
+}
+
+// permitted is authorize without writing the error
+func (h *handler) permitted(r *http.Request, action, resource string, perms ...rbac.Permission) error {
+	if h.authz == nil {
+		return nil
+	}
+	return h.authz.Authorize(r.Context(), action, resource, perms...)
 }
 
 func (h *handler) Get(w http.ResponseWriter, r *http.Request) {
@@ -38,16 +85,22 @@ func (h *handler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	fmt.Println(`id := `, id)
+	if !h.authorize(w, r, "Get", id, rbac.PermStockRead) {
+		return
+	}
 
-	evt, err := h.store.Get(r.Context(), &objects.GetRequest{ID: id})
-	if err != nil {
-		WriteError(w, err)
+	req := &objects.GetRequest{ID: id}
+	if Validate(w, req) != nil {
 		return
 	}
-	WriteResponse(w, &objects.StockResponseWrapper{Stock: evt})
+	evt, err := h.store.Get(r.Context(), req)
+	writeStock(w, r, evt, err)
 }
 
 func (h *handler) List(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "List", "", rbac.PermStockRead) {
+		return
+	}
 	values := r.URL.Query()
 	// after
 	after := values.Get("after")
@@ -58,38 +111,68 @@ func (h *handler) List(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		return
 	}
-	// list events
-	list, err := h.store.List(r.Context(), &objects.ListRequest{
+	req := &objects.ListRequest{
 		Limit: limit,
 		After: after,
 		Name:  name,
-	})
+	}
+	if Validate(w, req) != nil {
+		return
+	}
+	// list events
+	list, err := h.store.List(r.Context(), req)
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
+	convertUnits(values.Get("unit"), list...)
 	WriteResponse(w, &objects.StockResponseWrapper{Stocks: list})
 }
 
-func (h *handler) Create(w http.ResponseWriter, r *http.Request) {
-	data, err := ioutil.ReadAll(r.Body)
+// ListLow lists the stocks below their reorder point, or their safety stock
+// with `level=safety_stock`
+func (h *handler) ListLow(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "ListLow", "", rbac.PermStockRead) {
+		return
+	}
+	values := r.URL.Query()
+	limit, err := IntFromString(w, values.Get("limit"))
 	if err != nil {
-		WriteError(w, errors.ErrUnprocessableEntity)
 		return
 	}
-	evt := &objects.Stock{}
-	if Unmarshal(w, data, evt) != nil {
+	req := &objects.ListLowRequest{
+		Level: values.Get("level"),
+		Limit: limit,
+		After: values.Get("after"),
+	}
+	if req.Level != "" && req.Level != objects.LevelReorderPoint && req.Level != objects.LevelSafetyStock {
+		WriteError(w, errors.ErrValidation.WithField("level", "unknown_level", "unknown low stock level "+req.Level))
+		return
+	}
+	if Validate(w, req) != nil {
 		return
 	}
-	if evt.Availability < 0 {
-		WriteError(w, errors.ErrValidAvailibiltyIsRequired)
+	list, err := h.store.ListLow(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	convertUnits(values.Get("unit"), list...)
+	WriteResponse(w, &objects.StockResponseWrapper{Stocks: list})
+}
+
+func (h *handler) Create(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "Create", "", rbac.PermStockCreate) {
+		return
+	}
+	evt := &objects.Stock{}
+	if Decode(w, r, evt, h.maxBody) != nil {
 		return
 	}
-	if evt.Price <= 0 {
-		WriteError(w, errors.ErrValidPriceIsRequired)
+	if Validate(w, evt) != nil {
 		return
 	}
-	if err = h.store.Create(r.Context(), &objects.CreateRequest{Stock: evt}); err != nil {
+	if err := h.store.Create(r.Context(), &objects.CreateRequest{Stock: evt}); err != nil {
 		WriteError(w, err)
 		return
 	}
@@ -97,21 +180,24 @@ func (h *handler) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *handler) UpdateDetails(w http.ResponseWriter, r *http.Request) {
-	data, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		WriteError(w, errors.ErrUnprocessableEntity)
+	req := &objects.UpdateDetailsRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
 		return
 	}
-	req := &objects.UpdateDetailsRequest{}
-	if Unmarshal(w, data, req) != nil {
+	if Validate(w, req) != nil {
 		return
 	}
 
 	// check if event exist
-	if _, err := h.store.Get(r.Context(), &objects.GetRequest{ID: req.ID}); err != nil {
+	cur, err := h.store.Get(r.Context(), &objects.GetRequest{ID: req.ID})
+	if err != nil {
 		WriteError(w, err)
 		return
 	}
+	// each changed field needs its own permission
+	if !h.authorize(w, r, "UpdateDetails", req.ID, updatePermissions(cur, req)...) {
+		return
+	}
 
 	if err = h.store.UpdateDetails(r.Context(), req); err != nil {
 		WriteError(w, err)
@@ -119,3 +205,144 @@ func (h *handler) UpdateDetails(w http.ResponseWriter, r *http.Request) {
 	}
 	WriteResponse(w, &objects.StockResponseWrapper{})
 }
+
+func (h *handler) BatchGet(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "BatchGet", "", rbac.PermStockRead) {
+		return
+	}
+	req := &objects.BatchGetRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
+		return
+	}
+	if Validate(w, req) != nil {
+		return
+	}
+	list, err := h.store.BatchGet(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	convertUnits(req.Unit, list...)
+	found := make(map[string]bool, len(list))
+	for _, evt := range list {
+		found[evt.ID] = true
+	}
+	missing := []string{}
+	for _, id := range req.IDs {
+		if !found[id] {
+			found[id] = true
+			missing = append(missing, id)
+		}
+	}
+	WriteResponse(w, &objects.StockResponseWrapper{Stocks: list, Missing: missing})
+}
+
+func (h *handler) BatchUpdate(w http.ResponseWriter, r *http.Request) {
+	req := &objects.BatchUpdateRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
+		return
+	}
+	if Validate(w, req) != nil {
+		return
+	}
+
+	// current state of the stocks, to check they exist and which fields change
+	ids := make([]string, 0, len(req.Items))
+	for _, item := range req.Items {
+		if item != nil {
+			ids = append(ids, item.ID)
+		}
+	}
+	list, err := h.store.BatchGet(r.Context(), &objects.BatchGetRequest{IDs: ids})
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	current := make(map[string]*objects.Stock, len(list))
+	for _, evt := range list {
+		current[evt.ID] = evt
+	}
+
+	// items failing here never reach the store
+	results := make([]*objects.BatchUpdateResult, len(req.Items))
+	pending := &objects.BatchUpdateRequest{Atomic: req.Atomic}
+	var positions []int
+	for i, item := range req.Items {
+		if item == nil {
+			results[i] = &objects.BatchUpdateResult{Status: errors.ErrObjectIsRequired.Code, Error: errors.ErrObjectIsRequired}
+			continue
+		}
+		err := validation.Validate(item)
+		if err == nil {
+			if cur, ok := current[item.ID]; !ok {
+				err = errors.ErrStockNotFound
+			} else {
+				err = h.permitted(r, "BatchUpdate", item.ID, updatePermissions(cur, item)...)
+			}
+		}
+		if err != nil {
+			e := errors.From(err)
+			results[i] = &objects.BatchUpdateResult{ID: item.ID, Status: e.StatusCode(), Error: e}
+			continue
+		}
+		pending.Items = append(pending.Items, item)
+		positions = append(positions, i)
+	}
+
+	if req.Atomic && len(positions) < len(req.Items) {
+		for i, item := range req.Items {
+			if results[i] == nil {
+				results[i] = &objects.BatchUpdateResult{ID: item.ID, Status: errors.ErrBatchAborted.Code, Error: errors.ErrBatchAborted}
+			}
+		}
+		WriteResponse(w, &objects.StockResponseWrapper{Results: results, Code: http.StatusConflict})
+		return
+	}
+	if len(pending.Items) > 0 {
+		applied, err := h.store.BatchUpdate(r.Context(), pending)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		for j, res := range applied {
+			results[positions[j]] = res
+		}
+	}
+
+	res := &objects.StockResponseWrapper{Results: results}
+	if req.Atomic {
+		for _, item := range results {
+			if item.Error != nil {
+				res.Code = http.StatusConflict
+				break
+			}
+		}
+	}
+	WriteResponse(w, res)
+}
+
+// updatePermissions returns the permissions needed to apply req on cur
+func updatePermissions(cur *objects.Stock, req *objects.UpdateDetailsRequest) []rbac.Permission {
+	var perms []rbac.Permission
+	if req.Availability != cur.Availability {
+		perms = append(perms, rbac.PermStockAvailability)
+	}
+	if req.Price != cur.Price {
+		perms = append(perms, rbac.PermStockPrice)
+	}
+	if req.Name != cur.Name || req.IsActive != cur.IsActive ||
+		req.ReorderPoint != cur.ReorderPoint || req.SafetyStock != cur.SafetyStock ||
+		req.Backorderable != cur.Backorderable || req.BackorderLimit != cur.BackorderLimit ||
+		!sameTime(req.RestockOn, cur.RestockOn) || req.Serialized != cur.Serialized {
+		perms = append(perms, rbac.PermStockDetails)
+	}
+	return perms
+}
+
+// sameTime reports whether a and b are both nil or the same instant
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
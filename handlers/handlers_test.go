@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/store"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRouter returns a router serving the handlers over a memory store
+// holding one stock per name
+func newRouter(t *testing.T, names ...string) (*mux.Router, store.IStockStore, []*objects.Stock) {
+	st := store.NewMemoryStockStore()
+	var stocks []*objects.Stock
+	for _, name := range names {
+		evt := &objects.Stock{Name: name, Price: 1, Availability: 1}
+		if err := st.Create(context.TODO(), &objects.CreateRequest{Stock: evt}); err != nil {
+			t.Fatal(err)
+		}
+		stocks = append(stocks, evt)
+	}
+	hnd := NewEventHandler(st)
+	router := mux.NewRouter()
+	router.HandleFunc("/stocks:batchGet", hnd.BatchGet).Methods(http.MethodPost)
+	router.HandleFunc("/stocks:batchUpdate", hnd.BatchUpdate).Methods(http.MethodPost)
+	return router, st, stocks
+}
+
+func post(t *testing.T, router *mux.Router, path string, body interface{}) (*httptest.ResponseRecorder, *objects.StockResponseWrapper) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	got := &objects.StockResponseWrapper{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), got))
+	return w, got
+}
+
+func TestBatchGet(t *testing.T) {
+	router, _, stocks := newRouter(t, "One", "Two")
+
+	w, got := post(t, router, "/stocks:batchGet", &objects.BatchGetRequest{
+		IDs: []string{stocks[1].ID, "fake", stocks[0].ID},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Len(t, got.Stocks, 2) {
+		assert.Equal(t, "Two", got.Stocks[0].Name)
+		assert.Equal(t, "One", got.Stocks[1].Name)
+	}
+	assert.Equal(t, []string{"fake"}, got.Missing)
+
+	w, _ = post(t, router, "/stocks:batchGet", &objects.BatchGetRequest{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestBatchUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		atomic   bool
+		missing  bool
+		code     int
+		statuses []int
+		applied  bool
+	}{
+		{
+			name:     "Ok",
+			code:     http.StatusOK,
+			statuses: []int{http.StatusOK, http.StatusOK},
+			applied:  true,
+		},
+		{
+			name:     "Partial",
+			missing:  true,
+			code:     http.StatusOK,
+			statuses: []int{http.StatusOK, http.StatusNotFound},
+			applied:  true,
+		},
+		{
+			name:     "Atomic",
+			atomic:   true,
+			missing:  true,
+			code:     http.StatusConflict,
+			statuses: []int{http.StatusConflict, http.StatusNotFound},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, st, stocks := newRouter(t, "One", "Two")
+			second := stocks[1].ID
+			if tt.missing {
+				second = "fake"
+			}
+			w, got := post(t, router, "/stocks:batchUpdate", &objects.BatchUpdateRequest{
+				Atomic: tt.atomic,
+				Items: []*objects.UpdateDetailsRequest{
+					{ID: stocks[0].ID, Name: "Updated", Price: 2, Availability: 5},
+					{ID: second, Name: "Updated", Price: 2, Availability: 5},
+				},
+			})
+			assert.Equal(t, tt.code, w.Code)
+			if assert.Len(t, got.Results, len(tt.statuses)) {
+				for i, status := range tt.statuses {
+					assert.Equal(t, status, got.Results[i].Status)
+				}
+			}
+			evt, err := st.Get(context.TODO(), &objects.GetRequest{ID: stocks[0].ID})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.applied, evt.Name == "Updated")
+		})
+	}
+
+	router, _, stocks := newRouter(t, "One")
+	_, got := post(t, router, "/stocks:batchUpdate", &objects.BatchUpdateRequest{
+		Items: []*objects.UpdateDetailsRequest{{ID: stocks[0].ID, Name: "One", Price: -1}},
+	})
+	if assert.Len(t, got.Results, 1) {
+		assert.Equal(t, errors.ErrValidation.ErrorCode, got.Results[0].Error.ErrorCode)
+	}
+}
@@ -1,12 +1,12 @@
 package handlers
 
 import (
-	"encoding/json"
 	"log"
 	"net/http"
 	"strconv"
 
 	"go-inventory/errors"
+	"go-inventory/validation"
 )
 
 // Response helper used to write reponse
@@ -22,13 +22,13 @@ func WriteResponse(w http.ResponseWriter, res Response) {
 }
 
 // WriteError long the error and write the response to http response stream
+// as a problem+json document
 func WriteError(w http.ResponseWriter, err error) {
-	res, ok := err.(*errors.Error)
-	if !ok {
+	res := errors.From(err)
+	if res.StatusCode() >= http.StatusInternalServerError {
 		log.Println(err)
-		res = errors.ErrInternal
 	}
-	WriteResponse(w, res)
+	res.Write(w)
 }
 
 // IntFromString string to int
@@ -39,21 +39,17 @@ func IntFromString(w http.ResponseWriter, v string) (int, error) {
 	res, err := strconv.Atoi(v)
 	if err != nil {
 		log.Println(err)
-		WriteError(w, errors.ErrInvalidLimit)
+		WriteError(w, errors.Wrap(err, errors.ErrInvalidLimit))
 	}
 	return res, err
 }
 
-// Unmarshal json
-func Unmarshal(w http.ResponseWriter, data []byte, v interface{}) error {
-	if d := string(data); d == "null" || d == "" {
-		WriteError(w, errors.ErrObjectIsRequired)
-		return errors.ErrObjectIsRequired
-	}
-	err := json.Unmarshal(data, v)
+// Validate checks v against the rules of its `validate` tags,
+// every violation is reported at once
+func Validate(w http.ResponseWriter, v interface{}) error {
+	err := validation.Validate(v)
 	if err != nil {
-		log.Println(err)
-		WriteError(w, errors.ErrBadRequest)
+		WriteError(w, err)
 	}
 	return err
 }
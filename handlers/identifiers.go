@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go-inventory/objects"
+	"go-inventory/rbac"
+	"go-inventory/store"
+
+	"github.com/gorilla/mux"
+)
+
+// IIdentifierHandler handlers of the SKUs, barcodes and external identifiers of stocks
+type IIdentifierHandler interface {
+	GetBySKU(w http.ResponseWriter, r *http.Request)
+	GetByBarcode(w http.ResponseWriter, r *http.Request)
+	GetByExternalID(w http.ResponseWriter, r *http.Request)
+	SetIdentifiers(w http.ResponseWriter, r *http.Request)
+	CreateExternalID(w http.ResponseWriter, r *http.Request)
+	ListExternalIDs(w http.ResponseWriter, r *http.Request)
+	DeleteExternalID(w http.ResponseWriter, r *http.Request)
+}
+
+type identifierHandler struct {
+	handler
+	identifiers store.IIdentifierStore
+}
+
+// NewIdentifierHandler return current IIdentifierHandler implementation
+func NewIdentifierHandler(st store.IIdentifierStore, opts ...Option) IIdentifierHandler {
+	h := &identifierHandler{handler: handler{maxBody: DefaultMaxBodySize}, identifiers: st}
+	for _, opt := range opts {
+		opt(&h.handler)
+	}
+	return h
+}
+
+func (h *identifierHandler) GetBySKU(w http.ResponseWriter, r *http.Request) {
+	sku := mux.Vars(r)["sku"]
+	if !h.authorize(w, r, "GetBySKU", sku, rbac.PermStockRead) {
+		return
+	}
+	req := &objects.GetBySKURequest{SKU: sku}
+	if Validate(w, req) != nil {
+		return
+	}
+	evt, err := h.identifiers.GetBySKU(r.Context(), req)
+	writeStock(w, r, evt, err)
+}
+
+func (h *identifierHandler) GetByBarcode(w http.ResponseWriter, r *http.Request) {
+	code := mux.Vars(r)["code"]
+	if !h.authorize(w, r, "GetByBarcode", code, rbac.PermStockRead) {
+		return
+	}
+	req := &objects.GetByBarcodeRequest{Barcode: code}
+	if Validate(w, req) != nil {
+		return
+	}
+	evt, err := h.identifiers.GetByBarcode(r.Context(), req)
+	writeStock(w, r, evt, err)
+}
+
+func (h *identifierHandler) GetByExternalID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if !h.authorize(w, r, "GetByExternalID", vars["external_id"], rbac.PermStockRead) {
+		return
+	}
+	req := &objects.ExternalIDRequest{System: vars["system"], ExternalID: vars["external_id"]}
+	if Validate(w, req) != nil {
+		return
+	}
+	evt, err := h.identifiers.GetByExternalID(r.Context(), req)
+	writeStock(w, r, evt, err)
+}
+
+func (h *identifierHandler) SetIdentifiers(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "SetIdentifiers", id, rbac.PermStockDetails) {
+		return
+	}
+	req := &objects.SetIdentifiersRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
+		return
+	}
+	req.ID = id
+	if Validate(w, req) != nil {
+		return
+	}
+	evt, err := h.identifiers.SetIdentifiers(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.IdentifiersResponseWrapper{Stock: evt})
+}
+
+func (h *identifierHandler) CreateExternalID(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "CreateExternalID", id, rbac.PermStockDetails) {
+		return
+	}
+	req := &objects.CreateExternalIDRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
+		return
+	}
+	req.StockID = id
+	if Validate(w, req) != nil {
+		return
+	}
+	ext, err := h.identifiers.CreateExternalID(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.IdentifiersResponseWrapper{ExternalID: ext, Code: http.StatusCreated})
+}
+
+func (h *identifierHandler) ListExternalIDs(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "ListExternalIDs", id, rbac.PermStockRead) {
+		return
+	}
+	req := &objects.ListExternalIDsRequest{StockID: id}
+	if Validate(w, req) != nil {
+		return
+	}
+	list, err := h.identifiers.ListExternalIDs(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.IdentifiersResponseWrapper{ExternalIDs: list})
+}
+
+func (h *identifierHandler) DeleteExternalID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if !h.authorize(w, r, "DeleteExternalID", vars["id"], rbac.PermStockDetails) {
+		return
+	}
+	req := &objects.ExternalIDRequest{StockID: vars["id"], System: vars["system"], ExternalID: vars["external_id"]}
+	if Validate(w, req) != nil {
+		return
+	}
+	if err := h.identifiers.DeleteExternalID(r.Context(), req); err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.IdentifiersResponseWrapper{})
+}
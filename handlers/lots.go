@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/rbac"
+	"go-inventory/store"
+
+	"github.com/gorilla/mux"
+)
+
+// ILotHandler handlers of the lots of stocks
+type ILotHandler interface {
+	CreateLot(w http.ResponseWriter, r *http.Request)
+	GetLot(w http.ResponseWriter, r *http.Request)
+	ListLots(w http.ResponseWriter, r *http.Request)
+	ListExpiringLots(w http.ResponseWriter, r *http.Request)
+}
+
+type lotHandler struct {
+	handler
+	lots store.ILotStore
+}
+
+// lotStatuses statuses accepted by the `status` filter
+var lotStatuses = map[string]bool{
+	objects.LotActive:      true,
+	objects.LotQuarantined: true,
+}
+
+// NewLotHandler return current ILotHandler implementation
+func NewLotHandler(st store.ILotStore, opts ...Option) ILotHandler {
+	h := &lotHandler{handler: handler{maxBody: DefaultMaxBodySize}, lots: st}
+	for _, opt := range opts {
+		opt(&h.handler)
+	}
+	return h
+}
+
+func (h *lotHandler) CreateLot(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "CreateLot", id, rbac.PermStockAvailability) {
+		return
+	}
+	req := &objects.CreateLotRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
+		return
+	}
+	req.StockID = id
+	if Validate(w, req) != nil {
+		return
+	}
+	if req.ManufacturedOn != nil && req.ManufacturedOn.After(req.ExpiresOn) {
+		WriteError(w, errors.ErrValidation.WithField("manufactured_on", "after_expiry", "must not be after expires_on"))
+		return
+	}
+	lot, err := h.lots.CreateLot(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.LotsResponseWrapper{Lot: lot, Code: http.StatusCreated})
+}
+
+func (h *lotHandler) GetLot(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "GetLot", id, rbac.PermStockRead) {
+		return
+	}
+	req := &objects.GetRequest{ID: id}
+	if Validate(w, req) != nil {
+		return
+	}
+	lot, err := h.lots.GetLot(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.LotsResponseWrapper{Lot: lot})
+}
+
+func (h *lotHandler) ListLots(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "ListLots", id, rbac.PermStockRead) {
+		return
+	}
+	values := r.URL.Query()
+	limit, err := IntFromString(w, values.Get("limit"))
+	if err != nil {
+		return
+	}
+	req := &objects.ListLotsRequest{StockID: id, Limit: limit, Status: values.Get("status")}
+	if req.Status != "" && !lotStatuses[req.Status] {
+		WriteError(w, errors.ErrValidation.WithField("status", "unknown_status", "unknown lot status "+req.Status))
+		return
+	}
+	if Validate(w, req) != nil {
+		return
+	}
+	list, err := h.lots.ListLots(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.LotsResponseWrapper{Lots: list})
+}
+
+func (h *lotHandler) ListExpiringLots(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "ListExpiringLots", "", rbac.PermStockRead) {
+		return
+	}
+	values := r.URL.Query()
+	limit, err := IntFromString(w, values.Get("limit"))
+	if err != nil {
+		return
+	}
+	req := &objects.ListExpiringLotsRequest{Limit: limit}
+	if days := values.Get("days"); days != "" {
+		req.Days, err = strconv.Atoi(days)
+		if err != nil {
+			WriteError(w, errors.ErrValidation.WithField("days", "invalid_days", "days should be an integral value"))
+			return
+		}
+	}
+	if Validate(w, req) != nil {
+		return
+	}
+	list, err := h.lots.ListExpiringLots(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.LotsResponseWrapper{Lots: list})
+}
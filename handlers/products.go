@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go-inventory/objects"
+	"go-inventory/rbac"
+	"go-inventory/store"
+
+	"github.com/gorilla/mux"
+)
+
+// IProductHandler handlers of products and their variants
+type IProductHandler interface {
+	CreateProduct(w http.ResponseWriter, r *http.Request)
+	GetProduct(w http.ResponseWriter, r *http.Request)
+	ListProducts(w http.ResponseWriter, r *http.Request)
+	CreateVariant(w http.ResponseWriter, r *http.Request)
+	ListVariants(w http.ResponseWriter, r *http.Request)
+	ProductAvailability(w http.ResponseWriter, r *http.Request)
+}
+
+type productHandler struct {
+	handler
+	products store.IProductStore
+}
+
+// NewProductHandler return current IProductHandler implementation
+func NewProductHandler(st store.IProductStore, opts ...Option) IProductHandler {
+	h := &productHandler{handler: handler{maxBody: DefaultMaxBodySize}, products: st}
+	for _, opt := range opts {
+		opt(&h.handler)
+	}
+	return h
+}
+
+func (h *productHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "CreateProduct", "", rbac.PermStockCreate) {
+		return
+	}
+	req := &objects.CreateProductRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
+		return
+	}
+	if Validate(w, req) != nil {
+		return
+	}
+	product, err := h.products.CreateProduct(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.ProductsResponseWrapper{Product: product, Code: http.StatusCreated})
+}
+
+func (h *productHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "GetProduct", id, rbac.PermStockRead) {
+		return
+	}
+	req := &objects.GetRequest{ID: id}
+	if Validate(w, req) != nil {
+		return
+	}
+	product, err := h.products.GetProduct(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.ProductsResponseWrapper{Product: product})
+}
+
+func (h *productHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "ListProducts", "", rbac.PermStockRead) {
+		return
+	}
+	values := r.URL.Query()
+	limit, err := IntFromString(w, values.Get("limit"))
+	if err != nil {
+		return
+	}
+	req := &objects.ListRequest{Limit: limit, After: values.Get("after"), Name: values.Get("name")}
+	if Validate(w, req) != nil {
+		return
+	}
+	list, err := h.products.ListProducts(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.ProductsResponseWrapper{Products: list})
+}
+
+func (h *productHandler) CreateVariant(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "CreateVariant", id, rbac.PermStockCreate) {
+		return
+	}
+	req := &objects.CreateVariantRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
+		return
+	}
+	req.ProductID = id
+	if Validate(w, req) != nil {
+		return
+	}
+	variant, err := h.products.CreateVariant(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.ProductsResponseWrapper{Variant: variant, Code: http.StatusCreated})
+}
+
+func (h *productHandler) ListVariants(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "ListVariants", id, rbac.PermStockRead) {
+		return
+	}
+	values := r.URL.Query()
+	limit, err := IntFromString(w, values.Get("limit"))
+	if err != nil {
+		return
+	}
+	req := &objects.ListVariantsRequest{ProductID: id, Limit: limit, After: values.Get("after")}
+	if Validate(w, req) != nil {
+		return
+	}
+	list, err := h.products.ListVariants(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.ProductsResponseWrapper{Variants: list})
+}
+
+func (h *productHandler) ProductAvailability(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "ProductAvailability", id, rbac.PermStockRead) {
+		return
+	}
+	req := &objects.GetRequest{ID: id}
+	if Validate(w, req) != nil {
+		return
+	}
+	availability, err := h.products.ProductAvailability(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.ProductsResponseWrapper{Availability: availability})
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/rbac"
+	"go-inventory/store"
+
+	"github.com/gorilla/mux"
+)
+
+// IPurchasingHandler handlers of suppliers and purchase orders
+type IPurchasingHandler interface {
+	CreateSupplier(w http.ResponseWriter, r *http.Request)
+	GetSupplier(w http.ResponseWriter, r *http.Request)
+	ListSuppliers(w http.ResponseWriter, r *http.Request)
+	CreatePurchaseOrder(w http.ResponseWriter, r *http.Request)
+	GetPurchaseOrder(w http.ResponseWriter, r *http.Request)
+	ListPurchaseOrders(w http.ResponseWriter, r *http.Request)
+	SubmitPurchaseOrder(w http.ResponseWriter, r *http.Request)
+	ReceivePurchaseOrder(w http.ResponseWriter, r *http.Request)
+}
+
+type purchasingHandler struct {
+	handler
+	purchasing store.IPurchasingStore
+}
+
+// purchaseOrderStatuses statuses accepted by the `status` filter
+var purchaseOrderStatuses = map[string]bool{
+	objects.PurchaseOrderDraft:             true,
+	objects.PurchaseOrderSubmitted:         true,
+	objects.PurchaseOrderPartiallyReceived: true,
+	objects.PurchaseOrderReceived:          true,
+}
+
+// NewPurchasingHandler return current IPurchasingHandler implementation
+func NewPurchasingHandler(st store.IPurchasingStore, opts ...Option) IPurchasingHandler {
+	h := &purchasingHandler{handler: handler{maxBody: DefaultMaxBodySize}, purchasing: st}
+	for _, opt := range opts {
+		opt(&h.handler)
+	}
+	return h
+}
+
+func (h *purchasingHandler) CreateSupplier(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "CreateSupplier", "", rbac.PermPurchasingManage) {
+		return
+	}
+	sup := &objects.Supplier{}
+	if Decode(w, r, sup, h.maxBody) != nil {
+		return
+	}
+	if Validate(w, sup) != nil {
+		return
+	}
+	if err := h.purchasing.CreateSupplier(r.Context(), sup); err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.PurchasingResponseWrapper{Supplier: sup, Code: http.StatusCreated})
+}
+
+func (h *purchasingHandler) GetSupplier(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "GetSupplier", id, rbac.PermPurchasingRead) {
+		return
+	}
+	req := &objects.GetRequest{ID: id}
+	if Validate(w, req) != nil {
+		return
+	}
+	sup, err := h.purchasing.GetSupplier(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.PurchasingResponseWrapper{Supplier: sup})
+}
+
+func (h *purchasingHandler) ListSuppliers(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "ListSuppliers", "", rbac.PermPurchasingRead) {
+		return
+	}
+	values := r.URL.Query()
+	limit, err := IntFromString(w, values.Get("limit"))
+	if err != nil {
+		return
+	}
+	req := &objects.ListRequest{
+		Limit: limit,
+		After: values.Get("after"),
+		Name:  values.Get("name"),
+	}
+	if Validate(w, req) != nil {
+		return
+	}
+	list, err := h.purchasing.ListSuppliers(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.PurchasingResponseWrapper{Suppliers: list})
+}
+
+func (h *purchasingHandler) CreatePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "CreatePurchaseOrder", "", rbac.PermPurchasingManage) {
+		return
+	}
+	req := &objects.CreatePurchaseOrderRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
+		return
+	}
+	if Validate(w, req) != nil {
+		return
+	}
+	order, err := h.purchasing.CreatePurchaseOrder(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.PurchasingResponseWrapper{PurchaseOrder: order, Code: http.StatusCreated})
+}
+
+func (h *purchasingHandler) GetPurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "GetPurchaseOrder", id, rbac.PermPurchasingRead) {
+		return
+	}
+	req := &objects.GetRequest{ID: id}
+	if Validate(w, req) != nil {
+		return
+	}
+	order, err := h.purchasing.GetPurchaseOrder(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.PurchasingResponseWrapper{PurchaseOrder: order})
+}
+
+func (h *purchasingHandler) ListPurchaseOrders(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "ListPurchaseOrders", "", rbac.PermPurchasingRead) {
+		return
+	}
+	values := r.URL.Query()
+	limit, err := IntFromString(w, values.Get("limit"))
+	if err != nil {
+		return
+	}
+	req := &objects.ListPurchaseOrdersRequest{
+		Limit:      limit,
+		After:      values.Get("after"),
+		Status:     values.Get("status"),
+		SupplierID: values.Get("supplier_id"),
+	}
+	if req.Status != "" && !purchaseOrderStatuses[req.Status] {
+		WriteError(w, errors.ErrValidation.WithField("status", "unknown_status", "unknown purchase order status "+req.Status))
+		return
+	}
+	if Validate(w, req) != nil {
+		return
+	}
+	list, err := h.purchasing.ListPurchaseOrders(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.PurchasingResponseWrapper{PurchaseOrders: list})
+}
+
+func (h *purchasingHandler) SubmitPurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "SubmitPurchaseOrder", id, rbac.PermPurchasingManage) {
+		return
+	}
+	req := &objects.SubmitPurchaseOrderRequest{ID: id}
+	if Validate(w, req) != nil {
+		return
+	}
+	order, err := h.purchasing.SubmitPurchaseOrder(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.PurchasingResponseWrapper{PurchaseOrder: order})
+}
+
+func (h *purchasingHandler) ReceivePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "ReceivePurchaseOrder", id, rbac.PermPurchasingReceive) {
+		return
+	}
+	req := &objects.ReceivePurchaseOrderRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
+		return
+	}
+	req.ID = id
+	if Validate(w, req) != nil {
+		return
+	}
+	order, err := h.purchasing.ReceivePurchaseOrder(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.PurchasingResponseWrapper{PurchaseOrder: order})
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/rbac"
+	"go-inventory/store"
+
+	"github.com/gorilla/mux"
+)
+
+// IReturnsHandler handlers of customer returns and stock movements
+type IReturnsHandler interface {
+	CreateReturn(w http.ResponseWriter, r *http.Request)
+	GetReturn(w http.ResponseWriter, r *http.Request)
+	ListReturns(w http.ResponseWriter, r *http.Request)
+	InspectReturn(w http.ResponseWriter, r *http.Request)
+	ListMovements(w http.ResponseWriter, r *http.Request)
+}
+
+type returnsHandler struct {
+	handler
+	returns   store.IReturnsStore
+	movements store.IMovementStore
+}
+
+// returnStatuses statuses accepted by the `status` filter
+var returnStatuses = map[string]bool{
+	objects.ReturnOpen:               true,
+	objects.ReturnPartiallyInspected: true,
+	objects.ReturnCompleted:          true,
+}
+
+// inspectionOutcomes outcomes accepted by an inspection
+var inspectionOutcomes = map[string]bool{
+	objects.OutcomeRestock:   true,
+	objects.OutcomeRefurbish: true,
+	objects.OutcomeScrap:     true,
+}
+
+// NewReturnsHandler return current IReturnsHandler implementation
+func NewReturnsHandler(returns store.IReturnsStore, movements store.IMovementStore, opts ...Option) IReturnsHandler {
+	h := &returnsHandler{handler: handler{maxBody: DefaultMaxBodySize}, returns: returns, movements: movements}
+	for _, opt := range opts {
+		opt(&h.handler)
+	}
+	return h
+}
+
+func (h *returnsHandler) CreateReturn(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "CreateReturn", "", rbac.PermReturnsManage) {
+		return
+	}
+	req := &objects.CreateReturnRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
+		return
+	}
+	if Validate(w, req) != nil {
+		return
+	}
+	ret, err := h.returns.CreateReturn(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.ReturnsResponseWrapper{Return: ret, Code: http.StatusCreated})
+}
+
+func (h *returnsHandler) GetReturn(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "GetReturn", id, rbac.PermReturnsRead) {
+		return
+	}
+	req := &objects.GetRequest{ID: id}
+	if Validate(w, req) != nil {
+		return
+	}
+	ret, err := h.returns.GetReturn(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.ReturnsResponseWrapper{Return: ret})
+}
+
+func (h *returnsHandler) ListReturns(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "ListReturns", "", rbac.PermReturnsRead) {
+		return
+	}
+	values := r.URL.Query()
+	limit, err := IntFromString(w, values.Get("limit"))
+	if err != nil {
+		return
+	}
+	req := &objects.ListReturnsRequest{
+		Limit:        limit,
+		After:        values.Get("after"),
+		Status:       values.Get("status"),
+		SalesOrderID: values.Get("sales_order_id"),
+	}
+	if req.Status != "" && !returnStatuses[req.Status] {
+		WriteError(w, errors.ErrValidation.WithField("status", "unknown_status", "unknown return status "+req.Status))
+		return
+	}
+	if Validate(w, req) != nil {
+		return
+	}
+	list, err := h.returns.ListReturns(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.ReturnsResponseWrapper{Returns: list})
+}
+
+func (h *returnsHandler) InspectReturn(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "InspectReturn", id, rbac.PermReturnsInspect) {
+		return
+	}
+	req := &objects.InspectReturnRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
+		return
+	}
+	req.ID = id
+	if Validate(w, req) != nil {
+		return
+	}
+	for i, l := range req.Lines {
+		if l != nil && !inspectionOutcomes[l.Outcome] {
+			field := "lines[" + strconv.Itoa(i) + "].outcome"
+			WriteError(w, errors.ErrValidation.WithField(field, "unknown_outcome", "unknown outcome "+l.Outcome))
+			return
+		}
+	}
+	ret, movements, err := h.returns.InspectReturn(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.ReturnsResponseWrapper{Return: ret, Movements: movements})
+}
+
+func (h *returnsHandler) ListMovements(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "ListMovements", "", rbac.PermStockRead) {
+		return
+	}
+	values := r.URL.Query()
+	limit, err := IntFromString(w, values.Get("limit"))
+	if err != nil {
+		return
+	}
+	req := &objects.ListMovementsRequest{
+		Limit:     limit,
+		StockID:   values.Get("stock_id"),
+		Reference: values.Get("reference"),
+		Type:      values.Get("type"),
+	}
+	if after := values.Get("after"); after != "" {
+		req.After, err = strconv.ParseInt(after, 10, 64)
+		if err != nil {
+			WriteError(w, errors.ErrValidation.WithField("after", "invalid_after", "after should be a sequence number"))
+			return
+		}
+	}
+	if Validate(w, req) != nil {
+		return
+	}
+	list, err := h.movements.ListMovements(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.ReturnsResponseWrapper{Movements: list})
+}
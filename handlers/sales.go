@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/rbac"
+	"go-inventory/store"
+
+	"github.com/gorilla/mux"
+)
+
+// ISalesHandler handlers of sales orders
+type ISalesHandler interface {
+	CreateSalesOrder(w http.ResponseWriter, r *http.Request)
+	GetSalesOrder(w http.ResponseWriter, r *http.Request)
+	ListSalesOrders(w http.ResponseWriter, r *http.Request)
+	CancelSalesOrder(w http.ResponseWriter, r *http.Request)
+}
+
+type salesHandler struct {
+	handler
+	sales store.ISalesStore
+}
+
+// salesOrderStatuses statuses accepted by the `status` filter
+var salesOrderStatuses = map[string]bool{
+	objects.SalesOrderAllocated:   true,
+	objects.SalesOrderBackordered: true,
+	objects.SalesOrderCancelled:   true,
+}
+
+// NewSalesHandler return current ISalesHandler implementation
+func NewSalesHandler(st store.ISalesStore, opts ...Option) ISalesHandler {
+	h := &salesHandler{handler: handler{maxBody: DefaultMaxBodySize}, sales: st}
+	for _, opt := range opts {
+		opt(&h.handler)
+	}
+	return h
+}
+
+func (h *salesHandler) CreateSalesOrder(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "CreateSalesOrder", "", rbac.PermOrdersManage) {
+		return
+	}
+	req := &objects.CreateSalesOrderRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
+		return
+	}
+	if Validate(w, req) != nil {
+		return
+	}
+	order, err := h.sales.CreateSalesOrder(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.SalesResponseWrapper{SalesOrder: order, Code: http.StatusCreated})
+}
+
+func (h *salesHandler) GetSalesOrder(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "GetSalesOrder", id, rbac.PermOrdersRead) {
+		return
+	}
+	req := &objects.GetRequest{ID: id}
+	if Validate(w, req) != nil {
+		return
+	}
+	order, err := h.sales.GetSalesOrder(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.SalesResponseWrapper{SalesOrder: order})
+}
+
+func (h *salesHandler) ListSalesOrders(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "ListSalesOrders", "", rbac.PermOrdersRead) {
+		return
+	}
+	values := r.URL.Query()
+	limit, err := IntFromString(w, values.Get("limit"))
+	if err != nil {
+		return
+	}
+	req := &objects.ListSalesOrdersRequest{
+		Limit:     limit,
+		After:     values.Get("after"),
+		Status:    values.Get("status"),
+		Reference: values.Get("reference"),
+	}
+	if req.Status != "" && !salesOrderStatuses[req.Status] {
+		WriteError(w, errors.ErrValidation.WithField("status", "unknown_status", "unknown sales order status "+req.Status))
+		return
+	}
+	if Validate(w, req) != nil {
+		return
+	}
+	list, err := h.sales.ListSalesOrders(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.SalesResponseWrapper{SalesOrders: list})
+}
+
+func (h *salesHandler) CancelSalesOrder(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "CancelSalesOrder", id, rbac.PermOrdersManage) {
+		return
+	}
+	req := &objects.CancelSalesOrderRequest{ID: id}
+	if Validate(w, req) != nil {
+		return
+	}
+	order, err := h.sales.CancelSalesOrder(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.SalesResponseWrapper{SalesOrder: order})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/rbac"
+	"go-inventory/store"
+
+	"github.com/gorilla/mux"
+)
+
+// ISerialHandler handlers of the serial numbers of serialized stocks
+type ISerialHandler interface {
+	GetSerial(w http.ResponseWriter, r *http.Request)
+	ListSerials(w http.ResponseWriter, r *http.Request)
+}
+
+type serialHandler struct {
+	handler
+	serials store.ISerialStore
+}
+
+// serialStatuses statuses accepted by the `status` filter
+var serialStatuses = map[string]bool{
+	objects.SerialInStock:   true,
+	objects.SerialAllocated: true,
+}
+
+// NewSerialHandler return current ISerialHandler implementation
+func NewSerialHandler(st store.ISerialStore, opts ...Option) ISerialHandler {
+	h := &serialHandler{handler: handler{maxBody: DefaultMaxBodySize}, serials: st}
+	for _, opt := range opts {
+		opt(&h.handler)
+	}
+	return h
+}
+
+func (h *serialHandler) GetSerial(w http.ResponseWriter, r *http.Request) {
+	sn := mux.Vars(r)["sn"]
+	if !h.authorize(w, r, "GetSerial", sn, rbac.PermStockRead) {
+		return
+	}
+	req := &objects.GetSerialRequest{SerialNumber: sn}
+	if Validate(w, req) != nil {
+		return
+	}
+	serial, movements, err := h.serials.GetSerial(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.SerialsResponseWrapper{Serial: serial, Movements: movements})
+}
+
+func (h *serialHandler) ListSerials(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "ListSerials", id, rbac.PermStockRead) {
+		return
+	}
+	values := r.URL.Query()
+	limit, err := IntFromString(w, values.Get("limit"))
+	if err != nil {
+		return
+	}
+	req := &objects.ListSerialsRequest{StockID: id, Limit: limit, After: values.Get("after"), Status: values.Get("status")}
+	if req.Status != "" && !serialStatuses[req.Status] {
+		WriteError(w, errors.ErrValidation.WithField("status", "unknown_status", "unknown serial status "+req.Status))
+		return
+	}
+	if Validate(w, req) != nil {
+		return
+	}
+	list, err := h.serials.ListSerials(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.SerialsResponseWrapper{Serials: list})
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/rbac"
+	"go-inventory/store"
+
+	"github.com/gorilla/mux"
+)
+
+// IUnitHandler handlers of the units of measure of stocks
+type IUnitHandler interface {
+	SetUnits(w http.ResponseWriter, r *http.Request)
+}
+
+type unitHandler struct {
+	handler
+	units store.IUnitStore
+}
+
+// NewUnitHandler return current IUnitHandler implementation
+func NewUnitHandler(st store.IUnitStore, opts ...Option) IUnitHandler {
+	h := &unitHandler{handler: handler{maxBody: DefaultMaxBodySize}, units: st}
+	for _, opt := range opts {
+		opt(&h.handler)
+	}
+	return h
+}
+
+func (h *unitHandler) SetUnits(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "SetUnits", id, rbac.PermStockDetails) {
+		return
+	}
+	req := &objects.SetUnitsRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
+		return
+	}
+	req.ID = id
+	if Validate(w, req) != nil {
+		return
+	}
+	evt, err := h.units.SetUnits(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.UnitsResponseWrapper{Stock: evt})
+}
+
+// convertUnits expresses the quantities of list in unit as well, the stocks
+// without unit are left as they are
+func convertUnits(unit string, list ...*objects.Stock) {
+	if unit == "" {
+		return
+	}
+	for _, evt := range list {
+		evt.Convert(unit)
+	}
+}
+
+// writeStock writes the stock of a read, in the unit of the `unit` query parameter as well
+func writeStock(w http.ResponseWriter, r *http.Request, evt *objects.Stock, err error) {
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if unit := r.URL.Query().Get("unit"); unit != "" && !evt.Convert(unit) {
+		WriteError(w, errors.ErrValidation.WithField("unit", "unknown_unit", "the stock has no unit "+unit))
+		return
+	}
+	WriteResponse(w, &objects.StockResponseWrapper{Stock: evt})
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/rbac"
+	"go-inventory/store"
+
+	"github.com/gorilla/mux"
+)
+
+// IWebhookHandler handlers managing webhook subscriptions and their deliveries
+type IWebhookHandler interface {
+	CreateWebhook(w http.ResponseWriter, r *http.Request)
+	ListWebhooks(w http.ResponseWriter, r *http.Request)
+	DeleteWebhook(w http.ResponseWriter, r *http.Request)
+	ListDeliveries(w http.ResponseWriter, r *http.Request)
+	Redeliver(w http.ResponseWriter, r *http.Request)
+}
+
+type webhookHandler struct {
+	handler
+	webhooks store.IWebhookStore
+}
+
+// deliveryStatuses statuses accepted by the `status` filter
+var deliveryStatuses = map[string]bool{
+	objects.DeliveryPending:   true,
+	objects.DeliveryDelivered: true,
+	objects.DeliveryDead:      true,
+}
+
+// NewWebhookHandler return current IWebhookHandler implementation
+func NewWebhookHandler(st store.IWebhookStore, opts ...Option) IWebhookHandler {
+	h := &webhookHandler{handler: handler{maxBody: DefaultMaxBodySize}, webhooks: st}
+	for _, opt := range opts {
+		opt(&h.handler)
+	}
+	return h
+}
+
+func (h *webhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "CreateWebhook", "", rbac.PermWebhooksManage) {
+		return
+	}
+	req := &objects.CreateWebhookRequest{}
+	if Decode(w, r, req, h.maxBody) != nil {
+		return
+	}
+	if Validate(w, req) != nil {
+		return
+	}
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		WriteError(w, errors.ErrInvalidWebhookURL)
+		return
+	}
+	for _, t := range req.Events {
+		if !eventTypes[t] {
+			WriteError(w, errors.ErrValidation.WithField("events", "unknown_type", "unknown event type "+t))
+			return
+		}
+	}
+	sub := &objects.WebhookSubscription{URL: req.URL, Secret: req.Secret, Events: req.Events}
+	if err := h.webhooks.CreateWebhook(r.Context(), sub); err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.WebhookResponseWrapper{Webhook: sub, Code: http.StatusCreated})
+}
+
+func (h *webhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "ListWebhooks", "", rbac.PermWebhooksManage) {
+		return
+	}
+	list, err := h.webhooks.ListWebhooks(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.WebhookResponseWrapper{Webhooks: list})
+}
+
+func (h *webhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "DeleteWebhook", id, rbac.PermWebhooksManage) {
+		return
+	}
+	req := &objects.DeleteRequest{ID: id}
+	if Validate(w, req) != nil {
+		return
+	}
+	if err := h.webhooks.DeleteWebhook(r.Context(), req); err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.WebhookResponseWrapper{})
+}
+
+func (h *webhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "ListDeliveries", "", rbac.PermWebhooksManage) {
+		return
+	}
+	values := r.URL.Query()
+	limit, err := IntFromString(w, values.Get("limit"))
+	if err != nil {
+		return
+	}
+	req := &objects.ListDeliveriesRequest{Status: values.Get("status"), Limit: limit}
+	if req.Status != "" && !deliveryStatuses[req.Status] {
+		WriteError(w, errors.ErrValidation.WithField("status", "unknown_status", "unknown delivery status "+req.Status))
+		return
+	}
+	if Validate(w, req) != nil {
+		return
+	}
+	list, err := h.webhooks.ListDeliveries(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.WebhookResponseWrapper{Deliveries: list})
+}
+
+func (h *webhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, "Redeliver", id, rbac.PermWebhooksManage) {
+		return
+	}
+	req := &objects.RedeliverRequest{ID: id}
+	if Validate(w, req) != nil {
+		return
+	}
+	d, err := h.webhooks.Redeliver(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteResponse(w, &objects.WebhookResponseWrapper{Delivery: d, Code: http.StatusAccepted})
+}
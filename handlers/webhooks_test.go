@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-inventory/objects"
+	"go-inventory/store"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateWebhook(t *testing.T) {
+	hnd := NewWebhookHandler(store.NewMemoryStores().Webhooks)
+	router := mux.NewRouter()
+	router.HandleFunc("/webhooks", hnd.CreateWebhook).Methods(http.MethodPost)
+	router.HandleFunc("/webhooks", hnd.ListWebhooks).Methods(http.MethodGet)
+
+	tests := []struct {
+		name string
+		req  *objects.CreateWebhookRequest
+		code int
+	}{
+		{"Ok", &objects.CreateWebhookRequest{URL: "https://example.com/hook", Secret: "s3cret", Events: []string{objects.EventStockCreated}}, http.StatusCreated},
+		{"Every event", &objects.CreateWebhookRequest{URL: "http://example.com/hook", Secret: "s3cret"}, http.StatusCreated},
+		{"No secret", &objects.CreateWebhookRequest{URL: "https://example.com/hook"}, http.StatusBadRequest},
+		{"Bad scheme", &objects.CreateWebhookRequest{URL: "ftp://example.com/hook", Secret: "s3cret"}, http.StatusBadRequest},
+		{"Relative url", &objects.CreateWebhookRequest{URL: "/hook", Secret: "s3cret"}, http.StatusBadRequest},
+		{"Unknown event", &objects.CreateWebhookRequest{URL: "https://example.com/hook", Secret: "s3cret", Events: []string{"stock.eaten"}}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := json.Marshal(tt.req)
+			req := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(b))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.code, w.Code)
+			// the secret is never returned
+			assert.NotContains(t, w.Body.String(), "s3cret")
+		})
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/webhooks", nil))
+	got := &objects.WebhookResponseWrapper{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), got))
+	assert.Len(t, got.Webhooks, 2)
+}
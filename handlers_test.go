@@ -294,6 +294,7 @@ func TestCreateEndpoint(t *testing.T) {
 			if err != nil {
 				t.Fatal(err)
 			}
+			req.Header.Set("Content-Type", "application/json")
 			w := Do(req)
 			got, gotErr := &objects.StockResponseWrapper{}, &errors.Error{}
 			assert.Equal(t, tt.code, w.Code)
@@ -323,8 +324,9 @@ func TestUpdateDetailsEndpoint(t *testing.T) {
 		)
 		if evt != nil {
 			b, err = json.Marshal(&objects.UpdateDetailsRequest{
-				ID:   evt.ID,
-				Name: evt.Name,
+				ID:    evt.ID,
+				Name:  evt.Name,
+				Price: 1,
 			})
 			if err != nil {
 				t.Fatal(err)
@@ -334,6 +336,7 @@ func TestUpdateDetailsEndpoint(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
+		req.Header.Set("Content-Type", "application/json")
 		return req, evt
 	}
 	tests := []struct {
@@ -356,7 +359,7 @@ func TestUpdateDetailsEndpoint(t *testing.T) {
 			name: "NotFound",
 			setup: func(t *testing.T) (*http.Request, *objects.Stock) {
 				evt := createOne(t, "Ok")
-				evt.ID = ""
+				evt.ID = "fake"
 				return reqFn(t, evt)
 			},
 			message: errors.ErrStockNotFound.Message,
@@ -0,0 +1,71 @@
+package lots
+
+import (
+	"context"
+	"time"
+
+	"go-inventory/store"
+	"go-inventory/util/logger"
+)
+
+// Config of a Quarantiner, zero values are replaced by the defaults
+type Config struct {
+	// how often expired lots are looked for
+	PollInterval time.Duration
+	// lots quarantined at once
+	BatchSize int
+}
+
+// DefaultConfig of a Quarantiner
+var DefaultConfig = Config{
+	PollInterval: time.Minute,
+	BatchSize:    100,
+}
+
+// Quarantiner quarantines the lots of every tenant once they expire
+type Quarantiner struct {
+	store store.ILotStore
+	cfg   Config
+	log   *logger.Logger
+}
+
+// NewQuarantiner returns a Quarantiner of the lots of st
+func NewQuarantiner(st store.ILotStore, cfg Config, log *logger.Logger) *Quarantiner {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultConfig.PollInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultConfig.BatchSize
+	}
+	return &Quarantiner{store: st, cfg: cfg, log: log}
+}
+
+// Run quarantines expired lots until ctx is done
+func (q *Quarantiner) Run(ctx context.Context) {
+	ticker := time.NewTicker(q.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		// drain the backlog before waiting
+		n, err := q.Quarantine(ctx)
+		if err != nil {
+			q.log.Error().Err(err).Msg("lot quarantine failed")
+		}
+		if err == nil && n == q.cfg.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Quarantine quarantines one batch of expired lots and returns how many were quarantined
+func (q *Quarantiner) Quarantine(ctx context.Context) (int, error) {
+	n, err := q.store.QuarantineExpiredLots(ctx, q.cfg.BatchSize)
+	if n > 0 {
+		q.log.Warn().Int("lots", n).Msg("expired lots quarantined")
+	}
+	return n, err
+}
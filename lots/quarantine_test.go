@@ -0,0 +1,126 @@
+package lots
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/store"
+	"go-inventory/util/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuarantine(t *testing.T) {
+	stores := store.NewMemoryStores()
+	ctx := context.Background()
+	evt := &objects.Stock{Name: "Meat Ball", Price: 1, IsActive: true}
+	assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: evt}))
+	now := time.Now()
+	receive := func(number string, days, qty int) *objects.Lot {
+		lot, err := stores.Lots.CreateLot(ctx, &objects.CreateLotRequest{
+			StockID: evt.ID, LotNumber: number, ExpiresOn: now.AddDate(0, 0, days), Quantity: qty,
+		})
+		assert.Nil(t, err)
+		return lot
+	}
+	expired := receive("L1", -1, 3)
+	late := receive("L3", 20, 4)
+	soon := receive("L2", 10, 5)
+	stock := func() *objects.Stock {
+		got, _ := stores.Stocks.Get(ctx, &objects.GetRequest{ID: evt.ID})
+		return got
+	}
+	assert.Equal(t, 12, stock().Availability)
+
+	// the order quarantines the expired lot before allocating, first expired first out
+	order, err := stores.Sales.CreateSalesOrder(ctx, &objects.CreateSalesOrderRequest{
+		Lines: []*objects.SalesOrderLineRequest{{StockID: evt.ID, Quantity: 6}},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, order.Picks, 2)
+	assert.Equal(t, soon.ID, order.Picks[0].LotID)
+	assert.Equal(t, 5, order.Picks[0].Quantity)
+	assert.Equal(t, late.ID, order.Picks[1].LotID)
+	assert.Equal(t, 1, order.Picks[1].Quantity)
+	got, _ := stores.Lots.GetLot(ctx, &objects.GetRequest{ID: expired.ID})
+	assert.Equal(t, objects.LotQuarantined, got.Status)
+	assert.Equal(t, 3, stock().Availability)
+
+	// expired units are never sold, even before the sweep
+	_, err = stores.Sales.CreateSalesOrder(ctx, &objects.CreateSalesOrderRequest{
+		Lines: []*objects.SalesOrderLineRequest{{StockID: evt.ID, Quantity: 4}},
+	})
+	assert.ErrorIs(t, err, errors.ErrAllocationFailed)
+
+	stale := receive("L0", -2, 2)
+	expiring, err := stores.Lots.ListExpiringLots(ctx, &objects.ListExpiringLotsRequest{Days: 15})
+	assert.Nil(t, err)
+	assert.Len(t, expiring, 1)
+	assert.Equal(t, stale.ID, expiring[0].ID)
+
+	q := NewQuarantiner(stores.Lots, Config{}, logger.New(false))
+	n, err := q.Quarantine(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	n, _ = q.Quarantine(ctx)
+	assert.Equal(t, 0, n)
+	got, _ = stores.Lots.GetLot(ctx, &objects.GetRequest{ID: stale.ID})
+	assert.Equal(t, objects.LotQuarantined, got.Status)
+	assert.Equal(t, 3, stock().Availability)
+
+	// cancelling gives the picked units back to their lots
+	_, err = stores.Sales.CancelSalesOrder(ctx, &objects.CancelSalesOrderRequest{ID: order.ID})
+	assert.Nil(t, err)
+	got, _ = stores.Lots.GetLot(ctx, &objects.GetRequest{ID: soon.ID})
+	assert.Equal(t, 5, got.Quantity)
+	assert.Equal(t, 9, stock().Availability)
+
+	movements, err := stores.Movements.ListMovements(ctx, &objects.ListMovementsRequest{Reference: expired.ID})
+	assert.Nil(t, err)
+	assert.Len(t, movements, 2)
+	assert.Equal(t, objects.MovementLotQuarantine, movements[1].Type)
+	assert.Equal(t, -3, movements[1].AvailabilityDelta)
+}
+
+func TestBackorderPicks(t *testing.T) {
+	stores := store.NewMemoryStores()
+	ctx := context.Background()
+	evt := &objects.Stock{Name: "Meat Ball", Price: 1, IsActive: true, Backorderable: true, BackorderLimit: 10}
+	assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: evt}))
+	order, err := stores.Sales.CreateSalesOrder(ctx, &objects.CreateSalesOrderRequest{
+		Lines: []*objects.SalesOrderLineRequest{{StockID: evt.ID, Quantity: 5}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, objects.SalesOrderBackordered, order.Status)
+
+	// the backorder is picked from the lot received for it, over two receipts
+	receive := func(number string, qty int) *objects.Lot {
+		lot, err := stores.Lots.CreateLot(ctx, &objects.CreateLotRequest{
+			StockID: evt.ID, LotNumber: number, ExpiresOn: time.Now().AddDate(0, 0, 30), Quantity: qty,
+		})
+		assert.Nil(t, err)
+		return lot
+	}
+	first := receive("L1", 3)
+	assert.Equal(t, 0, first.Quantity)
+	second := receive("L2", 4)
+	assert.Equal(t, 2, second.Quantity)
+
+	order, err = stores.Sales.GetSalesOrder(ctx, &objects.GetRequest{ID: order.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, objects.SalesOrderAllocated, order.Status)
+	assert.Len(t, order.Picks, 2)
+	assert.Equal(t, first.ID, order.Picks[0].LotID)
+	assert.Equal(t, 3, order.Picks[0].Quantity)
+	assert.Equal(t, second.ID, order.Picks[1].LotID)
+	assert.Equal(t, 2, order.Picks[1].Quantity)
+
+	// cancelling gives them back
+	_, err = stores.Sales.CancelSalesOrder(ctx, &objects.CancelSalesOrderRequest{ID: order.ID})
+	assert.Nil(t, err)
+	got, _ := stores.Lots.GetLot(ctx, &objects.GetRequest{ID: first.ID})
+	assert.Equal(t, 3, got.Quantity)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"os"
+	"strconv"
 )
 
 func main() {
@@ -20,6 +21,35 @@ func main() {
 	if port := os.Getenv("PORT"); port != "" {
 		args.port = ":" + port
 	}
+	if exp := os.Getenv("TRACE_EXPORTER"); exp != "" {
+		args.traceExporter = exp
+	}
+	if endpoint := os.Getenv("TRACE_ENDPOINT"); endpoint != "" {
+		args.traceEndpoint = endpoint
+	}
+	args.apiKeysFile = os.Getenv("API_KEYS_FILE")
+	args.jwtSecret = os.Getenv("JWT_SECRET")
+	args.jwksFile = os.Getenv("JWT_JWKS_FILE")
+	args.jwtIssuer = os.Getenv("JWT_ISSUER")
+	args.jwtAudience = os.Getenv("JWT_AUDIENCE")
+	args.authDisabled = os.Getenv("AUTH_DISABLED") == "true"
+	args.rowLevelSecurity = os.Getenv("TENANT_RLS") == "true"
+	args.rateLimit = 600
+	if limit := os.Getenv("RATE_LIMIT_PER_MINUTE"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			log.Fatalln("Invalid RATE_LIMIT_PER_MINUTE: ", err)
+		}
+		args.rateLimit = n
+	}
+	if size := os.Getenv("MAX_BODY_SIZE"); size != "" {
+		n, err := strconv.ParseInt(size, 10, 64)
+		if err != nil {
+			log.Fatalln("Invalid MAX_BODY_SIZE: ", err)
+		}
+		args.maxBodySize = n
+	}
+	args.lowStockWebhook = os.Getenv("LOW_STOCK_WEBHOOK_URL")
 	// run server
 	if err := Run(args); err != nil {
 		log.Println(err)
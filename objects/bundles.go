@@ -0,0 +1,74 @@
+package objects
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Bundle a kit sold as one article and built from component stocks, e.g a gift box
+type Bundle struct {
+	ID       string  `gorm:"primary_key" json:"id"`
+	TenantID string  `gorm:"index;not null;default:default" json:"-"`
+	Name     string  `json:"name"`
+	Price    float64 `json:"price"`
+	// bill of materials, ordered by stock id
+	Components []*BundleComponent `gorm:"foreignKey:BundleID" json:"components"`
+	// bundles buildable from the components, computed on read
+	Availability int       `gorm:"-" json:"availability"`
+	CreatedOn    time.Time `json:"created_on"`
+	UpdatedOn    time.Time `json:"updated_on"`
+}
+
+// BundleComponent units of a stock in one Bundle
+type BundleComponent struct {
+	BundleID string `gorm:"primaryKey" json:"-"`
+	StockID  string `gorm:"primaryKey" json:"stock_id"`
+	TenantID string `gorm:"index;not null;default:default" json:"-"`
+	Quantity int    `json:"quantity"`
+}
+
+// CreateBundleRequest to create a Bundle
+type CreateBundleRequest struct {
+	Name       string                    `json:"name" validate:"required,maxlen=255"`
+	Price      float64                   `json:"price" validate:"gt=0"`
+	Components []*BundleComponentRequest `json:"components" validate:"required,maxlen=50"`
+}
+
+// BundleComponentRequest a component of a CreateBundleRequest
+type BundleComponentRequest struct {
+	StockID  string `json:"stock_id" validate:"required"`
+	Quantity int    `json:"quantity" validate:"gt=0,lte=1000000000"`
+}
+
+// SellBundleRequest to sell bundles, every component is allocated in one sales order
+type SellBundleRequest struct {
+	ID        string `json:"-" validate:"required"`
+	Quantity  int    `json:"quantity" validate:"gt=0,lte=1000000000"`
+	Reference string `json:"reference" validate:"maxlen=255"`
+}
+
+// BundlesResponseWrapper reponse of any bundle request
+type BundlesResponseWrapper struct {
+	Bundle     *Bundle     `json:"bundle,omitempty"`
+	Bundles    []*Bundle   `json:"bundles,omitempty"`
+	SalesOrder *SalesOrder `json:"sales_order,omitempty"`
+	Code       int         `json:"-"`
+}
+
+// JSON convert BundlesResponseWrapper in json
+func (e *BundlesResponseWrapper) JSON() []byte {
+	if e == nil {
+		return []byte("{}")
+	}
+	res, _ := json.Marshal(e)
+	return res
+}
+
+// StatusCode return status code
+func (e *BundlesResponseWrapper) StatusCode() int {
+	if e == nil || e.Code == 0 {
+		return http.StatusOK
+	}
+	return e.Code
+}
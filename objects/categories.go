@@ -0,0 +1,103 @@
+package objects
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// MaxTags maximum tags of a stock
+const MaxTags = 20
+
+// Category a node of the category tree of a tenant
+type Category struct {
+	ID       string `gorm:"primary_key" json:"id"`
+	TenantID string `gorm:"uniqueIndex:idx_category_name;not null;default:default" json:"-"`
+	// empty for a root category
+	ParentID string `gorm:"uniqueIndex:idx_category_name" json:"parent_id,omitempty"`
+	// unique among the children of a parent
+	Name      string    `gorm:"uniqueIndex:idx_category_name" json:"name"`
+	CreatedOn time.Time `json:"created_on"`
+	UpdatedOn time.Time `json:"updated_on"`
+}
+
+// CategoryClosure links a category to each of its descendants, itself included at
+// depth 0, a subtree is read without recursion
+type CategoryClosure struct {
+	AncestorID   string `gorm:"primaryKey" json:"ancestor_id"`
+	DescendantID string `gorm:"primaryKey;index" json:"descendant_id"`
+	TenantID     string `gorm:"index;not null;default:default" json:"-"`
+	Depth        int    `json:"depth"`
+}
+
+// CreateCategoryRequest to create a Category
+type CreateCategoryRequest struct {
+	Name string `json:"name" validate:"required,maxlen=255"`
+	// a root category when empty
+	ParentID string `json:"parent_id"`
+}
+
+// UpdateCategoryRequest to rename a Category or move it with its subtree
+type UpdateCategoryRequest struct {
+	ID       string `json:"-" validate:"required"`
+	Name     string `json:"name" validate:"required,maxlen=255"`
+	ParentID string `json:"parent_id"`
+}
+
+// ListCategoriesRequest for retrieving list of Categories, ordered by id
+type ListCategoriesRequest struct {
+	Limit int    `json:"limit" validate:"gte=0"`
+	After string `json:"after"`
+	// optional filter, the children of a category
+	ParentID string `json:"parent_id"`
+}
+
+// ListCategoryStocksRequest for retrieving the Stocks of a Category and its descendants
+type ListCategoryStocksRequest struct {
+	CategoryID string `json:"-" validate:"required"`
+	Limit      int    `json:"limit" validate:"gte=0"`
+	After      string `json:"after"`
+	// optional filter
+	Tag string `json:"tag"`
+}
+
+// ListTagStocksRequest for retrieving the Stocks carrying a tag
+type ListTagStocksRequest struct {
+	Tag   string `json:"-" validate:"required,maxlen=64"`
+	Limit int    `json:"limit" validate:"gte=0"`
+	After string `json:"after"`
+}
+
+// SetClassificationRequest to replace the category and the tags of a Stock
+type SetClassificationRequest struct {
+	ID string `json:"-" validate:"required"`
+	// uncategorized when empty
+	CategoryID string   `json:"category_id"`
+	Tags       []string `json:"tags" validate:"maxlen=20"`
+}
+
+// CategoriesResponseWrapper reponse of any category request
+type CategoriesResponseWrapper struct {
+	Category   *Category   `json:"category,omitempty"`
+	Categories []*Category `json:"categories,omitempty"`
+	Stock      *Stock      `json:"stock,omitempty"`
+	Stocks     []*Stock    `json:"stocks,omitempty"`
+	Code       int         `json:"-"`
+}
+
+// JSON convert CategoriesResponseWrapper in json
+func (e *CategoriesResponseWrapper) JSON() []byte {
+	if e == nil {
+		return []byte("{}")
+	}
+	res, _ := json.Marshal(e)
+	return res
+}
+
+// StatusCode return status code
+func (e *CategoriesResponseWrapper) StatusCode() int {
+	if e == nil || e.Code == 0 {
+		return http.StatusOK
+	}
+	return e.Code
+}
@@ -0,0 +1,110 @@
+package objects
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Statuses of a CountSession
+const (
+	// counts are being recorded
+	CountOpen = "open"
+	// every line is counted, waiting for approval
+	CountSubmitted = "submitted"
+	// its variances were posted as adjustments
+	CountApproved  = "approved"
+	CountCancelled = "cancelled"
+)
+
+// MaxCountLines stocks counted by one session
+const MaxCountLines = 500
+
+// CountSession a cycle count of a set of stocks, the quantities counted on the
+// shelves are compared to the availability at the time the count sheet was made
+type CountSession struct {
+	ID       string `gorm:"primary_key" json:"id"`
+	TenantID string `gorm:"index;not null;default:default" json:"-"`
+	// category the count sheet was made for, optional
+	CategoryID string `gorm:"index" json:"category_id,omitempty"`
+	Reference  string `json:"reference,omitempty"`
+	Status     string `gorm:"index" json:"status"`
+	// ordered by line number
+	Lines       []*CountLine `gorm:"foreignKey:SessionID" json:"lines"`
+	SubmittedOn *time.Time   `json:"submitted_on,omitempty"`
+	ApprovedOn  *time.Time   `json:"approved_on,omitempty"`
+	CreatedOn   time.Time    `json:"created_on"`
+	UpdatedOn   time.Time    `json:"updated_on"`
+}
+
+// CountLine a stock of a count sheet
+type CountLine struct {
+	SessionID string `gorm:"primaryKey" json:"-"`
+	// line number, from 1
+	Line     int    `gorm:"primaryKey;autoIncrement:false" json:"line"`
+	TenantID string `gorm:"index;not null;default:default" json:"-"`
+	StockID  string `gorm:"index" json:"stock_id"`
+	Name     string `json:"name"`
+	// availability of the stock when the count sheet was made
+	SystemQuantity int `json:"system_quantity"`
+	// units found, in base units, nil until counted
+	Counted *int `json:"counted"`
+	// Counted minus SystemQuantity, nil until counted
+	Variance *int `json:"variance"`
+}
+
+// CreateCountRequest to make the count sheet of stocks, or of the stocks of a category
+// and its subcategories, serialized stocks are counted by their serial numbers instead
+type CreateCountRequest struct {
+	StockIDs   []string `json:"stock_ids" validate:"maxlen=500"`
+	CategoryID string   `json:"category_id"`
+	Reference  string   `json:"reference" validate:"maxlen=128"`
+}
+
+// ListCountsRequest for retrieving list of CountSessions
+type ListCountsRequest struct {
+	Limit int    `json:"limit" validate:"gte=0"`
+	After string `json:"after"`
+	// optional filter
+	Status string `json:"status"`
+}
+
+// SubmitCountRequest to record counted quantities, a session is submitted
+// once every line is counted, lines may be recounted until approval
+type SubmitCountRequest struct {
+	ID    string         `json:"-" validate:"required"`
+	Lines []*CountedLine `json:"lines" validate:"required,maxlen=500"`
+}
+
+// CountedLine quantity found for a line of a CountSession
+type CountedLine struct {
+	Line    int `json:"line" validate:"gt=0"`
+	Counted int `json:"counted" validate:"gte=0,lte=1000000000"`
+	// unit of the quantity, the base unit of the stock when empty
+	Unit string `json:"unit"`
+}
+
+// CountsResponseWrapper reponse of any count request
+type CountsResponseWrapper struct {
+	Count     *CountSession    `json:"count,omitempty"`
+	Counts    []*CountSession  `json:"counts,omitempty"`
+	Movements []*StockMovement `json:"movements,omitempty"`
+	Code      int              `json:"-"`
+}
+
+// JSON convert CountsResponseWrapper in json
+func (e *CountsResponseWrapper) JSON() []byte {
+	if e == nil {
+		return []byte("{}")
+	}
+	res, _ := json.Marshal(e)
+	return res
+}
+
+// StatusCode return status code
+func (e *CountsResponseWrapper) StatusCode() int {
+	if e == nil || e.Code == 0 {
+		return http.StatusOK
+	}
+	return e.Code
+}
@@ -0,0 +1,75 @@
+package objects
+
+import (
+	"time"
+)
+
+// Types of StockEvent
+const (
+	EventStockCreated             = "stock.created"
+	EventStockUpdated             = "stock.updated"
+	EventStockAvailabilityChanged = "stock.availability_changed"
+	EventStockDeactivated         = "stock.deactivated"
+	// availability fell below the reorder point, or the safety stock
+	EventStockBelowReorderPoint = "stock.below_reorder_point"
+	EventStockBelowSafetyStock  = "stock.below_safety_stock"
+)
+
+// MaxEventsLimit maximum events returned at once
+const MaxEventsLimit = 500
+
+// StockEvent an entry of the change log of stocks
+type StockEvent struct {
+	// Sequence number, increasing in commit order within a tenant
+	Seq      int64  `gorm:"primaryKey;autoIncrement" json:"seq"`
+	TenantID string `gorm:"index;not null;default:default" json:"-"`
+	StockID  string `gorm:"index" json:"stock_id"`
+	Type     string `gorm:"index" json:"type"`
+	// state of the stock once changed
+	Stock     *Stock    `gorm:"type:jsonb;serializer:json" json:"stock"`
+	CreatedOn time.Time `json:"created_on"`
+}
+
+// ListEventsRequest for retrieving the change log
+type ListEventsRequest struct {
+	// only events with a greater sequence number
+	After int64 `json:"after" validate:"gte=0"`
+	Limit int   `json:"limit" validate:"gte=0"`
+	// optional filters, any of the values matches
+	StockIDs []string `json:"stock_ids"`
+	Types    []string `json:"types"`
+}
+
+// StockEvents returns the events describing the change of a stock from old to cur,
+// old is nil for a created stock
+func StockEvents(old, cur *Stock) []*StockEvent {
+	types := []string{EventStockCreated}
+	if old != nil {
+		types = []string{EventStockUpdated}
+		if old.Availability != cur.Availability {
+			types = append(types, EventStockAvailabilityChanged)
+		}
+		if old.IsActive && !cur.IsActive {
+			types = append(types, EventStockDeactivated)
+		}
+	}
+	// once per crossing, staying below raises nothing
+	if cur.Below(LevelReorderPoint) && (old == nil || !old.Below(LevelReorderPoint)) {
+		types = append(types, EventStockBelowReorderPoint)
+	}
+	if cur.Below(LevelSafetyStock) && (old == nil || !old.Below(LevelSafetyStock)) {
+		types = append(types, EventStockBelowSafetyStock)
+	}
+	res := make([]*StockEvent, 0, len(types))
+	for _, t := range types {
+		snapshot := *cur
+		res = append(res, &StockEvent{
+			TenantID:  cur.TenantID,
+			StockID:   cur.ID,
+			Type:      t,
+			Stock:     &snapshot,
+			CreatedOn: cur.UpdatedOn,
+		})
+	}
+	return res
+}
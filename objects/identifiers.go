@@ -0,0 +1,79 @@
+package objects
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// ExternalID identifier of a stock in another system, e.g a marketplace listing
+type ExternalID struct {
+	TenantID string `gorm:"primaryKey;default:default" json:"-"`
+	// name of the other system, e.g "amazon"
+	System     string    `gorm:"primaryKey" json:"system"`
+	ExternalID string    `gorm:"primaryKey" json:"external_id"`
+	StockID    string    `gorm:"index" json:"stock_id"`
+	CreatedOn  time.Time `json:"created_on"`
+}
+
+// GetBySKURequest to retrieve the Stock of a SKU
+type GetBySKURequest struct {
+	SKU string `json:"sku" validate:"required,sku"`
+}
+
+// GetByBarcodeRequest to retrieve the Stock of a barcode
+type GetByBarcodeRequest struct {
+	Barcode string `json:"barcode" validate:"required,gtin"`
+}
+
+// SetIdentifiersRequest to replace the SKU and the barcode of a Stock, empty ones are removed
+type SetIdentifiersRequest struct {
+	ID      string `json:"-" validate:"required"`
+	SKU     string `json:"sku" validate:"sku"`
+	Barcode string `json:"barcode" validate:"gtin"`
+}
+
+// CreateExternalIDRequest to map an identifier of another system to a Stock
+type CreateExternalIDRequest struct {
+	StockID    string `json:"-" validate:"required"`
+	System     string `json:"system" validate:"required,maxlen=64"`
+	ExternalID string `json:"external_id" validate:"required,maxlen=255"`
+}
+
+// ListExternalIDsRequest for retrieving the external identifiers of a Stock
+type ListExternalIDsRequest struct {
+	StockID string `json:"-" validate:"required"`
+}
+
+// ExternalIDRequest names one external identifier
+type ExternalIDRequest struct {
+	System     string `json:"system" validate:"required,maxlen=64"`
+	ExternalID string `json:"external_id" validate:"required,maxlen=255"`
+	// when set, the identifier must be mapped to this stock
+	StockID string `json:"-"`
+}
+
+// IdentifiersResponseWrapper reponse of any identifier request
+type IdentifiersResponseWrapper struct {
+	Stock       *Stock        `json:"stock,omitempty"`
+	ExternalID  *ExternalID   `json:"external_id,omitempty"`
+	ExternalIDs []*ExternalID `json:"external_ids,omitempty"`
+	Code        int           `json:"-"`
+}
+
+// JSON convert IdentifiersResponseWrapper in json
+func (e *IdentifiersResponseWrapper) JSON() []byte {
+	if e == nil {
+		return []byte("{}")
+	}
+	res, _ := json.Marshal(e)
+	return res
+}
+
+// StatusCode return status code
+func (e *IdentifiersResponseWrapper) StatusCode() int {
+	if e == nil || e.Code == 0 {
+		return http.StatusOK
+	}
+	return e.Code
+}
@@ -0,0 +1,97 @@
+package objects
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Statuses of a Lot
+const (
+	// its units may be allocated
+	LotActive = "active"
+	// expired, its units were taken out of availability
+	LotQuarantined = "quarantined"
+)
+
+// Lot a batch of units of a stock sharing a lot number and an expiry date
+type Lot struct {
+	ID       string `gorm:"primary_key" json:"id"`
+	TenantID string `gorm:"uniqueIndex:idx_lot_number;not null;default:default" json:"-"`
+	StockID  string `gorm:"uniqueIndex:idx_lot_number" json:"stock_id"`
+	// number printed on the packaging, unique by stock
+	LotNumber      string     `gorm:"uniqueIndex:idx_lot_number" json:"lot_number"`
+	ManufacturedOn *time.Time `json:"manufactured_on,omitempty"`
+	ExpiresOn      time.Time  `gorm:"index" json:"expires_on"`
+	// units of the lot left in stock
+	Quantity      int        `json:"quantity"`
+	Status        string     `gorm:"index" json:"status"`
+	QuarantinedOn *time.Time `json:"quarantined_on,omitempty"`
+	CreatedOn     time.Time  `json:"created_on"`
+	UpdatedOn     time.Time  `json:"updated_on"`
+}
+
+// Expired reports whether l is past its expiry date at now
+func (l *Lot) Expired(now time.Time) bool {
+	return !now.Before(l.ExpiresOn)
+}
+
+// SalesOrderPick units of a line of a SalesOrder taken from a lot
+type SalesOrderPick struct {
+	OrderID   string    `gorm:"primaryKey" json:"-"`
+	Line      int       `gorm:"primaryKey;autoIncrement:false" json:"line"`
+	LotID     string    `gorm:"primaryKey" json:"lot_id"`
+	TenantID  string    `gorm:"index;not null;default:default" json:"-"`
+	LotNumber string    `json:"lot_number"`
+	ExpiresOn time.Time `json:"expires_on"`
+	Quantity  int       `json:"quantity"`
+}
+
+// CreateLotRequest to receive a Lot of a stock, its quantity is added to availability
+type CreateLotRequest struct {
+	StockID        string     `json:"-" validate:"required"`
+	LotNumber      string     `json:"lot_number" validate:"required,maxlen=64"`
+	ManufacturedOn *time.Time `json:"manufactured_on"`
+	ExpiresOn      time.Time  `json:"expires_on" validate:"required"`
+	Quantity       int        `json:"quantity" validate:"gt=0,lte=1000000000"`
+	// unit of the quantity, the base unit of the stock when empty
+	Unit string `json:"unit"`
+}
+
+// ListLotsRequest for retrieving the lots of a stock, soonest expiry first
+type ListLotsRequest struct {
+	StockID string `json:"-" validate:"required"`
+	Limit   int    `json:"limit" validate:"gte=0"`
+	// optional filter
+	Status string `json:"status"`
+}
+
+// ListExpiringLotsRequest for retrieving the active lots expiring within Days, soonest first
+type ListExpiringLotsRequest struct {
+	Days  int `json:"days" validate:"gte=0,lte=3650"`
+	Limit int `json:"limit" validate:"gte=0"`
+}
+
+// LotsResponseWrapper reponse of any lot request
+type LotsResponseWrapper struct {
+	Lot  *Lot   `json:"lot,omitempty"`
+	Lots []*Lot `json:"lots,omitempty"`
+	Code int    `json:"-"`
+}
+
+// JSON convert LotsResponseWrapper in json
+func (e *LotsResponseWrapper) JSON() []byte {
+	if e == nil {
+		return []byte("{}")
+	}
+	res, _ := json.Marshal(e)
+	return res
+}
+
+// StatusCode return status code
+func (e *LotsResponseWrapper) StatusCode() int {
+	if e == nil || e.Code == 0 {
+		return http.StatusOK
+	}
+	return e.Code
+}
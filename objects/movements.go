@@ -0,0 +1,56 @@
+package objects
+
+import (
+	"time"
+)
+
+// Types of StockMovement
+const (
+	MovementReturnRestock   = "return.restock"
+	MovementReturnRefurbish = "return.refurbish"
+	MovementReturnScrap     = "return.scrap"
+	// units of a lot received
+	MovementLotReceipt = "lot.receipt"
+	// units of an expired lot taken out of availability
+	MovementLotQuarantine = "lot.quarantine"
+	// a serialized unit received
+	MovementSerialReceipt = "serial.receipt"
+	// a serialized unit allocated to a sales order
+	MovementSerialAllocation = "serial.allocation"
+	// a serialized unit given back by a cancelled sales order
+	MovementSerialRelease = "serial.release"
+	// a returned serialized unit restocked
+	MovementSerialReturn = "serial.return"
+	// the variance of a stock found by an approved cycle count
+	MovementCountAdjustment = "count.adjustment"
+)
+
+// StockMovement a typed record of units of a stock changing state, the
+// movements of a document add up to its quantities
+type StockMovement struct {
+	// Sequence number, increasing in insertion order
+	Seq      int64  `gorm:"primaryKey;autoIncrement" json:"seq"`
+	TenantID string `gorm:"index;not null;default:default" json:"-"`
+	StockID  string `gorm:"index" json:"stock_id"`
+	Type     string `gorm:"index" json:"type"`
+	// units moved
+	Quantity int `json:"quantity"`
+	// change of availability caused by the movement
+	AvailabilityDelta int `json:"availability_delta"`
+	// document and line the movement was posted for, e.g a return
+	Reference string `gorm:"index" json:"reference"`
+	Line      int    `json:"line,omitempty"`
+	// unit moved, for serialized stocks
+	SerialNumber string    `gorm:"index" json:"serial_number,omitempty"`
+	CreatedOn    time.Time `json:"created_on"`
+}
+
+// ListMovementsRequest for retrieving movements, oldest first
+type ListMovementsRequest struct {
+	Limit int   `json:"limit" validate:"gte=0"`
+	After int64 `json:"after" validate:"gte=0"`
+	// optional filters
+	StockID   string `json:"stock_id"`
+	Reference string `json:"reference"`
+	Type      string `json:"type"`
+}
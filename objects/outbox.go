@@ -0,0 +1,31 @@
+package objects
+
+import (
+	"time"
+)
+
+// OutboxMessage a domain event waiting to be published,
+// written in the transaction of the change it describes
+type OutboxMessage struct {
+	// Sequence number, messages are published in its order
+	Seq int64 `gorm:"primaryKey;autoIncrement" json:"seq"`
+	// stable id, for consumers to drop the duplicates of an at least once delivery
+	ID       string `gorm:"uniqueIndex" json:"id"`
+	TenantID string `gorm:"index;not null;default:default" json:"tenant_id"`
+	// type of the event, e.g "stock.created"
+	Topic string `json:"topic"`
+	// id of the changed entity
+	Key string `json:"key"`
+	// json document of the event
+	Payload   string    `json:"payload"`
+	CreatedOn time.Time `json:"created_on"`
+	// nil until published
+	PublishedOn *time.Time `gorm:"index" json:"published_on,omitempty"`
+	// failed publications so far
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error,omitempty"`
+	// not claimed before, the end of a claim or of the backoff after a failure
+	NextAttemptOn *time.Time `gorm:"index" json:"next_attempt_on,omitempty"`
+	// set once the relay gave up on the message
+	DeadOn *time.Time `gorm:"index" json:"dead_on,omitempty"`
+}
@@ -0,0 +1,103 @@
+package objects
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Types of attribute values
+const (
+	AttributeString  = "string"
+	AttributeNumber  = "number"
+	AttributeBoolean = "boolean"
+	// a string among the Values of the definition
+	AttributeEnum = "enum"
+)
+
+// Product groups the variants of an article, e.g a T-shirt in several sizes and colours
+type Product struct {
+	ID       string `gorm:"primary_key" json:"id"`
+	TenantID string `gorm:"index;not null;default:default" json:"-"`
+	Name     string `json:"name"`
+	// attributes telling its variants apart
+	Attributes []*AttributeDefinition `gorm:"type:jsonb;serializer:json" json:"attributes"`
+	CreatedOn  time.Time              `json:"created_on"`
+	UpdatedOn  time.Time              `json:"updated_on"`
+}
+
+// AttributeDefinition an attribute the variants of a Product carry
+type AttributeDefinition struct {
+	Name string `json:"name" validate:"required,maxlen=64"`
+	// string, number, boolean or enum
+	Type string `json:"type" validate:"required"`
+	// allowed values of an enum
+	Values []string `json:"values,omitempty" validate:"maxlen=100"`
+	// every variant must set it
+	Required bool `json:"required,omitempty"`
+}
+
+// ProductAvailability availability of a Product summed over its active variants
+type ProductAvailability struct {
+	ProductID string `json:"product_id"`
+	// active variants
+	Variants           int `json:"variants"`
+	Availability       int `json:"availability"`
+	Backordered        int `json:"backordered"`
+	AvailableToPromise int `json:"available_to_promise"`
+	// availability by attribute then by value, e.g {"colour": {"Red": 12}}
+	ByAttribute map[string]map[string]int `json:"by_attribute"`
+}
+
+// CreateProductRequest to create a Product
+type CreateProductRequest struct {
+	Name       string                 `json:"name" validate:"required,maxlen=255"`
+	Attributes []*AttributeDefinition `json:"attributes" validate:"maxlen=20"`
+}
+
+// CreateVariantRequest to create a variant of a Product, a Stock with attribute values
+type CreateVariantRequest struct {
+	ProductID    string  `json:"-" validate:"required"`
+	Name         string  `json:"name" validate:"required,maxlen=255"`
+	Price        float64 `json:"price" validate:"gt=0"`
+	Availability int     `json:"availability" validate:"gte=0"`
+	IsActive     bool    `json:"is_active"`
+	SKU          string  `json:"sku" validate:"sku"`
+	Barcode      string  `json:"barcode" validate:"gtin"`
+	// value of each attribute of the product, by name
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// ListVariantsRequest for retrieving the variants of a Product, ordered by id
+type ListVariantsRequest struct {
+	ProductID string `json:"-" validate:"required"`
+	Limit     int    `json:"limit" validate:"gte=0"`
+	After     string `json:"after"`
+}
+
+// ProductsResponseWrapper reponse of any product request
+type ProductsResponseWrapper struct {
+	Product      *Product             `json:"product,omitempty"`
+	Products     []*Product           `json:"products,omitempty"`
+	Variant      *Stock               `json:"variant,omitempty"`
+	Variants     []*Stock             `json:"variants,omitempty"`
+	Availability *ProductAvailability `json:"availability,omitempty"`
+	Code         int                  `json:"-"`
+}
+
+// JSON convert ProductsResponseWrapper in json
+func (e *ProductsResponseWrapper) JSON() []byte {
+	if e == nil {
+		return []byte("{}")
+	}
+	res, _ := json.Marshal(e)
+	return res
+}
+
+// StatusCode return status code
+func (e *ProductsResponseWrapper) StatusCode() int {
+	if e == nil || e.Code == 0 {
+		return http.StatusOK
+	}
+	return e.Code
+}
@@ -0,0 +1,133 @@
+package objects
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Statuses of a PurchaseOrder
+const (
+	PurchaseOrderDraft             = "draft"
+	PurchaseOrderSubmitted         = "submitted"
+	PurchaseOrderPartiallyReceived = "partially_received"
+	PurchaseOrderReceived          = "received"
+)
+
+// MaxOrderLines maximum lines of an order
+const MaxOrderLines = 100
+
+// Supplier a company stocks are bought from
+type Supplier struct {
+	ID        string    `gorm:"primary_key" json:"id"`
+	TenantID  string    `gorm:"index;not null;default:default" json:"-"`
+	Name      string    `json:"name" validate:"required,maxlen=255"`
+	Email     string    `json:"email,omitempty" validate:"maxlen=255"`
+	Phone     string    `json:"phone,omitempty" validate:"maxlen=64"`
+	CreatedOn time.Time `json:"created_on"`
+	UpdatedOn time.Time `json:"updated_on"`
+}
+
+// PurchaseOrder stocks ordered from a supplier
+type PurchaseOrder struct {
+	ID         string `gorm:"primary_key" json:"id"`
+	TenantID   string `gorm:"index;not null;default:default" json:"-"`
+	SupplierID string `gorm:"index" json:"supplier_id"`
+	// draft, submitted, partially_received or received
+	Status string `gorm:"index" json:"status"`
+	// ordered by line number
+	Lines       []*PurchaseOrderLine `gorm:"foreignKey:OrderID" json:"lines"`
+	SubmittedOn *time.Time           `json:"submitted_on,omitempty"`
+	CreatedOn   time.Time            `json:"created_on"`
+	UpdatedOn   time.Time            `json:"updated_on"`
+}
+
+// PurchaseOrderLine quantity of a stock ordered
+type PurchaseOrderLine struct {
+	OrderID string `gorm:"primaryKey" json:"-"`
+	// line number, from 1
+	Line     int     `gorm:"primaryKey;autoIncrement:false" json:"line"`
+	TenantID string  `gorm:"index;not null;default:default" json:"-"`
+	StockID  string  `gorm:"index" json:"stock_id"`
+	Quantity int     `json:"quantity"`
+	Received int     `json:"received"`
+	UnitCost float64 `json:"unit_cost,omitempty"`
+}
+
+// Outstanding quantity still to receive
+func (l *PurchaseOrderLine) Outstanding() int {
+	return l.Quantity - l.Received
+}
+
+// CreatePurchaseOrderRequest to create a draft PurchaseOrder
+type CreatePurchaseOrderRequest struct {
+	SupplierID string                      `json:"supplier_id" validate:"required"`
+	Lines      []*PurchaseOrderLineRequest `json:"lines" validate:"required,maxlen=100"`
+}
+
+// PurchaseOrderLineRequest a line of a CreatePurchaseOrderRequest
+type PurchaseOrderLineRequest struct {
+	StockID  string `json:"stock_id" validate:"required"`
+	Quantity int    `json:"quantity" validate:"gt=0,lte=1000000000"`
+	// cost of one unit of Unit
+	UnitCost float64 `json:"unit_cost" validate:"gte=0"`
+	// unit of the quantity, the base unit of the stock when empty
+	Unit string `json:"unit"`
+}
+
+// ListPurchaseOrdersRequest for retrieving list of PurchaseOrders
+type ListPurchaseOrdersRequest struct {
+	Limit int    `json:"limit" validate:"gte=0"`
+	After string `json:"after"`
+	// optional filters
+	Status     string `json:"status"`
+	SupplierID string `json:"supplier_id"`
+}
+
+// SubmitPurchaseOrderRequest to send a draft PurchaseOrder to its supplier
+type SubmitPurchaseOrderRequest struct {
+	ID string `json:"id" validate:"required"`
+}
+
+// ReceivePurchaseOrderRequest to record the goods received for a PurchaseOrder,
+// the availability of the stocks grows by the received quantities
+type ReceivePurchaseOrderRequest struct {
+	ID    string         `json:"-" validate:"required"`
+	Lines []*ReceiveLine `json:"lines" validate:"required,maxlen=100"`
+}
+
+// ReceiveLine quantity received for a line of a PurchaseOrder
+type ReceiveLine struct {
+	Line     int `json:"line" validate:"gt=0"`
+	Quantity int `json:"quantity" validate:"gt=0,lte=1000000000"`
+	// unit of the quantity, the base unit of the stock when empty
+	Unit string `json:"unit"`
+	// serial numbers of the received units, one by unit of a serialized stock
+	Serials []string `json:"serials" validate:"maxlen=1000"`
+}
+
+// PurchasingResponseWrapper reponse of any supplier or purchase order request
+type PurchasingResponseWrapper struct {
+	Supplier       *Supplier        `json:"supplier,omitempty"`
+	Suppliers      []*Supplier      `json:"suppliers,omitempty"`
+	PurchaseOrder  *PurchaseOrder   `json:"purchase_order,omitempty"`
+	PurchaseOrders []*PurchaseOrder `json:"purchase_orders,omitempty"`
+	Code           int              `json:"-"`
+}
+
+// JSON convert PurchasingResponseWrapper in json
+func (e *PurchasingResponseWrapper) JSON() []byte {
+	if e == nil {
+		return []byte("{}")
+	}
+	res, _ := json.Marshal(e)
+	return res
+}
+
+// StatusCode return status code
+func (e *PurchasingResponseWrapper) StatusCode() int {
+	if e == nil || e.Code == 0 {
+		return http.StatusOK
+	}
+	return e.Code
+}
@@ -3,19 +3,28 @@ package objects
 import (
 	"encoding/json"
 	"net/http"
+	"time"
+
+	"go-inventory/errors"
 )
 
 // MaxListLimit maximum listting
 const MaxListLimit = 200
 
+// MaxBatchSize maximum items of a batch request
+const MaxBatchSize = 100
+
+// MaxQuantity maximum quantity of a request line, the `lte` of quantity tags
+const MaxQuantity = 1000000000
+
 // GetRequest for retrieving single Stock
 type GetRequest struct {
-	ID string `json:"id"`
+	ID string `json:"id" validate:"required"`
 }
 
 // ListRequest for retrieving list of Stocks
 type ListRequest struct {
-	Limit int    `json:"limit"`
+	Limit int    `json:"limit" validate:"gte=0"`
 	After string `json:"after"`
 	// optional name matching
 	Name string `json:"name"`
@@ -23,28 +32,72 @@ type ListRequest struct {
 
 // CreateRequest for creating a new Stock
 type CreateRequest struct {
-	Stock *Stock `json:"Stock"`
+	Stock *Stock `json:"Stock" validate:"required"`
 }
 
-// UpdateDetailsRequest to update existing Stock
+// UpdateDetailsRequest to update existing Stock, the fields below are replaced as a
+// whole: an omitted one is cleared, e.g the reorder point or the backorder settings
 type UpdateDetailsRequest struct {
-	ID           string  `json:"id"`
-	Name         string  `json:"name"`
-	Price        float64 `json:"price"`
-	Availability int     `json:"availability"`
+	ID           string  `json:"id" validate:"required"`
+	Name         string  `json:"name" validate:"required,maxlen=255"`
+	Price        float64 `json:"price" validate:"gt=0"`
+	Availability int     `json:"availability" validate:"gte=0"`
 	IsActive     bool    `json:"is_active"`
+	ReorderPoint int     `json:"reorder_point" validate:"gte=0"`
+	SafetyStock  int     `json:"safety_stock" validate:"gte=0"`
+	// backorder settings
+	Backorderable  bool       `json:"backorderable"`
+	BackorderLimit int        `json:"backorder_limit" validate:"gte=0"`
+	RestockOn      *time.Time `json:"restock_on"`
+	// only changes while the stock is empty
+	Serialized bool `json:"serialized"`
+}
+
+// ListLowRequest for retrieving the Stocks below a low stock level
+type ListLowRequest struct {
+	// LevelReorderPoint or LevelSafetyStock, LevelReorderPoint when empty
+	Level string `json:"level"`
+	Limit int    `json:"limit" validate:"gte=0"`
+	After string `json:"after"`
 }
 
 // DeleteRequest to delete an Stock
 type DeleteRequest struct {
+	ID string `json:"id" validate:"required"`
+}
+
+// BatchGetRequest for retrieving many Stocks at once
+type BatchGetRequest struct {
+	IDs []string `json:"ids" validate:"required,maxlen=100"`
+	// optional unit the quantities are also expressed in
+	Unit string `json:"unit"`
+}
+
+// BatchUpdateRequest to update many existing Stocks at once
+type BatchUpdateRequest struct {
+	// validated one by one, an invalid item fails alone
+	Items []*UpdateDetailsRequest `json:"items" validate:"required,maxlen=100,nodive"`
+	// apply every item or none of them, otherwise items are applied independently
+	Atomic bool `json:"atomic"`
+}
+
+// BatchUpdateResult outcome of a single item of a BatchUpdateRequest
+type BatchUpdateResult struct {
 	ID string `json:"id"`
+	// HTTP status of the item
+	Status int           `json:"status"`
+	Error  *errors.Error `json:"error,omitempty"`
 }
 
 // StockResponseWrapper reponse of any Stock request
 type StockResponseWrapper struct {
 	Stock  *Stock   `json:"Stock,omitempty"`
 	Stocks []*Stock `json:"Stocks,omitempty"`
-	Code   int      `json:"-"`
+	// ids of a batch get which were not found
+	Missing []string `json:"missing,omitempty"`
+	// outcome of each item of a batch update
+	Results []*BatchUpdateResult `json:"results,omitempty"`
+	Code    int                  `json:"-"`
 }
 
 // JSON convert StockResponseWrapper in json
@@ -0,0 +1,122 @@
+package objects
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Statuses of a Return
+const (
+	ReturnOpen               = "open"
+	ReturnPartiallyInspected = "partially_inspected"
+	// every returned unit was inspected
+	ReturnCompleted = "completed"
+)
+
+// Inspection outcomes of returned units
+const (
+	// back on the shelf, added to availability
+	OutcomeRestock = "restock"
+	// sent for repair, not available
+	OutcomeRefurbish = "refurbish"
+	// destroyed
+	OutcomeScrap = "scrap"
+)
+
+// Return a customer return, or RMA, of stocks
+type Return struct {
+	ID       string `gorm:"primary_key" json:"id"`
+	TenantID string `gorm:"index;not null;default:default" json:"-"`
+	// sales order the goods were bought with, optional
+	SalesOrderID string `gorm:"index" json:"sales_order_id,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+	Status       string `gorm:"index" json:"status"`
+	// ordered by line number
+	Lines     []*ReturnLine `gorm:"foreignKey:ReturnID" json:"lines"`
+	CreatedOn time.Time     `json:"created_on"`
+	UpdatedOn time.Time     `json:"updated_on"`
+}
+
+// ReturnLine quantity of a stock returned and the outcome of its inspection
+type ReturnLine struct {
+	ReturnID string `gorm:"primaryKey" json:"-"`
+	// line number, from 1
+	Line        int    `gorm:"primaryKey;autoIncrement:false" json:"line"`
+	TenantID    string `gorm:"index;not null;default:default" json:"-"`
+	StockID     string `gorm:"index" json:"stock_id"`
+	Quantity    int    `json:"quantity"`
+	Restocked   int    `json:"restocked"`
+	Refurbished int    `json:"refurbished"`
+	Scrapped    int    `json:"scrapped"`
+}
+
+// Uninspected units of the line still waiting for an outcome
+func (l *ReturnLine) Uninspected() int {
+	return l.Quantity - l.Restocked - l.Refurbished - l.Scrapped
+}
+
+// CreateReturnRequest to open a Return
+type CreateReturnRequest struct {
+	SalesOrderID string               `json:"sales_order_id"`
+	Reason       string               `json:"reason" validate:"maxlen=1024"`
+	Lines        []*ReturnLineRequest `json:"lines" validate:"required,maxlen=100"`
+}
+
+// ReturnLineRequest a line of a CreateReturnRequest
+type ReturnLineRequest struct {
+	StockID  string `json:"stock_id" validate:"required"`
+	Quantity int    `json:"quantity" validate:"gt=0,lte=1000000000"`
+	// unit of the quantity, the base unit of the stock when empty
+	Unit string `json:"unit"`
+}
+
+// ListReturnsRequest for retrieving list of Returns
+type ListReturnsRequest struct {
+	Limit int    `json:"limit" validate:"gte=0"`
+	After string `json:"after"`
+	// optional filters
+	Status       string `json:"status"`
+	SalesOrderID string `json:"sales_order_id"`
+}
+
+// InspectReturnRequest to record the outcome of the inspection of returned units
+type InspectReturnRequest struct {
+	ID    string         `json:"-" validate:"required"`
+	Lines []*InspectLine `json:"lines" validate:"required,maxlen=100"`
+}
+
+// InspectLine outcome of units of a line of a Return
+type InspectLine struct {
+	Line int `json:"line" validate:"gt=0"`
+	// restock, refurbish or scrap
+	Outcome  string `json:"outcome" validate:"required"`
+	Quantity int    `json:"quantity" validate:"gt=0,lte=1000000000"`
+	// serial numbers of the restocked units, one by unit of a serialized stock
+	Serials []string `json:"serials" validate:"maxlen=1000"`
+}
+
+// ReturnsResponseWrapper reponse of any return request
+type ReturnsResponseWrapper struct {
+	Return    *Return          `json:"return,omitempty"`
+	Returns   []*Return        `json:"returns,omitempty"`
+	Movements []*StockMovement `json:"movements,omitempty"`
+	Code      int              `json:"-"`
+}
+
+// JSON convert ReturnsResponseWrapper in json
+func (e *ReturnsResponseWrapper) JSON() []byte {
+	if e == nil {
+		return []byte("{}")
+	}
+	res, _ := json.Marshal(e)
+	return res
+}
+
+// StatusCode return status code
+func (e *ReturnsResponseWrapper) StatusCode() int {
+	if e == nil || e.Code == 0 {
+		return http.StatusOK
+	}
+	return e.Code
+}
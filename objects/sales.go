@@ -0,0 +1,108 @@
+package objects
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Statuses of a SalesOrder
+const (
+	// every line is allocated against availability
+	SalesOrderAllocated = "allocated"
+	// some lines wait for a receipt, they are allocated first come first served
+	SalesOrderBackordered = "backordered"
+	// cancelled, its quantities went back to availability
+	SalesOrderCancelled = "cancelled"
+)
+
+// SalesOrder stocks sold to a customer, allocated on creation
+type SalesOrder struct {
+	ID       string `gorm:"primary_key" json:"id"`
+	TenantID string `gorm:"index;not null;default:default" json:"-"`
+	// reference of the order in the calling system, e.g the shop order number
+	Reference string `gorm:"index" json:"reference,omitempty"`
+	// bundle sold by the order, its lines are the components
+	BundleID string `gorm:"index" json:"bundle_id,omitempty"`
+	Status   string `gorm:"index" json:"status"`
+	// ordered by line number
+	Lines []*SalesOrderLine `gorm:"foreignKey:OrderID" json:"lines"`
+	// lots the allocated units are picked from, first expired first out
+	Picks       []*SalesOrderPick `gorm:"foreignKey:OrderID" json:"picks,omitempty"`
+	CancelledOn *time.Time        `json:"cancelled_on,omitempty"`
+	CreatedOn   time.Time         `json:"created_on"`
+	UpdatedOn   time.Time         `json:"updated_on"`
+}
+
+// SalesOrderLine quantity of a stock sold
+type SalesOrderLine struct {
+	OrderID string `gorm:"primaryKey" json:"-"`
+	// line number, from 1
+	Line     int    `gorm:"primaryKey;autoIncrement:false" json:"line"`
+	TenantID string `gorm:"index;not null;default:default" json:"-"`
+	StockID  string `gorm:"index" json:"stock_id"`
+	Quantity int    `json:"quantity"`
+	// quantity taken from availability
+	Allocated int `json:"allocated"`
+	// quantity waiting for a receipt
+	Backordered int `json:"backordered,omitempty"`
+	// restock date of the stock when the line was backordered
+	ExpectedOn *time.Time `json:"expected_on,omitempty"`
+}
+
+// CreateSalesOrderRequest to create a SalesOrder, every line is allocated, or
+// backordered, or none is
+type CreateSalesOrderRequest struct {
+	Reference string                   `json:"reference" validate:"maxlen=255"`
+	Lines     []*SalesOrderLineRequest `json:"lines" validate:"required,maxlen=100"`
+	// set when selling a bundle
+	BundleID string `json:"-"`
+}
+
+// SalesOrderLineRequest a line of a CreateSalesOrderRequest
+type SalesOrderLineRequest struct {
+	StockID  string `json:"stock_id" validate:"required"`
+	Quantity int    `json:"quantity" validate:"gt=0,lte=1000000000"`
+	// unit of the quantity, the base unit of the stock when empty
+	Unit string `json:"unit"`
+	// serial numbers of the sold units, one by unit of a serialized stock
+	Serials []string `json:"serials" validate:"maxlen=1000"`
+}
+
+// ListSalesOrdersRequest for retrieving list of SalesOrders
+type ListSalesOrdersRequest struct {
+	Limit int    `json:"limit" validate:"gte=0"`
+	After string `json:"after"`
+	// optional filters
+	Status    string `json:"status"`
+	Reference string `json:"reference"`
+}
+
+// CancelSalesOrderRequest to cancel a SalesOrder and release its quantities
+type CancelSalesOrderRequest struct {
+	ID string `json:"id" validate:"required"`
+}
+
+// SalesResponseWrapper reponse of any sales order request
+type SalesResponseWrapper struct {
+	SalesOrder  *SalesOrder   `json:"sales_order,omitempty"`
+	SalesOrders []*SalesOrder `json:"sales_orders,omitempty"`
+	Code        int           `json:"-"`
+}
+
+// JSON convert SalesResponseWrapper in json
+func (e *SalesResponseWrapper) JSON() []byte {
+	if e == nil {
+		return []byte("{}")
+	}
+	res, _ := json.Marshal(e)
+	return res
+}
+
+// StatusCode return status code
+func (e *SalesResponseWrapper) StatusCode() int {
+	if e == nil || e.Code == 0 {
+		return http.StatusOK
+	}
+	return e.Code
+}
@@ -0,0 +1,67 @@
+package objects
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Statuses of a Serial
+const (
+	// on the shelf, counted in the availability of its stock
+	SerialInStock = "in_stock"
+	// allocated to a sales order
+	SerialAllocated = "allocated"
+)
+
+// Serial a single unit of a serialized stock
+type Serial struct {
+	TenantID     string `gorm:"primaryKey;default:default" json:"-"`
+	SerialNumber string `gorm:"primaryKey" json:"serial_number"`
+	StockID      string `gorm:"index" json:"stock_id"`
+	Status       string `gorm:"index" json:"status"`
+	// sales order line the unit is allocated to
+	OrderID   string    `gorm:"index" json:"order_id,omitempty"`
+	Line      int       `json:"line,omitempty"`
+	CreatedOn time.Time `json:"created_on"`
+	UpdatedOn time.Time `json:"updated_on"`
+}
+
+// GetSerialRequest to retrieve a Serial and its movements
+type GetSerialRequest struct {
+	SerialNumber string `json:"serial_number" validate:"required"`
+}
+
+// ListSerialsRequest for retrieving the serials of a stock, ordered by serial number
+type ListSerialsRequest struct {
+	StockID string `json:"-" validate:"required"`
+	Limit   int    `json:"limit" validate:"gte=0"`
+	After   string `json:"after"`
+	// optional filter
+	Status string `json:"status"`
+}
+
+// SerialsResponseWrapper reponse of any serial request
+type SerialsResponseWrapper struct {
+	Serial    *Serial          `json:"serial,omitempty"`
+	Serials   []*Serial        `json:"serials,omitempty"`
+	Movements []*StockMovement `json:"movements,omitempty"`
+	Code      int              `json:"-"`
+}
+
+// JSON convert SerialsResponseWrapper in json
+func (e *SerialsResponseWrapper) JSON() []byte {
+	if e == nil {
+		return []byte("{}")
+	}
+	res, _ := json.Marshal(e)
+	return res
+}
+
+// StatusCode return status code
+func (e *SerialsResponseWrapper) StatusCode() int {
+	if e == nil || e.Code == 0 {
+		return http.StatusOK
+	}
+	return e.Code
+}
@@ -8,13 +8,84 @@ import (
 type Stock struct {
 	// Identifier
 	ID string `gorm:"primary_key" json:"id,omitempty"`
+	// Tenant owning the stock, never exposed
+	TenantID string `gorm:"index;not null;default:default" json:"-"`
 
 	// General details
-	Name  string  `json:"name,omitempty"`
-	Price float64 `json:"price,omitempty"`
+	Name  string  `json:"name,omitempty" validate:"required,maxlen=255"`
+	Price float64 `json:"price,omitempty" validate:"gt=0"`
 
-	Availability int       `json:"availability,omitempty"`
-	IsActive     bool      `json:"is_active,omitempty"`
-	CreatedOn    time.Time `json:"created_on,omitempty"`
-	UpdatedOn    time.Time `json:"updated_on,omitempty"`
+	// Identifiers of scanners and marketplaces, unique by tenant when set
+	SKU     string `json:"sku,omitempty" validate:"sku"`
+	Barcode string `json:"barcode,omitempty" validate:"gtin"`
+	// barcode zero padded to a GTIN-14, UPC-A and EAN-13 of a same article match
+	GTIN string `json:"-"`
+
+	// Category of the stock and free-form tags, trimmed and lower cased
+	CategoryID string   `gorm:"index" json:"category_id,omitempty"`
+	Tags       []string `gorm:"type:jsonb;serializer:json" json:"tags,omitempty" validate:"maxlen=20"`
+
+	Availability int  `json:"availability,omitempty" validate:"gte=0"`
+	IsActive     bool `json:"is_active,omitempty"`
+	// Low stock thresholds, an alert is raised when availability falls
+	// below one of them, 0 disables it
+	ReorderPoint int `json:"reorder_point,omitempty" validate:"gte=0"`
+	SafetyStock  int `json:"safety_stock,omitempty" validate:"gte=0"`
+
+	// Backorder settings, orders beyond availability are queued when
+	// backorderable, up to BackorderLimit units waiting at once
+	Backorderable  bool `json:"backorderable,omitempty"`
+	BackorderLimit int  `json:"backorder_limit,omitempty" validate:"gte=0"`
+	// expected date of the next receipt, told to backordered lines
+	RestockOn *time.Time `json:"restock_on,omitempty"`
+	// units ordered and waiting for a receipt, maintained by the sales orders
+	Backordered int `json:"backordered,omitempty"`
+	// units are tracked by serial number, availability is the count of
+	// serials in stock and only changes by receiving or selling serials
+	Serialized bool `json:"serialized,omitempty"`
+
+	// Product the stock is a variant of, with its attribute values by name
+	ProductID  string                 `gorm:"index" json:"product_id,omitempty"`
+	Attributes map[string]interface{} `gorm:"type:jsonb;serializer:json" json:"attributes,omitempty"`
+
+	// Unit of every quantity of the stock, and the units accepted by requests
+	// with the base units in one of them, e.g {"case": 24}
+	BaseUnit string         `gorm:"not null;default:each" json:"base_unit,omitempty" validate:"maxlen=32"`
+	Units    map[string]int `gorm:"type:jsonb;serializer:json" json:"units,omitempty"`
+	// quantities in the unit requested by a read, never stored
+	InUnit *UnitQuantities `gorm:"-" json:"in_unit,omitempty"`
+
+	CreatedOn time.Time `json:"created_on,omitempty"`
+	UpdatedOn time.Time `json:"updated_on,omitempty"`
+}
+
+// AvailableToPromise units which can still be promised to new orders,
+// negative once backorders are queued
+func (s *Stock) AvailableToPromise() int {
+	return s.Availability - s.Backordered
+}
+
+// BackorderRoom units which can still be backordered
+func (s *Stock) BackorderRoom() int {
+	if !s.Backorderable || s.Backordered >= s.BackorderLimit {
+		return 0
+	}
+	return s.BackorderLimit - s.Backordered
+}
+
+// Low stock levels of a Stock
+const (
+	LevelReorderPoint = "reorder_point"
+	LevelSafetyStock  = "safety_stock"
+)
+
+// Below reports whether the availability of s is below the threshold of level
+func (s *Stock) Below(level string) bool {
+	switch level {
+	case LevelReorderPoint:
+		return s.Availability < s.ReorderPoint
+	case LevelSafetyStock:
+		return s.Availability < s.SafetyStock
+	}
+	return false
 }
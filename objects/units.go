@@ -0,0 +1,93 @@
+package objects
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+)
+
+// DefaultBaseUnit base unit of the stocks created without one
+const DefaultBaseUnit = "each"
+
+// MaxUnits maximum conversion units of a stock
+const MaxUnits = 20
+
+// Unit the base unit of s, DefaultBaseUnit when unset
+func (s *Stock) Unit() string {
+	if s.BaseUnit == "" {
+		return DefaultBaseUnit
+	}
+	return s.BaseUnit
+}
+
+// Factor base units in one unit of s, false when s does not define unit,
+// the base unit and "" are 1
+func (s *Stock) Factor(unit string) (int, bool) {
+	if unit == "" || unit == s.Unit() {
+		return 1, true
+	}
+	n, ok := s.Units[unit]
+	return n, ok
+}
+
+// UnitQuantities quantities of a stock expressed in one of its units
+type UnitQuantities struct {
+	Unit string `json:"unit"`
+	// base units in one unit
+	Factor             int     `json:"factor"`
+	Availability       float64 `json:"availability"`
+	Backordered        float64 `json:"backordered"`
+	AvailableToPromise float64 `json:"available_to_promise"`
+}
+
+// Convert sets the quantities of s in unit, false when s does not define unit
+func (s *Stock) Convert(unit string) bool {
+	factor, ok := s.Factor(unit)
+	if !ok {
+		return false
+	}
+	in := func(qty int) float64 {
+		// rounded to 3 decimals, a unit of a case of 24 is 0.042
+		return math.Round(float64(qty)/float64(factor)*1000) / 1000
+	}
+	s.InUnit = &UnitQuantities{
+		Unit:               unit,
+		Factor:             factor,
+		Availability:       in(s.Availability),
+		Backordered:        in(s.Backordered),
+		AvailableToPromise: in(s.AvailableToPromise()),
+	}
+	return true
+}
+
+// SetUnitsRequest to replace the base unit and the conversion units of a Stock
+type SetUnitsRequest struct {
+	ID string `json:"-" validate:"required"`
+	// only changes while the stock is empty
+	BaseUnit string `json:"base_unit" validate:"required,maxlen=32"`
+	// base units in one of each unit, e.g {"case": 24}
+	Units map[string]int `json:"units"`
+}
+
+// UnitsResponseWrapper reponse of any unit request
+type UnitsResponseWrapper struct {
+	Stock *Stock `json:"stock,omitempty"`
+	Code  int    `json:"-"`
+}
+
+// JSON convert UnitsResponseWrapper in json
+func (e *UnitsResponseWrapper) JSON() []byte {
+	if e == nil {
+		return []byte("{}")
+	}
+	res, _ := json.Marshal(e)
+	return res
+}
+
+// StatusCode return status code
+func (e *UnitsResponseWrapper) StatusCode() int {
+	if e == nil || e.Code == 0 {
+		return http.StatusOK
+	}
+	return e.Code
+}
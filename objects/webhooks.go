@@ -0,0 +1,106 @@
+package objects
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Statuses of a WebhookDelivery
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	// attempts exhausted, waiting for a manual redelivery
+	DeliveryDead = "dead"
+)
+
+// WebhookSubscription a partner endpoint notified of stock events
+type WebhookSubscription struct {
+	ID       string `gorm:"primary_key" json:"id"`
+	TenantID string `gorm:"index;not null;default:default" json:"-"`
+	URL      string `json:"url"`
+	// key of the HMAC signature, never returned
+	Secret string `json:"-"`
+	// event types delivered, every type when empty
+	Events    []string  `gorm:"type:jsonb;serializer:json" json:"events"`
+	CreatedOn time.Time `json:"created_on"`
+}
+
+// Matches reports whether events of type t are delivered to s
+func (s *WebhookSubscription) Matches(t string) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery a stock event to deliver to a subscription,
+// written in the transaction of the change
+type WebhookDelivery struct {
+	ID             string `gorm:"primary_key" json:"id"`
+	TenantID       string `gorm:"index;not null;default:default" json:"-"`
+	SubscriptionID string `gorm:"index" json:"subscription_id"`
+	EventSeq       int64  `json:"event_seq"`
+	EventType      string `json:"event_type"`
+	// json body posted to the subscriber
+	Payload string `json:"-"`
+	Status  string `gorm:"index" json:"status"`
+	// attempts made so far
+	Attempts      int       `json:"attempts"`
+	NextAttemptOn time.Time `gorm:"index" json:"next_attempt_on"`
+	LastError     string    `json:"last_error,omitempty"`
+	CreatedOn     time.Time `json:"created_on"`
+	UpdatedOn     time.Time `json:"updated_on"`
+
+	// subscription of the delivery, loaded when claimed
+	Subscription *WebhookSubscription `gorm:"-" json:"-"`
+}
+
+// CreateWebhookRequest to subscribe an endpoint to stock events
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,maxlen=2048"`
+	Secret string   `json:"secret" validate:"required,maxlen=256"`
+	Events []string `json:"events"`
+}
+
+// ListDeliveriesRequest for retrieving deliveries, e.g the dead letters
+type ListDeliveriesRequest struct {
+	Status string `json:"status"`
+	Limit  int    `json:"limit" validate:"gte=0"`
+}
+
+// RedeliverRequest to attempt a delivery again
+type RedeliverRequest struct {
+	ID string `json:"id" validate:"required"`
+}
+
+// WebhookResponseWrapper reponse of any webhook request
+type WebhookResponseWrapper struct {
+	Webhook    *WebhookSubscription   `json:"webhook,omitempty"`
+	Webhooks   []*WebhookSubscription `json:"webhooks,omitempty"`
+	Delivery   *WebhookDelivery       `json:"delivery,omitempty"`
+	Deliveries []*WebhookDelivery     `json:"deliveries,omitempty"`
+	Code       int                    `json:"-"`
+}
+
+// JSON convert WebhookResponseWrapper in json
+func (e *WebhookResponseWrapper) JSON() []byte {
+	if e == nil {
+		return []byte("{}")
+	}
+	res, _ := json.Marshal(e)
+	return res
+}
+
+// StatusCode return status code
+func (e *WebhookResponseWrapper) StatusCode() int {
+	if e == nil || e.Code == 0 {
+		return http.StatusOK
+	}
+	return e.Code
+}
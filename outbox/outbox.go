@@ -0,0 +1,240 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go-inventory/objects"
+	"go-inventory/store"
+	"go-inventory/util/logger"
+)
+
+// Publisher sends outbox messages to their consumers, e.g a message broker.
+// Publish may be called again with a message it already accepted.
+type Publisher interface {
+	Publish(ctx context.Context, msg *objects.OutboxMessage) error
+	// Topics returns the topics it routes to a consumer, nil when it routes every
+	// topic, the messages of other topics are left in the outbox
+	Topics() []string
+}
+
+// Config of a Relay, zero values are replaced by the defaults
+type Config struct {
+	// how often unpublished messages are looked for
+	PollInterval time.Duration
+	// messages relayed at once
+	BatchSize int
+	// time given to publish a batch, the messages left are claimed again later
+	Timeout time.Duration
+	// attempts before a message is dead
+	MaxAttempts int
+	// delay before the first retry, doubled on each following one
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// how long published, dead and unrouted messages are kept
+	Retention time.Duration
+	// how often they are pruned
+	PruneInterval time.Duration
+}
+
+// DefaultConfig of a Relay, it retries for about 21 minutes before giving up
+var DefaultConfig = Config{
+	PollInterval:  time.Second,
+	BatchSize:     100,
+	Timeout:       30 * time.Second,
+	MaxAttempts:   8,
+	BaseBackoff:   10 * time.Second,
+	MaxBackoff:    time.Hour,
+	Retention:     7 * 24 * time.Hour,
+	PruneInterval: time.Hour,
+}
+
+// Relay publishes the messages of an outbox
+type Relay struct {
+	store store.IOutboxStore
+	pub   Publisher
+	cfg   Config
+	log   *logger.Logger
+	now   func() time.Time
+}
+
+// NewRelay returns a Relay of the messages of st to pub
+func NewRelay(st store.IOutboxStore, pub Publisher, cfg Config, log *logger.Logger) *Relay {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultConfig.PollInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultConfig.BatchSize
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultConfig.Timeout
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultConfig.MaxAttempts
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = DefaultConfig.BaseBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultConfig.MaxBackoff
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = DefaultConfig.Retention
+	}
+	if cfg.PruneInterval <= 0 {
+		cfg.PruneInterval = DefaultConfig.PruneInterval
+	}
+	return &Relay{store: st, pub: pub, cfg: cfg, log: log, now: time.Now}
+}
+
+// Run relays messages until ctx is done
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+	var pruned time.Time
+	for {
+		if r.now().Sub(pruned) >= r.cfg.PruneInterval {
+			if _, err := r.Prune(ctx); err != nil {
+				r.log.Error().Err(err).Msg("outbox prune failed")
+			}
+			pruned = r.now()
+		}
+		// drain the backlog before waiting
+		n, err := r.Relay(ctx)
+		if err != nil {
+			r.log.Error().Err(err).Msg("outbox relay failed")
+		}
+		if err == nil && n == r.cfg.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Relay publishes one batch of messages, oldest first, and returns how many were
+// published. A failed message is retried after a backoff, the later ones go ahead.
+func (r *Relay) Relay(ctx context.Context) (int, error) {
+	// a claim outlives the batch so that no other relay picks it up meanwhile,
+	// the messages are published outside of any transaction
+	list, err := r.store.ClaimOutbox(ctx, r.pub.Topics(), r.now(), 2*r.cfg.Timeout, r.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	pubCtx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+	published := 0
+	for _, msg := range list {
+		if pubCtx.Err() != nil {
+			break
+		}
+		if r.publish(pubCtx, msg) {
+			published++
+		}
+		// a failed save leaves the message to be claimed again once its lease ran out
+		if err := r.store.SaveOutboxMessage(ctx, msg); err != nil {
+			r.log.Error().Err(err).Int64("seq", msg.Seq).Msg("outbox message not saved")
+		}
+	}
+	return published, nil
+}
+
+// Prune deletes the messages kept longer than the retention and returns how many
+func (r *Relay) Prune(ctx context.Context) (int, error) {
+	return r.store.PruneOutbox(ctx, r.pub.Topics(), r.now().Add(-r.cfg.Retention))
+}
+
+// publish publishes msg, records the outcome on it and reports whether it succeeded
+func (r *Relay) publish(ctx context.Context, msg *objects.OutboxMessage) bool {
+	err := r.pub.Publish(ctx, msg)
+	now := r.now()
+	if err == nil {
+		msg.PublishedOn = &now
+		msg.LastError = ""
+		return true
+	}
+	msg.Attempts++
+	msg.LastError = err.Error()
+	if msg.Attempts >= r.cfg.MaxAttempts {
+		msg.DeadOn = &now
+		r.log.Warn().Int64("seq", msg.Seq).Str("topic", msg.Topic).Err(err).Msg("outbox message is dead")
+		return false
+	}
+	next := now.Add(Backoff(r.cfg, msg.Attempts))
+	msg.NextAttemptOn = &next
+	r.log.Warn().Int64("seq", msg.Seq).Str("topic", msg.Topic).Err(err).Msg("outbox message not published")
+	return false
+}
+
+// Backoff returns the delay after attempt failed attempts
+func Backoff(cfg Config, attempt int) time.Duration {
+	delay := cfg.BaseBackoff
+	for i := 1; i < attempt && delay < cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > cfg.MaxBackoff {
+		delay = cfg.MaxBackoff
+	}
+	return delay
+}
+
+// Handler consumes the messages of an InProcess publisher
+type Handler func(ctx context.Context, msg *objects.OutboxMessage) error
+
+// InProcess publishes messages to the handlers subscribed in the same process
+type InProcess struct {
+	mu sync.RWMutex
+	// handlers by topic, the ones of "" receive every topic
+	handlers map[string][]Handler
+}
+
+// NewInProcess returns a publisher without subscribers
+func NewInProcess() *InProcess {
+	return &InProcess{handlers: map[string][]Handler{}}
+}
+
+// Subscribe calls h with every message of topic, of every topic when empty
+func (p *InProcess) Subscribe(topic string, h Handler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers[topic] = append(p.handlers[topic], h)
+}
+
+// Topics returns the subscribed topics, nil once a handler receives every topic
+func (p *InProcess) Topics() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if len(p.handlers[""]) > 0 {
+		return nil
+	}
+	topics := make([]string, 0, len(p.handlers))
+	for topic := range p.handlers {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// Publish calls the handlers of msg in turn and fails with the first failing one, or
+// when the topic has none
+func (p *InProcess) Publish(ctx context.Context, msg *objects.OutboxMessage) error {
+	p.mu.RLock()
+	handlers := append(append([]Handler(nil), p.handlers[""]...), p.handlers[msg.Topic]...)
+	p.mu.RUnlock()
+	if len(handlers) == 0 {
+		// left unpublished until subscribed
+		return fmt.Errorf("no subscriber of topic %s", msg.Topic)
+	}
+	for _, h := range handlers {
+		if err := h(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go-inventory/objects"
+	"go-inventory/store"
+	"go-inventory/util/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelay(t *testing.T) {
+	stores := store.NewMemoryStores()
+	ctx := context.Background()
+	pub := NewInProcess()
+	var (
+		all, created []string
+		fail         bool
+	)
+	pub.Subscribe("", func(_ context.Context, msg *objects.OutboxMessage) error {
+		if fail {
+			return fmt.Errorf("broker down")
+		}
+		all = append(all, msg.Topic)
+		return nil
+	})
+	pub.Subscribe(objects.EventStockCreated, func(_ context.Context, msg *objects.OutboxMessage) error {
+		created = append(created, msg.Key)
+		return nil
+	})
+	relay := NewRelay(stores.Outbox, pub, Config{}, logger.New(false))
+
+	evt := &objects.Stock{Name: "a", Price: 1, Availability: 1, IsActive: true}
+	assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: evt}))
+	assert.Nil(t, stores.Stocks.UpdateDetails(ctx, &objects.UpdateDetailsRequest{ID: evt.ID, Name: "a", Price: 1, Availability: 2, IsActive: true}))
+
+	fail = true
+	n, err := relay.Relay(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	// retried after the backoff
+	fail = false
+	n, _ = relay.Relay(ctx)
+	assert.Equal(t, 0, n)
+	now := time.Now().Add(DefaultConfig.BaseBackoff)
+	relay.now = func() time.Time { return now }
+	n, err = relay.Relay(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []string{objects.EventStockCreated, objects.EventStockUpdated, objects.EventStockAvailabilityChanged}, all)
+	assert.Equal(t, []string{evt.ID}, created)
+
+	// published once
+	n, _ = relay.Relay(ctx)
+	assert.Equal(t, 0, n)
+}
+
+func TestTransaction(t *testing.T) {
+	stores := store.NewMemoryStores()
+	ctx := context.Background()
+	pub := NewInProcess()
+	var published int
+	pub.Subscribe("", func(context.Context, *objects.OutboxMessage) error {
+		published++
+		return nil
+	})
+	relay := NewRelay(stores.Outbox, pub, Config{}, logger.New(false))
+
+	// rolled back with its messages
+	err := stores.Stocks.Transaction(ctx, func(tx store.IStockStore) error {
+		if err := tx.Create(ctx, &objects.CreateRequest{Stock: &objects.Stock{Name: "a", Price: 1}}); err != nil {
+			return err
+		}
+		return fmt.Errorf("abort")
+	})
+	assert.EqualError(t, err, "abort")
+	list, _ := stores.Stocks.List(ctx, &objects.ListRequest{})
+	assert.Empty(t, list)
+	n, _ := relay.Relay(ctx)
+	assert.Equal(t, 0, n)
+
+	// committed with its messages
+	err = stores.Stocks.Transaction(ctx, func(tx store.IStockStore) error {
+		for _, name := range []string{"a", "b"} {
+			if err := tx.Create(ctx, &objects.CreateRequest{Stock: &objects.Stock{Name: name, Price: 1}}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	list, _ = stores.Stocks.List(ctx, &objects.ListRequest{})
+	assert.Len(t, list, 2)
+	n, _ = relay.Relay(ctx)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, 2, published)
+}
+
+func TestUnrouted(t *testing.T) {
+	stores := store.NewMemoryStores()
+	ctx := context.Background()
+	pub := NewInProcess()
+	var availability []string
+	pub.Subscribe(objects.EventStockAvailabilityChanged, func(_ context.Context, msg *objects.OutboxMessage) error {
+		// publishers may call the stores
+		_, err := stores.Stocks.Get(ctx, &objects.GetRequest{ID: msg.Key})
+		availability = append(availability, msg.Key)
+		return err
+	})
+	relay := NewRelay(stores.Outbox, pub, Config{}, logger.New(false))
+
+	evt := &objects.Stock{Name: "a", Price: 1, Availability: 1, IsActive: true}
+	assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: evt}))
+	assert.Nil(t, stores.Stocks.UpdateDetails(ctx, &objects.UpdateDetailsRequest{ID: evt.ID, Name: "a", Price: 1, Availability: 2, IsActive: true}))
+	n, err := relay.Relay(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{evt.ID}, availability)
+
+	// the other topics wait for a subscriber
+	var all []string
+	pub.Subscribe("", func(_ context.Context, msg *objects.OutboxMessage) error {
+		all = append(all, msg.Topic)
+		return nil
+	})
+	n, _ = relay.Relay(ctx)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{objects.EventStockCreated, objects.EventStockUpdated}, all)
+}
+
+func TestPruneUnrouted(t *testing.T) {
+	stores := store.NewMemoryStores()
+	ctx := context.Background()
+	pub := NewInProcess()
+	pub.Subscribe(objects.EventStockAvailabilityChanged, func(context.Context, *objects.OutboxMessage) error {
+		return nil
+	})
+	relay := NewRelay(stores.Outbox, pub, Config{}, logger.New(false))
+
+	evt := &objects.Stock{Name: "a", Price: 1, Availability: 1, IsActive: true}
+	assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: evt}))
+	assert.Nil(t, stores.Stocks.UpdateDetails(ctx, &objects.UpdateDetailsRequest{ID: evt.ID, Name: "a", Price: 1, Availability: 2, IsActive: true}))
+
+	// unrouted messages are kept no longer than the published ones
+	now := time.Now().Add(DefaultConfig.Retention).Add(time.Minute)
+	relay.now = func() time.Time { return now }
+	n, err := relay.Prune(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	n, _ = relay.Relay(ctx)
+	assert.Equal(t, 1, n)
+}
+
+func TestDead(t *testing.T) {
+	stores := store.NewMemoryStores()
+	ctx := context.Background()
+	pub := NewInProcess()
+	var published []string
+	pub.Subscribe("", func(_ context.Context, msg *objects.OutboxMessage) error {
+		if msg.Topic == objects.EventStockCreated {
+			return fmt.Errorf("rejected")
+		}
+		published = append(published, msg.Topic)
+		return nil
+	})
+	relay := NewRelay(stores.Outbox, pub, Config{MaxAttempts: 2, BaseBackoff: time.Minute}, logger.New(false))
+	now := time.Now()
+	relay.now = func() time.Time { return now }
+
+	// a failing message does not hold back the later ones
+	evt := &objects.Stock{Name: "a", Price: 1, Availability: 1, IsActive: true}
+	assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: evt}))
+	assert.Nil(t, stores.Stocks.UpdateDetails(ctx, &objects.UpdateDetailsRequest{ID: evt.ID, Name: "a", Price: 1, Availability: 2, IsActive: true}))
+	n, err := relay.Relay(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{objects.EventStockUpdated, objects.EventStockAvailabilityChanged}, published)
+
+	// then dead once out of attempts
+	now = now.Add(time.Minute)
+	n, _ = relay.Relay(ctx)
+	assert.Equal(t, 0, n)
+	now = now.Add(time.Hour)
+	n, _ = relay.Relay(ctx)
+	assert.Equal(t, 0, n)
+
+	// published and dead messages are pruned after the retention
+	n, err = relay.Prune(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+	now = now.Add(DefaultConfig.Retention)
+	n, _ = relay.Prune(ctx)
+	assert.Equal(t, 3, n)
+}
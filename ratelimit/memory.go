@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// refill period, the bucket is full once idle that long
+	per time.Duration
+}
+
+type memory struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+	sweep   time.Time
+}
+
+// NewMemoryBackend returns an in process Backend
+func NewMemoryBackend() Backend {
+	return &memory{buckets: map[string]*bucket{}, now: time.Now}
+}
+
+func (m *memory) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	capacity := float64(limit.Requests)
+	// tokens refilled per second
+	rate := capacity / limit.Per.Seconds()
+	m.cleanup(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now, per: limit.Per}
+		m.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	res := Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = duration((1 - b.tokens) / rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = duration((capacity - b.tokens) / rate)
+	return res, nil
+}
+
+// cleanup drops the buckets which are full again, at most once a minute
+func (m *memory) cleanup(now time.Time) {
+	if now.Sub(m.sweep) < time.Minute {
+		return
+	}
+	m.sweep = now
+	for key, b := range m.buckets {
+		if now.Sub(b.last) > b.per {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+func duration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-inventory/auth"
+	"go-inventory/errors"
+
+	"github.com/gorilla/mux"
+)
+
+// Limit a token bucket holding Requests tokens, refilled over Per
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Result outcome of taking a token
+type Result struct {
+	Allowed bool
+	// tokens left in the bucket
+	Remaining int
+	// time until the bucket is full again
+	Reset time.Duration
+	// time until a token is available, zero when allowed
+	RetryAfter time.Duration
+}
+
+// Backend stores the buckets, implementations may be shared between instances
+type Backend interface {
+	// Take removes a token from the bucket of key
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Config limits applied by the middleware
+type Config struct {
+	// limit of routes not listed in Routes
+	Default Limit
+	// limits by mux route name
+	Routes map[string]Limit
+}
+
+// Middleware limits the rate of requests of each client on each route.
+// Clients are identified by their principal when authenticated, by their ip otherwise.
+func Middleware(backend Backend, cfg Config) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := ""
+			if cur := mux.CurrentRoute(r); cur != nil {
+				route = cur.GetName()
+			}
+			limit, ok := cfg.Routes[route]
+			if !ok {
+				limit = cfg.Default
+			}
+			if limit.Requests <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			res, err := backend.Take(r.Context(), clientKey(r)+"|"+route, limit)
+			if err != nil {
+				// fail open, the limiter must not take the api down
+				next.ServeHTTP(w, r)
+				return
+			}
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+			if !res.Allowed {
+				h.Set("Retry-After", strconv.Itoa(seconds(res.RetryAfter)))
+				errors.ErrTooManyRequests.Write(w)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientKey identifies the caller of r
+func clientKey(r *http.Request) string {
+	if p, ok := auth.FromContext(r.Context()); ok {
+		return "principal:" + p.ID
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// seconds rounds d up to whole seconds
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-inventory/auth"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	now := time.Unix(0, 0)
+	backend := NewMemoryBackend()
+	backend.(*memory).now = func() time.Time { return now }
+
+	router := mux.NewRouter()
+	router.Use(Middleware(backend, Config{
+		Default: Limit{Requests: 10, Per: time.Minute},
+		Routes: map[string]Limit{
+			"ListStocks": {Requests: 2, Per: time.Minute},
+		},
+	}))
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	router.HandleFunc("/stocks", ok).Name("ListStocks")
+	router.HandleFunc("/stock", ok).Name("CreateStock")
+
+	do := func(path, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("/stocks", "10.0.0.1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+
+	assert.Equal(t, http.StatusOK, do("/stocks", "10.0.0.1").Code)
+
+	w = do("/stocks", "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+
+	// other routes and other clients have their own buckets
+	assert.Equal(t, http.StatusOK, do("/stock", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusOK, do("/stocks", "10.0.0.2").Code)
+
+	// a token is refilled after Per / Requests
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, http.StatusOK, do("/stocks", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, do("/stocks", "10.0.0.1").Code)
+}
+
+func TestUnauthenticated(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(
+		auth.Resolve(auth.NewAPIKeyAuthenticator([]auth.APIKey{{Name: "cart", Hash: auth.HashAPIKey("k3y")}})),
+		Middleware(NewMemoryBackend(), Config{Default: Limit{Requests: 2, Per: time.Minute}}),
+		auth.Require,
+	)
+	router.HandleFunc("/stock", func(w http.ResponseWriter, r *http.Request) {}).Name("CreateStock")
+
+	do := func(key, ip string) int {
+		req := httptest.NewRequest(http.MethodGet, "/stock", nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set(auth.APIKeyHeader, key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// rejected credentials count against the ip
+	assert.Equal(t, http.StatusUnauthorized, do("guess", "10.0.0.1"))
+	assert.Equal(t, http.StatusUnauthorized, do("guess", "10.0.0.1"))
+	assert.Equal(t, http.StatusTooManyRequests, do("guess", "10.0.0.1"))
+
+	// the principal has its own bucket
+	assert.Equal(t, http.StatusOK, do("k3y", "10.0.0.1"))
+}
@@ -0,0 +1,291 @@
+package rbac
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-inventory/audit"
+	"go-inventory/auth"
+	"go-inventory/errors"
+
+	"github.com/gorilla/mux"
+)
+
+// Permission an operation a role may perform
+type Permission string
+
+// Permissions on stocks
+const (
+	// read a single stock or list them
+	PermStockRead Permission = "stock:read"
+	// create new stocks
+	PermStockCreate Permission = "stock:create"
+	// change the availability of a stock
+	PermStockAvailability Permission = "stock:availability"
+	// change the price of a stock
+	PermStockPrice Permission = "stock:price"
+	// change the name, the active flag or the low stock thresholds of a stock
+	PermStockDetails Permission = "stock:details"
+)
+
+// PermWebhooksManage subscribe endpoints to stock events and manage their deliveries
+const PermWebhooksManage Permission = "webhooks:manage"
+
+// Permissions on suppliers and purchase orders
+const (
+	// read suppliers and purchase orders
+	PermPurchasingRead Permission = "purchasing:read"
+	// create suppliers, create and submit purchase orders
+	PermPurchasingManage Permission = "purchasing:manage"
+	// record the goods received for a purchase order
+	PermPurchasingReceive Permission = "purchasing:receive"
+)
+
+// Permissions on sales orders
+const (
+	// read sales orders
+	PermOrdersRead Permission = "orders:read"
+	// place and cancel sales orders
+	PermOrdersManage Permission = "orders:manage"
+)
+
+// Permissions on customer returns
+const (
+	// read returns
+	PermReturnsRead Permission = "returns:read"
+	// open returns
+	PermReturnsManage Permission = "returns:manage"
+	// record the inspection outcome of returned units
+	PermReturnsInspect Permission = "returns:inspect"
+)
+
+// Permissions on cycle counts
+const (
+	// read count sessions
+	PermCountsRead Permission = "counts:read"
+	// make count sheets, record counted quantities and cancel counts
+	PermCountsManage Permission = "counts:manage"
+	// approve counts, posting their variances to availability
+	PermCountsApprove Permission = "counts:approve"
+)
+
+// Roles known by the default policy
+const (
+	RoleReader       = "reader"
+	RoleWarehouse    = "warehouse"
+	RoleMerchandiser = "merchandiser"
+	RoleAdmin        = "admin"
+	// storefronts and other order taking systems
+	RoleSales = "sales"
+)
+
+// Policy maps each role to the permissions it grants
+type Policy map[string][]Permission
+
+// DefaultPolicy warehouse staff adjust availability, receive goods and count them, merchandisers
+// manage prices, details and purchasing, sales systems place orders, readers only read,
+// admins alone approve counts
+var DefaultPolicy = Policy{
+	RoleReader: {PermStockRead},
+	RoleWarehouse: {
+		PermStockRead, PermStockAvailability, PermPurchasingRead, PermPurchasingReceive,
+		PermOrdersRead, PermReturnsRead, PermReturnsInspect, PermCountsRead, PermCountsManage,
+	},
+	RoleSales: {PermStockRead, PermOrdersRead, PermOrdersManage, PermReturnsRead, PermReturnsManage},
+	RoleMerchandiser: {
+		PermStockRead, PermStockCreate, PermStockPrice, PermStockDetails,
+		PermPurchasingRead, PermPurchasingManage,
+	},
+	RoleAdmin: {
+		PermStockRead, PermStockCreate, PermStockAvailability, PermStockPrice, PermStockDetails,
+		PermWebhooksManage,
+		PermPurchasingRead, PermPurchasingManage, PermPurchasingReceive,
+		PermOrdersRead, PermOrdersManage,
+		PermReturnsRead, PermReturnsManage, PermReturnsInspect,
+		PermCountsRead, PermCountsManage, PermCountsApprove,
+	},
+}
+
+// Grants reports whether any of roles grants perm
+func (p Policy) Grants(roles []string, perm Permission) bool {
+	for _, role := range roles {
+		for _, granted := range p[role] {
+			if granted == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RoutePermissions permissions required by each named route of the api,
+// holding any one of them is enough to reach the handler which may check further
+var RoutePermissions = map[string][]Permission{
+	"GetStock":           {PermStockRead},
+	"ListStocks":         {PermStockRead},
+	"ListLowStocks":      {PermStockRead},
+	"CreateStock":        {PermStockCreate},
+	"UpdateStockDetails": {PermStockAvailability, PermStockPrice, PermStockDetails},
+	"BatchGetStocks":     {PermStockRead},
+	"StockEvents":        {PermStockRead},
+	"BatchUpdateStocks":  {PermStockAvailability, PermStockPrice, PermStockDetails},
+
+	"CreateWebhook":         {PermWebhooksManage},
+	"ListWebhooks":          {PermWebhooksManage},
+	"DeleteWebhook":         {PermWebhooksManage},
+	"ListWebhookDeliveries": {PermWebhooksManage},
+	"RedeliverWebhook":      {PermWebhooksManage},
+
+	"CreateSupplier":       {PermPurchasingManage},
+	"GetSupplier":          {PermPurchasingRead},
+	"ListSuppliers":        {PermPurchasingRead},
+	"CreatePurchaseOrder":  {PermPurchasingManage},
+	"GetPurchaseOrder":     {PermPurchasingRead},
+	"ListPurchaseOrders":   {PermPurchasingRead},
+	"SubmitPurchaseOrder":  {PermPurchasingManage},
+	"ReceivePurchaseOrder": {PermPurchasingReceive},
+
+	"CreateSalesOrder": {PermOrdersManage},
+	"GetSalesOrder":    {PermOrdersRead},
+	"ListSalesOrders":  {PermOrdersRead},
+	"CancelSalesOrder": {PermOrdersManage},
+
+	"CreateReturn":  {PermReturnsManage},
+	"GetReturn":     {PermReturnsRead},
+	"ListReturns":   {PermReturnsRead},
+	"InspectReturn": {PermReturnsInspect},
+	"ListMovements": {PermStockRead},
+
+	"CreateLot":        {PermStockAvailability},
+	"GetLot":           {PermStockRead},
+	"ListLots":         {PermStockRead},
+	"ListExpiringLots": {PermStockRead},
+
+	"GetSerial":   {PermStockRead},
+	"ListSerials": {PermStockRead},
+
+	"CreateProduct":       {PermStockCreate},
+	"GetProduct":          {PermStockRead},
+	"ListProducts":        {PermStockRead},
+	"CreateVariant":       {PermStockCreate},
+	"ListVariants":        {PermStockRead},
+	"ProductAvailability": {PermStockRead},
+
+	"CreateBundle": {PermStockCreate},
+	"GetBundle":    {PermStockRead},
+	"ListBundles":  {PermStockRead},
+	"SellBundle":   {PermOrdersManage},
+
+	"SetUnits": {PermStockDetails},
+
+	"GetBySKU":         {PermStockRead},
+	"GetByBarcode":     {PermStockRead},
+	"GetByExternalID":  {PermStockRead},
+	"SetIdentifiers":   {PermStockDetails},
+	"CreateExternalID": {PermStockDetails},
+	"ListExternalIDs":  {PermStockRead},
+	"DeleteExternalID": {PermStockDetails},
+
+	"CreateCategory":     {PermStockDetails},
+	"GetCategory":        {PermStockRead},
+	"ListCategories":     {PermStockRead},
+	"UpdateCategory":     {PermStockDetails},
+	"DeleteCategory":     {PermStockDetails},
+	"ListCategoryStocks": {PermStockRead},
+	"ListTagStocks":      {PermStockRead},
+	"SetClassification":  {PermStockDetails},
+
+	"CreateCount":  {PermCountsManage},
+	"GetCount":     {PermCountsRead},
+	"ListCounts":   {PermCountsRead},
+	"SubmitCount":  {PermCountsManage},
+	"ApproveCount": {PermCountsApprove},
+	"CancelCount":  {PermCountsManage},
+}
+
+// Authorizer checks the permissions of the principal of a request
+// and writes denials to the audit trail
+type Authorizer struct {
+	policy Policy
+	audit  audit.Recorder
+}
+
+// NewAuthorizer returns an Authorizer enforcing policy
+func NewAuthorizer(policy Policy, recorder audit.Recorder) *Authorizer {
+	return &Authorizer{policy: policy, audit: recorder}
+}
+
+// Authorize returns errors.ErrForbidden unless the principal of ctx holds
+// every one of perms
+func (a *Authorizer) Authorize(ctx context.Context, action, resource string, perms ...Permission) error {
+	p, _ := auth.FromContext(ctx)
+	for _, perm := range perms {
+		if p == nil || !a.policy.Grants(p.Roles, perm) {
+			a.deny(ctx, p, action, resource, "missing permission "+string(perm))
+			return errors.ErrForbidden
+		}
+	}
+	return nil
+}
+
+// Middleware rejects requests whose principal holds none of the
+// permissions listed for the matched route in routes
+func (a *Authorizer) Middleware(routes map[string][]Permission) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name := ""
+			if route := mux.CurrentRoute(r); route != nil {
+				name = route.GetName()
+			}
+			perms, ok := routes[name]
+			if !ok {
+				// routes without a mapping are denied
+				a.forbid(w, r, name, "route has no permission mapping")
+				return
+			}
+			p, _ := auth.FromContext(r.Context())
+			if p != nil {
+				for _, perm := range perms {
+					if a.policy.Grants(p.Roles, perm) {
+						next.ServeHTTP(w, r)
+						return
+					}
+				}
+			}
+			a.forbid(w, r, name, "requires one of "+join(perms))
+		})
+	}
+}
+
+func (a *Authorizer) forbid(w http.ResponseWriter, r *http.Request, route, reason string) {
+	p, _ := auth.FromContext(r.Context())
+	a.deny(r.Context(), p, route, r.URL.Path, reason)
+	errors.ErrForbidden.Write(w)
+}
+
+func (a *Authorizer) deny(ctx context.Context, p *auth.Principal, action, resource, reason string) {
+	if a.audit == nil {
+		return
+	}
+	e := audit.Entry{
+		Time:     time.Now().UTC(),
+		Action:   action,
+		Resource: resource,
+		Outcome:  audit.OutcomeDenied,
+		Reason:   reason,
+	}
+	if p != nil {
+		e.Principal = p.ID
+	}
+	a.audit.Record(ctx, e)
+}
+
+func join(perms []Permission) string {
+	s := make([]string, 0, len(perms))
+	for _, p := range perms {
+		s = append(s, string(p))
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,87 @@
+package rbac
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-inventory/audit"
+	"go-inventory/auth"
+	"go-inventory/errors"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	rec := audit.NewMemoryRecorder()
+	authz := NewAuthorizer(DefaultPolicy, rec)
+
+	router := mux.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if role := r.Header.Get("Role"); role != "" {
+				r = r.WithContext(auth.NewContext(r.Context(), &auth.Principal{ID: role, Roles: []string{role}}))
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	router.Use(authz.Middleware(RoutePermissions))
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	router.HandleFunc("/stocks", ok).Methods(http.MethodGet).Name("ListStocks")
+	router.HandleFunc("/stock", ok).Methods(http.MethodPost).Name("CreateStock")
+	router.HandleFunc("/stock/details", ok).Methods(http.MethodPut).Name("UpdateStockDetails")
+	router.HandleFunc("/unmapped", ok).Name("Unmapped")
+
+	tests := []struct {
+		name   string
+		role   string
+		method string
+		path   string
+		code   int
+	}{
+		{"ReaderList", RoleReader, http.MethodGet, "/stocks", http.StatusOK},
+		{"ReaderCreate", RoleReader, http.MethodPost, "/stock", http.StatusForbidden},
+		{"ReaderUpdate", RoleReader, http.MethodPut, "/stock/details", http.StatusForbidden},
+		{"WarehouseUpdate", RoleWarehouse, http.MethodPut, "/stock/details", http.StatusOK},
+		{"MerchandiserCreate", RoleMerchandiser, http.MethodPost, "/stock", http.StatusOK},
+		{"Anonymous", "", http.MethodGet, "/stocks", http.StatusForbidden},
+		{"Unknown role", "intern", http.MethodGet, "/stocks", http.StatusForbidden},
+		{"Unmapped", RoleAdmin, http.MethodGet, "/unmapped", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(rec.Entries())
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.role != "" {
+				req.Header.Set("Role", tt.role)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.code, w.Code)
+			entries := rec.Entries()
+			if tt.code == http.StatusForbidden {
+				if assert.Len(t, entries, before+1) {
+					assert.Equal(t, audit.OutcomeDenied, entries[before].Outcome)
+					assert.Equal(t, tt.role, entries[before].Principal)
+				}
+			} else {
+				assert.Len(t, entries, before)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	rec := audit.NewMemoryRecorder()
+	authz := NewAuthorizer(DefaultPolicy, rec)
+	ctx := auth.NewContext(context.Background(), &auth.Principal{ID: "w", Roles: []string{RoleWarehouse}})
+
+	assert.Nil(t, authz.Authorize(ctx, "UpdateDetails", "1", PermStockAvailability))
+	assert.Equal(t, errors.ErrForbidden, authz.Authorize(ctx, "UpdateDetails", "1", PermStockAvailability, PermStockPrice))
+	if entries := rec.Entries(); assert.Len(t, entries, 1) {
+		assert.Equal(t, "UpdateDetails", entries[0].Action)
+		assert.Equal(t, "1", entries[0].Resource)
+	}
+}
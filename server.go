@@ -1,11 +1,26 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"net/http"
+	"time"
 
+	"go-inventory/alerts"
+	"go-inventory/audit"
+	"go-inventory/auth"
 	"go-inventory/handlers"
+	"go-inventory/lots"
+	"go-inventory/outbox"
+	"go-inventory/ratelimit"
+	"go-inventory/rbac"
 	"go-inventory/store"
+	"go-inventory/tenant"
+	"go-inventory/util/logger"
+	"go-inventory/util/requestid"
+	"go-inventory/util/tracing"
+	"go-inventory/webhooks"
 
 	"github.com/gorilla/mux"
 )
@@ -18,26 +33,170 @@ type Args struct {
 	// port for the server of the form,
 	// e.g ":8080"
 	port string
+	// trace exporter, one of "none", "stdout" or "otlp"
+	traceExporter string
+	// OTLP collector endpoint, e.g "localhost:4318"
+	traceEndpoint string
+	// path of a json file listing the hashed api keys
+	apiKeysFile string
+	// shared secret of HS256 bearer tokens
+	jwtSecret string
+	// path of the JSON Web Key Set holding RS256 public keys
+	jwksFile string
+	// expected issuer and audience of bearer tokens, optional
+	jwtIssuer   string
+	jwtAudience string
+	// serve without credentials when no api key or jwt is configured, the
+	// server refuses to start otherwise
+	authDisabled bool
+	// back tenant isolation with postgres row level security
+	rowLevelSecurity bool
+	// requests allowed per client and minute, 0 disables rate limiting
+	rateLimit int
+	// limit of request bodies in bytes, the handlers default when 0
+	maxBodySize int64
+	// url low stock alerts are posted to, they are logged when empty
+	lowStockWebhook string
 }
 
 // Run run the server based on given args
 func Run(args Args) error {
+	// tracing
+	exp, err := tracing.NewExporter(context.Background(), tracing.Config{
+		Exporter: args.traceExporter,
+		Endpoint: args.traceEndpoint,
+	})
+	if err != nil {
+		return err
+	}
+	shutdown := tracing.Setup(tracing.TracerName, exp)
+	defer func() { _ = shutdown(context.Background()) }()
+
 	// router
 	router := mux.NewRouter().
 		PathPrefix("/api/v1/"). // add prefix for v1 api `/api/v1/`
 		Subrouter()
 
-	st := store.NewPostgresStockStore(args.conn)
-	hnd := handlers.NewEventHandler(st)
-	RegisterAllRoutes(router, hnd)
+	// authentication
+	authenticators, err := Authenticators(args)
+	if err != nil {
+		return err
+	}
+	var (
+		middlewares []mux.MiddlewareFunc
+		opts        []handlers.Option
+	)
+	if args.maxBodySize > 0 {
+		opts = append(opts, handlers.WithMaxBodySize(args.maxBodySize))
+	}
+	switch {
+	case len(authenticators) > 0:
+		middlewares = append(middlewares, auth.Resolve(authenticators...))
+	case args.authDisabled:
+		log.Println("No api key or jwt configured, authentication is disabled")
+	default:
+		return fmt.Errorf("no api key or jwt configured, set AUTH_DISABLED=true to serve without authentication")
+	}
+	// rate limiting before rejecting unauthenticated requests, keyed by the principal
+	// when there is one, by the ip otherwise
+	if args.rateLimit > 0 {
+		middlewares = append(middlewares, ratelimit.Middleware(ratelimit.NewMemoryBackend(), RateLimits(args.rateLimit)))
+	}
+	if len(authenticators) > 0 {
+		middlewares = append(middlewares, auth.Require)
+		// authorization, denials go to the audit trail
+		authz := rbac.NewAuthorizer(rbac.DefaultPolicy, audit.NewLogRecorder(logger.New(false)))
+		middlewares = append(middlewares, authz.Middleware(rbac.RoutePermissions))
+		opts = append(opts, handlers.WithAuthorizer(authz))
+	}
+
+	var pgOpts []store.PostgresOption
+	if args.rowLevelSecurity {
+		pgOpts = append(pgOpts, store.WithRowLevelSecurity())
+	}
+	stores := store.NewPostgresStores(args.conn, pgOpts...)
+	st := store.NewTracedStockStore(stores.Stocks)
+	hnd := handlers.NewEventHandler(st, opts...)
+	RegisterAllRoutes(router, hnd, middlewares...)
+	RegisterWebhookRoutes(router, handlers.NewWebhookHandler(stores.Webhooks, opts...))
+	RegisterPurchasingRoutes(router, handlers.NewPurchasingHandler(stores.Purchasing, opts...))
+	RegisterSalesRoutes(router, handlers.NewSalesHandler(stores.Sales, opts...))
+	RegisterReturnsRoutes(router, handlers.NewReturnsHandler(stores.Returns, stores.Movements, opts...))
+	RegisterLotRoutes(router, handlers.NewLotHandler(stores.Lots, opts...))
+	RegisterSerialRoutes(router, handlers.NewSerialHandler(stores.Serials, opts...))
+	RegisterProductRoutes(router, handlers.NewProductHandler(stores.Products, opts...))
+	RegisterBundleRoutes(router, handlers.NewBundleHandler(stores.Bundles, opts...))
+	RegisterUnitRoutes(router, handlers.NewUnitHandler(stores.Units, opts...))
+	RegisterIdentifierRoutes(router, handlers.NewIdentifierHandler(stores.Identifiers, opts...))
+	RegisterCategoryRoutes(router, handlers.NewCategoryHandler(stores.Categories, opts...))
+	RegisterCountRoutes(router, handlers.NewCountHandler(stores.Counts, opts...))
+
+	// deliver webhooks and publish domain events in the background
+	go webhooks.NewDispatcher(stores.Webhooks, webhooks.DefaultConfig, logger.New(false)).Run(context.Background())
+	pub := outbox.NewInProcess()
+	var notifier alerts.Notifier = alerts.NewLogNotifier(logger.New(false))
+	if args.lowStockWebhook != "" {
+		notifier = alerts.NewWebhookNotifier(args.lowStockWebhook)
+	}
+	alerts.Subscribe(pub, notifier)
+	go outbox.NewRelay(stores.Outbox, pub, outbox.DefaultConfig, logger.New(false)).Run(context.Background())
+	go lots.NewQuarantiner(stores.Lots, lots.DefaultConfig, logger.New(false)).Run(context.Background())
 
 	// start server
 	log.Println("Starting server at port: ", args.port)
 	return http.ListenAndServe(args.port, router)
 }
 
-// RegisterAllRoutes registers all routes of the api
-func RegisterAllRoutes(router *mux.Router, hnd handlers.IStockHandler) {
+// Authenticators returns the authenticators configured by args
+func Authenticators(args Args) ([]auth.Authenticator, error) {
+	var res []auth.Authenticator
+	if args.apiKeysFile != "" {
+		keys, err := auth.LoadAPIKeys(args.apiKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, auth.NewAPIKeyAuthenticator(keys))
+	}
+	if args.jwtSecret != "" || args.jwksFile != "" {
+		cfg := auth.JWTConfig{
+			Secret:   []byte(args.jwtSecret),
+			Issuer:   args.jwtIssuer,
+			Audience: args.jwtAudience,
+		}
+		if args.jwksFile != "" {
+			keys, err := auth.LoadJWKS(args.jwksFile)
+			if err != nil {
+				return nil, err
+			}
+			cfg.Keys = keys
+		}
+		res = append(res, auth.NewJWTAuthenticator(cfg))
+	}
+	return res, nil
+}
+
+// RateLimits returns the limits of each route for perMinute requests a minute,
+// listing is the most expensive and gets a tenth of it
+func RateLimits(perMinute int) ratelimit.Config {
+	list := perMinute / 10
+	if list == 0 {
+		list = 1
+	}
+	return ratelimit.Config{
+		Default: ratelimit.Limit{Requests: perMinute, Per: time.Minute},
+		Routes: map[string]ratelimit.Limit{
+			"ListStocks": {Requests: list, Per: time.Minute},
+		},
+	}
+}
+
+// RegisterAllRoutes registers all routes of the api,
+// middlewares (e.g authentication) run after tracing
+func RegisterAllRoutes(router *mux.Router, hnd handlers.IStockHandler, middlewares ...mux.MiddlewareFunc) {
+
+	// identify and trace requests
+	router.Use(requestid.Middleware)
+	router.Use(tracing.Middleware(logger.New(false)))
 
 	// set content type
 	router.Use(func(next http.Handler) http.Handler {
@@ -46,15 +205,168 @@ func RegisterAllRoutes(router *mux.Router, hnd handlers.IStockHandler) {
 			next.ServeHTTP(w, r)
 		})
 	})
+	router.Use(middlewares...)
+
+	// resolve the tenant, from the principal or the header
+	router.Use(tenant.Middleware)
 
 	// get stock
-	router.HandleFunc("/stock/{id}", hnd.Get).Methods(http.MethodGet)
+	router.HandleFunc("/stock/{id}", hnd.Get).Methods(http.MethodGet).Name("GetStock")
 	// create stock
-	router.HandleFunc("/stock", hnd.Create).Methods(http.MethodPost)
+	router.HandleFunc("/stock", hnd.Create).Methods(http.MethodPost).Name("CreateStock")
 
 	// update stock details
-	router.HandleFunc("/stock/details", hnd.UpdateDetails).Methods(http.MethodPut)
+	router.HandleFunc("/stock/details", hnd.UpdateDetails).Methods(http.MethodPut).Name("UpdateStockDetails")
 
 	// list stock
-	router.HandleFunc("/stocks", hnd.List).Methods(http.MethodGet)
+	router.HandleFunc("/stocks", hnd.List).Methods(http.MethodGet).Name("ListStocks")
+
+	// stocks below their reorder point or safety stock
+	router.HandleFunc("/stocks/low", hnd.ListLow).Methods(http.MethodGet).Name("ListLowStocks")
+
+	// get many stocks at once
+	router.HandleFunc("/stocks:batchGet", hnd.BatchGet).Methods(http.MethodPost).Name("BatchGetStocks")
+	// stream of stock changes
+	router.HandleFunc("/stocks/events", hnd.Events).Methods(http.MethodGet).Name("StockEvents")
+	// update many stocks at once
+	router.HandleFunc("/stocks:batchUpdate", hnd.BatchUpdate).Methods(http.MethodPost).Name("BatchUpdateStocks")
+}
+
+// RegisterWebhookRoutes registers the routes managing webhooks on a router
+// set up by RegisterAllRoutes
+func RegisterWebhookRoutes(router *mux.Router, hnd handlers.IWebhookHandler) {
+	// subscribe an endpoint
+	router.HandleFunc("/webhooks", hnd.CreateWebhook).Methods(http.MethodPost).Name("CreateWebhook")
+	// list subscriptions
+	router.HandleFunc("/webhooks", hnd.ListWebhooks).Methods(http.MethodGet).Name("ListWebhooks")
+	// list deliveries, e.g the dead ones with `?status=dead`
+	router.HandleFunc("/webhooks/deliveries", hnd.ListDeliveries).Methods(http.MethodGet).Name("ListWebhookDeliveries")
+	// attempt a delivery again
+	router.HandleFunc("/webhooks/deliveries/{id}:redeliver", hnd.Redeliver).Methods(http.MethodPost).Name("RedeliverWebhook")
+	// unsubscribe
+	router.HandleFunc("/webhooks/{id}", hnd.DeleteWebhook).Methods(http.MethodDelete).Name("DeleteWebhook")
+}
+
+// RegisterPurchasingRoutes registers the routes of suppliers and purchase orders on a
+// router set up by RegisterAllRoutes
+func RegisterPurchasingRoutes(router *mux.Router, hnd handlers.IPurchasingHandler) {
+	// suppliers
+	router.HandleFunc("/suppliers", hnd.CreateSupplier).Methods(http.MethodPost).Name("CreateSupplier")
+	router.HandleFunc("/suppliers", hnd.ListSuppliers).Methods(http.MethodGet).Name("ListSuppliers")
+	router.HandleFunc("/suppliers/{id}", hnd.GetSupplier).Methods(http.MethodGet).Name("GetSupplier")
+
+	// purchase orders, created as drafts
+	router.HandleFunc("/purchase-orders", hnd.CreatePurchaseOrder).Methods(http.MethodPost).Name("CreatePurchaseOrder")
+	router.HandleFunc("/purchase-orders", hnd.ListPurchaseOrders).Methods(http.MethodGet).Name("ListPurchaseOrders")
+	router.HandleFunc("/purchase-orders/{id}", hnd.GetPurchaseOrder).Methods(http.MethodGet).Name("GetPurchaseOrder")
+	// send a draft to the supplier
+	router.HandleFunc("/purchase-orders/{id}:submit", hnd.SubmitPurchaseOrder).Methods(http.MethodPost).Name("SubmitPurchaseOrder")
+	// record received goods, increasing availability
+	router.HandleFunc("/purchase-orders/{id}:receive", hnd.ReceivePurchaseOrder).Methods(http.MethodPost).Name("ReceivePurchaseOrder")
+}
+
+// RegisterSalesRoutes registers the routes of sales orders on a router set up by RegisterAllRoutes
+func RegisterSalesRoutes(router *mux.Router, hnd handlers.ISalesHandler) {
+	// place an order, allocated on creation
+	router.HandleFunc("/orders", hnd.CreateSalesOrder).Methods(http.MethodPost).Name("CreateSalesOrder")
+	router.HandleFunc("/orders", hnd.ListSalesOrders).Methods(http.MethodGet).Name("ListSalesOrders")
+	router.HandleFunc("/orders/{id}", hnd.GetSalesOrder).Methods(http.MethodGet).Name("GetSalesOrder")
+	// cancel an order, its quantities go back to availability
+	router.HandleFunc("/orders/{id}:cancel", hnd.CancelSalesOrder).Methods(http.MethodPost).Name("CancelSalesOrder")
+}
+
+// RegisterReturnsRoutes registers the routes of customer returns and stock movements
+// on a router set up by RegisterAllRoutes
+func RegisterReturnsRoutes(router *mux.Router, hnd handlers.IReturnsHandler) {
+	// open a return
+	router.HandleFunc("/returns", hnd.CreateReturn).Methods(http.MethodPost).Name("CreateReturn")
+	router.HandleFunc("/returns", hnd.ListReturns).Methods(http.MethodGet).Name("ListReturns")
+	router.HandleFunc("/returns/{id}", hnd.GetReturn).Methods(http.MethodGet).Name("GetReturn")
+	// record inspection outcomes, restocked units go back to availability
+	router.HandleFunc("/returns/{id}:inspect", hnd.InspectReturn).Methods(http.MethodPost).Name("InspectReturn")
+	router.HandleFunc("/movements", hnd.ListMovements).Methods(http.MethodGet).Name("ListMovements")
+}
+
+// RegisterLotRoutes registers the routes of lots on a router set up by RegisterAllRoutes
+func RegisterLotRoutes(router *mux.Router, hnd handlers.ILotHandler) {
+	// receive a lot, its quantity is added to availability
+	router.HandleFunc("/stocks/{id}/lots", hnd.CreateLot).Methods(http.MethodPost).Name("CreateLot")
+	router.HandleFunc("/stocks/{id}/lots", hnd.ListLots).Methods(http.MethodGet).Name("ListLots")
+	// registered before /lots/{id} which would match it
+	router.HandleFunc("/lots/expiring", hnd.ListExpiringLots).Methods(http.MethodGet).Name("ListExpiringLots")
+	router.HandleFunc("/lots/{id}", hnd.GetLot).Methods(http.MethodGet).Name("GetLot")
+}
+
+// RegisterSerialRoutes registers the routes of serial numbers on a router set up by RegisterAllRoutes
+func RegisterSerialRoutes(router *mux.Router, hnd handlers.ISerialHandler) {
+	// a unit with its stock, status and movements
+	router.HandleFunc("/serials/{sn}", hnd.GetSerial).Methods(http.MethodGet).Name("GetSerial")
+	router.HandleFunc("/stocks/{id}/serials", hnd.ListSerials).Methods(http.MethodGet).Name("ListSerials")
+}
+
+// RegisterProductRoutes registers the routes of products on a router set up by RegisterAllRoutes
+func RegisterProductRoutes(router *mux.Router, hnd handlers.IProductHandler) {
+	router.HandleFunc("/products", hnd.CreateProduct).Methods(http.MethodPost).Name("CreateProduct")
+	router.HandleFunc("/products", hnd.ListProducts).Methods(http.MethodGet).Name("ListProducts")
+	router.HandleFunc("/products/{id}", hnd.GetProduct).Methods(http.MethodGet).Name("GetProduct")
+	// a variant is a stock with the attribute values of the product
+	router.HandleFunc("/products/{id}/variants", hnd.CreateVariant).Methods(http.MethodPost).Name("CreateVariant")
+	router.HandleFunc("/products/{id}/variants", hnd.ListVariants).Methods(http.MethodGet).Name("ListVariants")
+	// availability summed over the active variants
+	router.HandleFunc("/products/{id}/availability", hnd.ProductAvailability).Methods(http.MethodGet).Name("ProductAvailability")
+}
+
+// RegisterBundleRoutes registers the routes of bundles on a router set up by RegisterAllRoutes
+func RegisterBundleRoutes(router *mux.Router, hnd handlers.IBundleHandler) {
+	router.HandleFunc("/bundles", hnd.CreateBundle).Methods(http.MethodPost).Name("CreateBundle")
+	router.HandleFunc("/bundles", hnd.ListBundles).Methods(http.MethodGet).Name("ListBundles")
+	router.HandleFunc("/bundles/{id}", hnd.GetBundle).Methods(http.MethodGet).Name("GetBundle")
+	// a sales order allocating every component at once
+	router.HandleFunc("/bundles/{id}:sell", hnd.SellBundle).Methods(http.MethodPost).Name("SellBundle")
+}
+
+// RegisterUnitRoutes registers the routes of units of measure on a router set up by RegisterAllRoutes
+func RegisterUnitRoutes(router *mux.Router, hnd handlers.IUnitHandler) {
+	// replaces the base unit and the conversion units of a stock
+	router.HandleFunc("/stocks/{id}/units", hnd.SetUnits).Methods(http.MethodPut).Name("SetUnits")
+}
+
+// RegisterIdentifierRoutes registers the routes of SKUs, barcodes and external identifiers
+// on a router set up by RegisterAllRoutes
+func RegisterIdentifierRoutes(router *mux.Router, hnd handlers.IIdentifierHandler) {
+	// lookups answer like GET /stock/{id}
+	router.HandleFunc("/stock/by-sku/{sku}", hnd.GetBySKU).Methods(http.MethodGet).Name("GetBySKU")
+	router.HandleFunc("/stock/by-barcode/{code}", hnd.GetByBarcode).Methods(http.MethodGet).Name("GetByBarcode")
+	router.HandleFunc("/stock/by-external-id/{system}/{external_id}", hnd.GetByExternalID).Methods(http.MethodGet).Name("GetByExternalID")
+	router.HandleFunc("/stocks/{id}/identifiers", hnd.SetIdentifiers).Methods(http.MethodPut).Name("SetIdentifiers")
+	router.HandleFunc("/stocks/{id}/external-ids", hnd.CreateExternalID).Methods(http.MethodPost).Name("CreateExternalID")
+	router.HandleFunc("/stocks/{id}/external-ids", hnd.ListExternalIDs).Methods(http.MethodGet).Name("ListExternalIDs")
+	router.HandleFunc("/stocks/{id}/external-ids/{system}/{external_id}", hnd.DeleteExternalID).Methods(http.MethodDelete).Name("DeleteExternalID")
+}
+
+// RegisterCategoryRoutes registers the routes of categories and tags on a router set up by RegisterAllRoutes
+func RegisterCategoryRoutes(router *mux.Router, hnd handlers.ICategoryHandler) {
+	router.HandleFunc("/categories", hnd.CreateCategory).Methods(http.MethodPost).Name("CreateCategory")
+	router.HandleFunc("/categories", hnd.ListCategories).Methods(http.MethodGet).Name("ListCategories")
+	router.HandleFunc("/categories/{id}", hnd.GetCategory).Methods(http.MethodGet).Name("GetCategory")
+	// renames the category, or moves it with its subtree under another parent
+	router.HandleFunc("/categories/{id}", hnd.UpdateCategory).Methods(http.MethodPut).Name("UpdateCategory")
+	router.HandleFunc("/categories/{id}", hnd.DeleteCategory).Methods(http.MethodDelete).Name("DeleteCategory")
+	// stocks of the category and of its descendants
+	router.HandleFunc("/categories/{id}/stocks", hnd.ListCategoryStocks).Methods(http.MethodGet).Name("ListCategoryStocks")
+	router.HandleFunc("/tags/{tag}/stocks", hnd.ListTagStocks).Methods(http.MethodGet).Name("ListTagStocks")
+	router.HandleFunc("/stocks/{id}/classification", hnd.SetClassification).Methods(http.MethodPut).Name("SetClassification")
+}
+
+// RegisterCountRoutes registers the routes of cycle counts on a router set up by RegisterAllRoutes
+func RegisterCountRoutes(router *mux.Router, hnd handlers.ICountHandler) {
+	// make the count sheet of stocks or of a category, snapshotting their availability
+	router.HandleFunc("/counts", hnd.CreateCount).Methods(http.MethodPost).Name("CreateCount")
+	router.HandleFunc("/counts", hnd.ListCounts).Methods(http.MethodGet).Name("ListCounts")
+	router.HandleFunc("/counts/{id}", hnd.GetCount).Methods(http.MethodGet).Name("GetCount")
+	// record counted quantities, the count is submitted once every line is counted
+	router.HandleFunc("/counts/{id}:submit", hnd.SubmitCount).Methods(http.MethodPost).Name("SubmitCount")
+	// post the variances as adjustment movements
+	router.HandleFunc("/counts/{id}:approve", hnd.ApproveCount).Methods(http.MethodPost).Name("ApproveCount")
+	router.HandleFunc("/counts/{id}:cancel", hnd.CancelCount).Methods(http.MethodPost).Name("CancelCount")
 }
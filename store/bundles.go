@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+)
+
+// IBundleStore is the database interface for bundles
+type IBundleStore interface {
+	// CreateBundle creates a bundle, its components must exist
+	CreateBundle(ctx context.Context, in *objects.CreateBundleRequest) (*objects.Bundle, error)
+	GetBundle(ctx context.Context, in *objects.GetRequest) (*objects.Bundle, error)
+	// ListBundles returns bundles ordered by id, optionally matching in.Name
+	ListBundles(ctx context.Context, in *objects.ListRequest) ([]*objects.Bundle, error)
+	// SellBundle allocates the components of in.Quantity bundles in one sales order,
+	// or fails with errors.ErrAllocationFailed when fewer can be built
+	SellBundle(ctx context.Context, in *objects.SellBundleRequest) (*objects.SalesOrder, error)
+}
+
+// newBundle returns the bundle of in, its components ordered by stock
+func newBundle(tenantID string, in *objects.CreateBundleRequest, now time.Time) (*objects.Bundle, error) {
+	bundle := &objects.Bundle{
+		ID:         GenerateUniqueID(),
+		TenantID:   tenantID,
+		Name:       in.Name,
+		Price:      in.Price,
+		Components: make([]*objects.BundleComponent, 0, len(in.Components)),
+		CreatedOn:  now,
+		UpdatedOn:  now,
+	}
+	seen := map[string]bool{}
+	for i, c := range in.Components {
+		if seen[c.StockID] {
+			return nil, errors.ErrValidation.WithField(fmt.Sprintf("components[%d].stock_id", i), "duplicate_component", "stock "+c.StockID+" is listed twice")
+		}
+		seen[c.StockID] = true
+		if err := checkQuantity(c.Quantity, fmt.Sprintf("components[%d].quantity", i)); err != nil {
+			return nil, err
+		}
+		bundle.Components = append(bundle.Components, &objects.BundleComponent{
+			BundleID: bundle.ID,
+			StockID:  c.StockID,
+			TenantID: tenantID,
+			Quantity: c.Quantity,
+		})
+	}
+	sort.Slice(bundle.Components, func(i, j int) bool { return bundle.Components[i].StockID < bundle.Components[j].StockID })
+	return bundle, nil
+}
+
+// componentIDs returns the stocks of the components of bundle, in order
+func componentIDs(bundle *objects.Bundle) []string {
+	ids := make([]string, 0, len(bundle.Components))
+	for _, c := range bundle.Components {
+		ids = append(ids, c.StockID)
+	}
+	return ids
+}
+
+// buildable returns how many bundles the stocks, by id, can still promise:
+// the least of the promisable units of each component over its quantity
+func buildable(bundle *objects.Bundle, stocks map[string]*objects.Stock) int {
+	n := -1
+	for _, c := range bundle.Components {
+		s, ok := stocks[c.StockID]
+		if !ok || !s.IsActive || c.Quantity < 1 {
+			return 0
+		}
+		if b := promisable(s) / c.Quantity; n < 0 || b < n {
+			n = b
+		}
+	}
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// bundleOrder returns the sales order of in, a line by component of bundle, after checking
+// that stocks, by id, can build the bundles without backorders. Their expired lots must
+// be quarantined first.
+func bundleOrder(bundle *objects.Bundle, stocks map[string]*objects.Stock, in *objects.SellBundleRequest) (*objects.CreateSalesOrderRequest, error) {
+	if err := checkQuantity(in.Quantity, "quantity"); err != nil {
+		return nil, err
+	}
+	if n := buildable(bundle, stocks); n < in.Quantity {
+		return nil, errors.ErrAllocationFailed.WithField("quantity", "insufficient_stock", fmt.Sprintf("%d requested, %d buildable", in.Quantity, n))
+	}
+	order := &objects.CreateSalesOrderRequest{
+		Reference: in.Reference,
+		BundleID:  bundle.ID,
+		Lines:     make([]*objects.SalesOrderLineRequest, 0, len(bundle.Components)),
+	}
+	for i, c := range bundle.Components {
+		// both at most objects.MaxQuantity, their product fits
+		qty := c.Quantity * in.Quantity
+		if err := checkQuantity(qty, fmt.Sprintf("components[%d].quantity", i)); err != nil {
+			return nil, err
+		}
+		order.Lines = append(order.Lines, &objects.SalesOrderLineRequest{StockID: c.StockID, Quantity: qty})
+	}
+	return order, nil
+}
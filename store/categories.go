@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+)
+
+// ICategoryStore is the database interface for the category tree and the tags of stocks
+type ICategoryStore interface {
+	CreateCategory(ctx context.Context, in *objects.CreateCategoryRequest) (*objects.Category, error)
+	GetCategory(ctx context.Context, in *objects.GetRequest) (*objects.Category, error)
+	ListCategories(ctx context.Context, in *objects.ListCategoriesRequest) ([]*objects.Category, error)
+	// UpdateCategory renames a category and moves it with its subtree
+	UpdateCategory(ctx context.Context, in *objects.UpdateCategoryRequest) (*objects.Category, error)
+	// DeleteCategory deletes a category without subcategories nor stocks
+	DeleteCategory(ctx context.Context, in *objects.DeleteRequest) error
+	// ListCategoryStocks lists the stocks of a category and of its descendants
+	ListCategoryStocks(ctx context.Context, in *objects.ListCategoryStocksRequest) ([]*objects.Stock, error)
+	ListTagStocks(ctx context.Context, in *objects.ListTagStocksRequest) ([]*objects.Stock, error)
+	// SetClassification replaces the category and the tags of a stock
+	SetClassification(ctx context.Context, in *objects.SetClassificationRequest) (*objects.Stock, error)
+}
+
+// normalizeTag trims and lower cases tag
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// normalizeTags returns tags normalized, without duplicates and sorted, nil when empty
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) > objects.MaxTags {
+		return nil, errors.ErrValidation.WithField("tags", "too_many_tags", fmt.Sprintf("at most %d tags", objects.MaxTags))
+	}
+	seen := make(map[string]bool, len(tags))
+	var res []string
+	for i, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" || len(tag) > 64 {
+			return nil, errors.ErrValidation.WithField(fmt.Sprintf("tags[%d]", i), "invalid_tag", "tags have 1 to 64 characters")
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			res = append(res, tag)
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// hasTag reports whether s carries the normalized tag
+func hasTag(s *objects.Stock, tag string) bool {
+	for _, t := range s.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// newCategory returns the category of in
+func newCategory(tenantID string, in *objects.CreateCategoryRequest, now time.Time) *objects.Category {
+	return &objects.Category{
+		ID:        GenerateUniqueID(),
+		TenantID:  tenantID,
+		ParentID:  in.ParentID,
+		Name:      in.Name,
+		CreatedOn: now,
+		UpdatedOn: now,
+	}
+}
+
+// closureRows returns the closure rows of a new category, parentRows are the rows
+// linking its parent to the ancestors of the parent
+func closureRows(c *objects.Category, parentRows []*objects.CategoryClosure) []*objects.CategoryClosure {
+	rows := []*objects.CategoryClosure{{AncestorID: c.ID, DescendantID: c.ID, TenantID: c.TenantID}}
+	for _, r := range parentRows {
+		rows = append(rows, &objects.CategoryClosure{
+			AncestorID:   r.AncestorID,
+			DescendantID: c.ID,
+			TenantID:     c.TenantID,
+			Depth:        r.Depth + 1,
+		})
+	}
+	return rows
+}
+
+// errCycle rejects moving a category under one of its descendants
+var errCycle = errors.ErrValidation.WithField("parent_id", "cycle", "a category cannot move under itself or its descendants")
+
+// errParentNotFound names the missing parent of a category
+var errParentNotFound = errors.ErrCategoryNotFound.WithField("parent_id", "category_not_found", "no such parent category")
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+)
+
+// ICountStore is the database interface for cycle counts
+type ICountStore interface {
+	// CreateCount makes a count sheet, snapshotting the availability of its stocks
+	CreateCount(ctx context.Context, in *objects.CreateCountRequest) (*objects.CountSession, error)
+	GetCount(ctx context.Context, in *objects.GetRequest) (*objects.CountSession, error)
+	ListCounts(ctx context.Context, in *objects.ListCountsRequest) ([]*objects.CountSession, error)
+	// SubmitCount records counted quantities and their variance
+	SubmitCount(ctx context.Context, in *objects.SubmitCountRequest) (*objects.CountSession, error)
+	// ApproveCount posts a movement for each variance and applies it to availability,
+	// in one transaction
+	ApproveCount(ctx context.Context, in *objects.GetRequest) (*objects.CountSession, []*objects.StockMovement, error)
+	CancelCount(ctx context.Context, in *objects.GetRequest) (*objects.CountSession, error)
+}
+
+// countStockIDs returns the stocks of in without duplicates, in request order
+func countStockIDs(in *objects.CreateCountRequest) []string {
+	seen := make(map[string]bool, len(in.StockIDs))
+	ids := make([]string, 0, len(in.StockIDs))
+	for _, id := range in.StockIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// newCount returns the open count session of list, serialized stocks fail when
+// requested by id and are left out of a category
+func newCount(tenantID string, in *objects.CreateCountRequest, list []*objects.Stock, now time.Time) (*objects.CountSession, error) {
+	count := &objects.CountSession{
+		ID:         GenerateUniqueID(),
+		TenantID:   tenantID,
+		CategoryID: in.CategoryID,
+		Reference:  in.Reference,
+		Status:     objects.CountOpen,
+		Lines:      make([]*objects.CountLine, 0, len(list)),
+		CreatedOn:  now,
+		UpdatedOn:  now,
+	}
+	for _, s := range list {
+		if s.Serialized {
+			if in.CategoryID != "" {
+				continue
+			}
+			return nil, checkUnserialized(s)
+		}
+		count.Lines = append(count.Lines, &objects.CountLine{
+			SessionID:      count.ID,
+			Line:           len(count.Lines) + 1,
+			TenantID:       tenantID,
+			StockID:        s.ID,
+			Name:           s.Name,
+			SystemQuantity: s.Availability,
+		})
+	}
+	switch {
+	case len(count.Lines) == 0:
+		return nil, errors.ErrValidation.WithField("stock_ids", "nothing_to_count", "no stock to count")
+	case len(count.Lines) > objects.MaxCountLines:
+		return nil, errors.ErrValidation.WithField("category_id", "too_many_stocks", fmt.Sprintf("at most %d stocks by count", objects.MaxCountLines))
+	}
+	return count, nil
+}
+
+// countLineStockIDs returns the stocks of the lines of count
+func countLineStockIDs(count *objects.CountSession) []string {
+	ids := make([]string, 0, len(count.Lines))
+	for _, l := range count.Lines {
+		ids = append(ids, l.StockID)
+	}
+	return ids
+}
+
+// recordCounts applies the quantities of in, converted with the units of stocks, to the
+// lines of count and submits it once every line is counted, it returns the changed lines
+func recordCounts(count *objects.CountSession, in *objects.SubmitCountRequest, stocks map[string]*objects.Stock, now time.Time) ([]*objects.CountLine, error) {
+	if count.Status != objects.CountOpen && count.Status != objects.CountSubmitted {
+		return nil, errors.ErrInvalidTransition.WithMessage("Only open or submitted counts are recorded")
+	}
+	byLine := make(map[int]*objects.CountLine, len(count.Lines))
+	for _, l := range count.Lines {
+		byLine[l.Line] = l
+	}
+	var changed []*objects.CountLine
+	for i, r := range in.Lines {
+		l, ok := byLine[r.Line]
+		if !ok {
+			return nil, errors.ErrValidation.WithField("lines", "unknown_line", "the count has no such line")
+		}
+		if r.Counted < 0 {
+			return nil, errors.ErrValidation.WithField(fmt.Sprintf("lines[%d].counted", i), "gte", "must be greater than or equal to 0")
+		}
+		qty := r.Counted
+		if r.Unit != "" {
+			s, ok := stocks[l.StockID]
+			if !ok {
+				return nil, errors.ErrStockNotFound
+			}
+			var err error
+			if qty, err = toBase(s, r.Unit, r.Counted, fmt.Sprintf("lines[%d]", i)); err != nil {
+				return nil, err
+			}
+		}
+		variance := qty - l.SystemQuantity
+		l.Counted, l.Variance = &qty, &variance
+		if !containsCountLine(changed, l) {
+			changed = append(changed, l)
+		}
+	}
+	counted := true
+	for _, l := range count.Lines {
+		if l.Counted == nil {
+			counted = false
+			break
+		}
+	}
+	if counted && count.Status == objects.CountOpen {
+		count.Status = objects.CountSubmitted
+		count.SubmittedOn = &now
+	}
+	count.UpdatedOn = now
+	return changed, nil
+}
+
+func containsCountLine(lines []*objects.CountLine, l *objects.CountLine) bool {
+	for _, c := range lines {
+		if c == l {
+			return true
+		}
+	}
+	return false
+}
+
+// approveCount approves count and returns the adjustments of its variances, which
+// must leave the current availability of stocks non-negative
+func approveCount(count *objects.CountSession, stocks map[string]*objects.Stock, now time.Time) ([]*objects.StockMovement, error) {
+	if count.Status != objects.CountSubmitted {
+		return nil, errors.ErrInvalidTransition.WithMessage("Only submitted counts are approved")
+	}
+	var movements []*objects.StockMovement
+	for _, l := range count.Lines {
+		if *l.Variance == 0 {
+			continue
+		}
+		s, ok := stocks[l.StockID]
+		if !ok {
+			return nil, errors.ErrStockNotFound.WithMessage("Stock " + l.StockID + " not found")
+		}
+		if err := checkUnserialized(s); err != nil {
+			return nil, err
+		}
+		if s.Availability+*l.Variance < 0 {
+			return nil, errors.ErrStaleCount.WithField("lines", "stale_count", fmt.Sprintf("line %d leaves stock %s below zero", l.Line, l.StockID))
+		}
+		qty := *l.Variance
+		if qty < 0 {
+			qty = -qty
+		}
+		movements = append(movements, &objects.StockMovement{
+			TenantID:          count.TenantID,
+			StockID:           l.StockID,
+			Type:              objects.MovementCountAdjustment,
+			Quantity:          qty,
+			AvailabilityDelta: *l.Variance,
+			Reference:         count.ID,
+			Line:              l.Line,
+			CreatedOn:         now,
+		})
+	}
+	count.Status = objects.CountApproved
+	count.ApprovedOn = &now
+	count.UpdatedOn = now
+	return movements, nil
+}
+
+// cancelCount cancels count unless it is approved
+func cancelCount(count *objects.CountSession, now time.Time) error {
+	if count.Status != objects.CountOpen && count.Status != objects.CountSubmitted {
+		return errors.ErrInvalidTransition.WithMessage("Only open or submitted counts are cancelled")
+	}
+	count.Status = objects.CountCancelled
+	count.UpdatedOn = now
+	return nil
+}
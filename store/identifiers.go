@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+)
+
+// IIdentifierStore is the database interface for the SKUs, barcodes and external
+// identifiers of stocks
+type IIdentifierStore interface {
+	GetBySKU(ctx context.Context, in *objects.GetBySKURequest) (*objects.Stock, error)
+	// GetByBarcode matches any form of the barcode, e.g the EAN-13 of an UPC-A
+	GetByBarcode(ctx context.Context, in *objects.GetByBarcodeRequest) (*objects.Stock, error)
+	SetIdentifiers(ctx context.Context, in *objects.SetIdentifiersRequest) (*objects.Stock, error)
+	CreateExternalID(ctx context.Context, in *objects.CreateExternalIDRequest) (*objects.ExternalID, error)
+	ListExternalIDs(ctx context.Context, in *objects.ListExternalIDsRequest) ([]*objects.ExternalID, error)
+	GetByExternalID(ctx context.Context, in *objects.ExternalIDRequest) (*objects.Stock, error)
+	DeleteExternalID(ctx context.Context, in *objects.ExternalIDRequest) error
+}
+
+// gtin14 returns barcode zero padded to 14 digits, "" when empty
+func gtin14(barcode string) string {
+	if barcode == "" || len(barcode) >= 14 {
+		return barcode
+	}
+	return strings.Repeat("0", 14-len(barcode)) + barcode
+}
+
+// checkIdentifiers rejects s when one of others, the stocks sharing its SKU or
+// barcode, is another stock
+func checkIdentifiers(s *objects.Stock, others []*objects.Stock) error {
+	for _, o := range others {
+		switch {
+		case o.ID == s.ID:
+		case s.SKU != "" && o.SKU == s.SKU:
+			return errors.ErrSKUExists.WithMessage("Stock " + o.ID + " has the SKU " + s.SKU)
+		case s.GTIN != "" && o.GTIN == s.GTIN:
+			return errors.ErrBarcodeExists.WithMessage("Stock " + o.ID + " has the barcode " + o.Barcode)
+		}
+	}
+	return nil
+}
+
+// newExternalID returns the mapping of in to its stock
+func newExternalID(tenantID string, in *objects.CreateExternalIDRequest, now time.Time) *objects.ExternalID {
+	return &objects.ExternalID{
+		TenantID:   tenantID,
+		System:     in.System,
+		ExternalID: in.ExternalID,
+		StockID:    in.StockID,
+		CreatedOn:  now,
+	}
+}
@@ -0,0 +1,208 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go-inventory/objects"
+)
+
+// ILotStore is the database interface for the lots of stocks
+type ILotStore interface {
+	// CreateLot receives a lot, its quantity is added to the availability of its stock
+	CreateLot(ctx context.Context, in *objects.CreateLotRequest) (*objects.Lot, error)
+	GetLot(ctx context.Context, in *objects.GetRequest) (*objects.Lot, error)
+	ListLots(ctx context.Context, in *objects.ListLotsRequest) ([]*objects.Lot, error)
+	// ListExpiringLots returns the active lots holding units which expire within in.Days
+	ListExpiringLots(ctx context.Context, in *objects.ListExpiringLotsRequest) ([]*objects.Lot, error)
+	// QuarantineExpiredLots quarantines up to limit expired lots of every tenant, taking
+	// their units out of availability, and returns how many were quarantined
+	QuarantineExpiredLots(ctx context.Context, limit int) (int, error)
+}
+
+// newLot returns the active lot of in
+func newLot(tenantID string, in *objects.CreateLotRequest, now time.Time) *objects.Lot {
+	return &objects.Lot{
+		ID:             GenerateUniqueID(),
+		TenantID:       tenantID,
+		StockID:        in.StockID,
+		LotNumber:      in.LotNumber,
+		ManufacturedOn: in.ManufacturedOn,
+		ExpiresOn:      in.ExpiresOn,
+		Quantity:       in.Quantity,
+		Status:         objects.LotActive,
+		CreatedOn:      now,
+		UpdatedOn:      now,
+	}
+}
+
+// lotMovement returns the movement of qty units of lot changing availability by delta
+func lotMovement(lot *objects.Lot, typ string, qty, delta int, now time.Time) *objects.StockMovement {
+	return &objects.StockMovement{
+		TenantID:          lot.TenantID,
+		StockID:           lot.StockID,
+		Type:              typ,
+		Quantity:          qty,
+		AvailabilityDelta: delta,
+		Reference:         lot.ID,
+		CreatedOn:         now,
+	}
+}
+
+// fefo sorts lots first expired first out
+func fefo(lots []*objects.Lot) {
+	sort.Slice(lots, func(i, j int) bool {
+		if !lots[i].ExpiresOn.Equal(lots[j].ExpiresOn) {
+			return lots[i].ExpiresOn.Before(lots[j].ExpiresOn)
+		}
+		if !lots[i].CreatedOn.Equal(lots[j].CreatedOn) {
+			return lots[i].CreatedOn.Before(lots[j].CreatedOn)
+		}
+		return lots[i].ID < lots[j].ID
+	})
+}
+
+// pickLots picks the allocated units of the lines of order from lots, first expired first
+// out, and returns the lots it took units from. Expired and quarantined lots are skipped;
+// units which no lot holds, e.g received without a lot, are left unpicked.
+func pickLots(order *objects.SalesOrder, lots []*objects.Lot, now time.Time) []*objects.Lot {
+	byStock := pickable(lots, now)
+	var changed []*objects.Lot
+	for _, l := range order.Lines {
+		changed = pickLine(order, l, l.Allocated, byStock[l.StockID], now, changed)
+	}
+	return changed
+}
+
+// pickable returns by stock the lots units are picked from, first expired first out
+func pickable(lots []*objects.Lot, now time.Time) map[string][]*objects.Lot {
+	fefo(lots)
+	byStock := map[string][]*objects.Lot{}
+	for _, lot := range lots {
+		if lot.Status == objects.LotActive && !lot.Expired(now) && lot.Quantity > 0 {
+			byStock[lot.StockID] = append(byStock[lot.StockID], lot)
+		}
+	}
+	return byStock
+}
+
+// pickLine picks need units of line l of order from lots, adding to the picks of the
+// line, and returns changed with the lots it took units from
+func pickLine(order *objects.SalesOrder, l *objects.SalesOrderLine, need int, lots []*objects.Lot, now time.Time, changed []*objects.Lot) []*objects.Lot {
+	for _, lot := range lots {
+		if need == 0 {
+			break
+		}
+		n := lot.Quantity
+		if n == 0 {
+			continue
+		}
+		if n > need {
+			n = need
+		}
+		lot.Quantity -= n
+		lot.UpdatedOn = now
+		need -= n
+		if p := findPick(order, l.Line, lot.ID); p != nil {
+			p.Quantity += n
+		} else {
+			order.Picks = append(order.Picks, &objects.SalesOrderPick{
+				OrderID:   order.ID,
+				Line:      l.Line,
+				LotID:     lot.ID,
+				TenantID:  order.TenantID,
+				LotNumber: lot.LotNumber,
+				ExpiresOn: lot.ExpiresOn,
+				Quantity:  n,
+			})
+		}
+		if !containsLot(changed, lot) {
+			changed = append(changed, lot)
+		}
+	}
+	return changed
+}
+
+func findPick(order *objects.SalesOrder, line int, lotID string) *objects.SalesOrderPick {
+	for _, p := range order.Picks {
+		if p.Line == line && p.LotID == lotID {
+			return p
+		}
+	}
+	return nil
+}
+
+// unpick gives the picks of a cancelled order back to lots, by id, and returns by
+// stock the units given back to quarantined lots, which stay out of availability
+func unpick(order *objects.SalesOrder, lots map[string]*objects.Lot, now time.Time) map[string]int {
+	withheld := map[string]int{}
+	for _, p := range order.Picks {
+		lot, ok := lots[p.LotID]
+		if !ok {
+			continue
+		}
+		lot.Quantity += p.Quantity
+		lot.UpdatedOn = now
+		if lot.Status == objects.LotQuarantined {
+			withheld[lot.StockID] += p.Quantity
+		}
+	}
+	order.Picks = nil
+	return withheld
+}
+
+// quarantine quarantines an expired lot and returns its movement, the units of the
+// lot are taken out of availability, which never goes below zero because of it
+func quarantine(lot *objects.Lot, availability int, now time.Time) *objects.StockMovement {
+	removed := lot.Quantity
+	if removed > availability {
+		removed = availability
+	}
+	if removed < 0 {
+		removed = 0
+	}
+	lot.Status = objects.LotQuarantined
+	lot.QuarantinedOn = &now
+	lot.UpdatedOn = now
+	return lotMovement(lot, objects.MovementLotQuarantine, lot.Quantity, -removed, now)
+}
+
+// expireLots quarantines the expired active lots of s among lots, taking their units
+// out of the availability of s, and returns them with their movements. Allocations
+// run it on the locked stocks, the units of a lot expired since the last sweep are
+// never sold.
+func expireLots(s *objects.Stock, lots []*objects.Lot, now time.Time) ([]*objects.Lot, []*objects.StockMovement) {
+	var (
+		changed   []*objects.Lot
+		movements []*objects.StockMovement
+	)
+	for _, lot := range lots {
+		if lot.StockID != s.ID || lot.Status != objects.LotActive || !lot.Expired(now) {
+			continue
+		}
+		mv := quarantine(lot, s.Availability, now)
+		s.Availability += mv.AvailabilityDelta
+		changed = append(changed, lot)
+		movements = append(movements, mv)
+	}
+	return changed, movements
+}
+
+// lotIDs returns the distinct lots picked by order, in order
+func lotIDs(order *objects.SalesOrder) []string {
+	ids := make(map[string]int, len(order.Picks))
+	for _, p := range order.Picks {
+		ids[p.LotID]++
+	}
+	return sortedKeys(ids)
+}
+
+func containsLot(lots []*objects.Lot, lot *objects.Lot) bool {
+	for _, l := range lots {
+		if l == lot {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,499 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/tenant"
+)
+
+type memory struct {
+	mu sync.RWMutex
+	// stocks by tenant then by id
+	stocks map[string]map[string]*objects.Stock
+	// change log of every tenant, by increasing sequence number
+	events []*objects.StockEvent
+	// webhook subscriptions and deliveries by id
+	webhooks   map[string]*objects.WebhookSubscription
+	deliveries map[string]*objects.WebhookDelivery
+	// outbox messages by increasing sequence number
+	outbox []*objects.OutboxMessage
+	// suppliers and purchase orders by id
+	suppliers map[string]*objects.Supplier
+	orders    map[string]*objects.PurchaseOrder
+	// sales orders by id
+	salesOrders map[string]*objects.SalesOrder
+	// returns by id
+	returns map[string]*objects.Return
+	// stock movements by increasing sequence number
+	movements []*objects.StockMovement
+	// lots by id
+	lots map[string]*objects.Lot
+	// serials by serialKey
+	serials map[string]*objects.Serial
+	// products by id
+	products map[string]*objects.Product
+	// bundles by id
+	bundles map[string]*objects.Bundle
+	// external identifiers of stocks
+	externalIDs map[externalKey]*objects.ExternalID
+	// categories by id, the tree is walked through the parents
+	categories map[string]*objects.Category
+	// count sessions by id
+	counts map[string]*objects.CountSession
+}
+
+// NewMemoryStockStore returns an in memory implementation of Stock store,
+// following the same rules as the postgres one
+func NewMemoryStockStore() IStockStore {
+	return NewMemoryStores().Stocks
+}
+
+// NewMemoryStores returns the in memory implementation of every store
+func NewMemoryStores() *Stores {
+	m := &memory{
+		stocks:      map[string]map[string]*objects.Stock{},
+		webhooks:    map[string]*objects.WebhookSubscription{},
+		deliveries:  map[string]*objects.WebhookDelivery{},
+		suppliers:   map[string]*objects.Supplier{},
+		orders:      map[string]*objects.PurchaseOrder{},
+		salesOrders: map[string]*objects.SalesOrder{},
+		returns:     map[string]*objects.Return{},
+		lots:        map[string]*objects.Lot{},
+		serials:     map[string]*objects.Serial{},
+		products:    map[string]*objects.Product{},
+		bundles:     map[string]*objects.Bundle{},
+		externalIDs: map[externalKey]*objects.ExternalID{},
+		categories:  map[string]*objects.Category{},
+		counts:      map[string]*objects.CountSession{},
+	}
+	return &Stores{Stocks: m, Webhooks: m, Outbox: m, Purchasing: m, Sales: m, Returns: m, Movements: m, Lots: m, Serials: m, Products: m, Bundles: m, Units: m, Identifiers: m, Categories: m, Counts: m}
+}
+
+func (m *memory) Get(ctx context.Context, in *objects.GetRequest) (*objects.Stock, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	evt, ok := m.stocks[tenant.FromContext(ctx)][in.ID]
+	if !ok {
+		return nil, errors.ErrStockNotFound
+	}
+	cp := *evt
+	return &cp, nil
+}
+
+func (m *memory) List(ctx context.Context, in *objects.ListRequest) ([]*objects.Stock, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	list := make([]*objects.Stock, 0, in.Limit)
+	for _, evt := range m.stocks[tenant.FromContext(ctx)] {
+		if in.After != "" && evt.ID <= in.After {
+			continue
+		}
+		if in.Name != "" && !strings.Contains(strings.ToLower(evt.Name), strings.ToLower(in.Name)) {
+			continue
+		}
+		cp := *evt
+		list = append(list, &cp)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	if len(list) > in.Limit {
+		list = list[:in.Limit]
+	}
+	return list, nil
+}
+
+func (m *memory) Create(ctx context.Context, in *objects.CreateRequest) error {
+	if in.Stock == nil {
+		return errors.ErrObjectIsRequired
+	}
+	if err := checkNewStock(in.Stock); err != nil {
+		return err
+	}
+	tenantID := tenant.FromContext(ctx)
+	now := time.Now()
+	in.Stock.ID = GenerateUniqueID()
+	in.Stock.TenantID = tenantID
+	in.Stock.CreatedOn = now
+	in.Stock.UpdatedOn = now
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkIdentifiers(tenantID, in.Stock); err != nil {
+		return err
+	}
+	if err := m.checkCategory(tenantID, in.Stock.CategoryID); err != nil {
+		return err
+	}
+	if m.stocks[tenantID] == nil {
+		m.stocks[tenantID] = map[string]*objects.Stock{}
+	}
+	cp := *in.Stock
+	m.stocks[tenantID][cp.ID] = &cp
+	m.appendEvents(objects.StockEvents(nil, &cp))
+	return nil
+}
+
+func (m *memory) UpdateDetails(ctx context.Context, in *objects.UpdateDetailsRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.updateDetails(tenant.FromContext(ctx), in)
+}
+
+func (m *memory) BatchGet(ctx context.Context, in *objects.BatchGetRequest) ([]*objects.Stock, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	stocks := m.stocks[tenant.FromContext(ctx)]
+	list := make([]*objects.Stock, 0, len(in.IDs))
+	for _, id := range in.IDs {
+		if evt, ok := stocks[id]; ok {
+			cp := *evt
+			list = append(list, &cp)
+		}
+	}
+	return inOrder(list, in.IDs), nil
+}
+
+func (m *memory) BatchUpdate(ctx context.Context, in *objects.BatchUpdateRequest) ([]*objects.BatchUpdateResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenantID := tenant.FromContext(ctx)
+	results := make([]*objects.BatchUpdateResult, len(in.Items))
+	if in.Atomic {
+		// applied in turn to a copy, kept only when every item succeeds
+		c := m.clone()
+		for i, item := range in.Items {
+			if err := c.updateDetails(tenantID, item); err != nil {
+				results[i] = batchResult(item.ID, err)
+				abortBatch(in, results, i)
+				return results, nil
+			}
+			results[i] = batchResult(item.ID, nil)
+		}
+		m.restore(c)
+		return results, nil
+	}
+	for i, item := range in.Items {
+		results[i] = batchResult(item.ID, m.updateDetails(tenantID, item))
+	}
+	return results, nil
+}
+
+// updateDetails updates the stock of in owned by tenantID, the lock must be held
+func (m *memory) updateDetails(tenantID string, in *objects.UpdateDetailsRequest) error {
+	evt, ok := m.stocks[tenantID][in.ID]
+	if !ok {
+		// missing or owned by another tenant
+		return errors.ErrStockNotFound
+	}
+	if err := checkSerialized(evt, in); err != nil {
+		return err
+	}
+	old := *evt
+	evt.Name = in.Name
+	evt.Price = in.Price
+	evt.Availability = in.Availability
+	evt.IsActive = in.IsActive
+	evt.ReorderPoint = in.ReorderPoint
+	evt.SafetyStock = in.SafetyStock
+	evt.Backorderable = in.Backorderable
+	evt.BackorderLimit = in.BackorderLimit
+	evt.RestockOn = in.RestockOn
+	evt.Serialized = in.Serialized
+	evt.UpdatedOn = time.Now()
+	m.appendEvents(objects.StockEvents(&old, evt))
+	return nil
+}
+
+// adjustStock adds to the availability and the backordered units of a stock,
+// the lock must be held
+func (m *memory) adjustStock(tenantID, id string, availability, backordered int) error {
+	evt, ok := m.stocks[tenantID][id]
+	if !ok {
+		return errors.ErrStockNotFound
+	}
+	old := *evt
+	evt.Availability += availability
+	evt.Backordered += backordered
+	evt.UpdatedOn = time.Now()
+	m.appendEvents(objects.StockEvents(&old, evt))
+	return nil
+}
+
+// receiveStock adds qty received units to the availability of a stock and fulfils
+// its backorders with them, the lock must be held
+func (m *memory) receiveStock(tenantID, id string, qty int) error {
+	evt, ok := m.stocks[tenantID][id]
+	if !ok {
+		return errors.ErrStockNotFound
+	}
+	old := *evt
+	evt.Availability += qty
+	if evt.Backordered > 0 {
+		// expired units serve no backorder
+		now := time.Now()
+		lots := m.stockLots(tenantID, []string{id})
+		_, movements := expireLots(evt, lots, now)
+		m.appendMovements(movements...)
+		byStock := pickable(lots, now)
+		// first come first served
+		var orders []*objects.SalesOrder
+		for _, order := range m.salesOrders {
+			if order.TenantID == tenantID && order.Status == objects.SalesOrderBackordered {
+				orders = append(orders, order)
+			}
+		}
+		sort.Slice(orders, func(i, j int) bool {
+			if !orders[i].CreatedOn.Equal(orders[j].CreatedOn) {
+				return orders[i].CreatedOn.Before(orders[j].CreatedOn)
+			}
+			return orders[i].ID < orders[j].ID
+		})
+		for _, order := range orders {
+			var lines []*objects.SalesOrderLine
+			for _, l := range order.Lines {
+				if l.StockID == id && l.Backordered > 0 {
+					lines = append(lines, l)
+				}
+			}
+			fulfilled := fulfil(evt, lines)
+			if len(fulfilled) == 0 {
+				continue
+			}
+			for _, f := range fulfilled {
+				pickLine(order, f.line, f.qty, byStock[id], now, nil)
+			}
+			order.UpdatedOn = now
+			if !backordered(order) {
+				order.Status = objects.SalesOrderAllocated
+			}
+		}
+	}
+	evt.UpdatedOn = time.Now()
+	m.appendEvents(objects.StockEvents(&old, evt))
+	return nil
+}
+
+// backordered reports whether a line of order waits for a receipt
+func backordered(order *objects.SalesOrder) bool {
+	for _, l := range order.Lines {
+		if l.Backordered > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memory) ListLow(ctx context.Context, in *objects.ListLowRequest) ([]*objects.Stock, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	level := in.Level
+	if level == "" {
+		level = objects.LevelReorderPoint
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	list := make([]*objects.Stock, 0, in.Limit)
+	for _, evt := range m.stocks[tenant.FromContext(ctx)] {
+		if (in.After != "" && evt.ID <= in.After) || !evt.Below(level) {
+			continue
+		}
+		cp := *evt
+		list = append(list, &cp)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	if len(list) > in.Limit {
+		list = list[:in.Limit]
+	}
+	return list, nil
+}
+
+func (m *memory) ListEvents(ctx context.Context, in *objects.ListEventsRequest) ([]*objects.StockEvent, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxEventsLimit {
+		in.Limit = objects.MaxEventsLimit
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	// sequence numbers start at 1 and have no gaps
+	start := int(in.After)
+	if start < 0 {
+		start = 0
+	}
+	if start > len(m.events) {
+		start = len(m.events)
+	}
+	list := make([]*objects.StockEvent, 0, in.Limit)
+	for _, e := range m.events[start:] {
+		if len(list) == in.Limit {
+			break
+		}
+		if e.TenantID != tenantID || !contains(in.StockIDs, e.StockID) || !contains(in.Types, e.Type) {
+			continue
+		}
+		cp := *e
+		list = append(list, &cp)
+	}
+	return list, nil
+}
+
+// Transaction runs fn on a copy of the store, holding the lock,
+// and keeps the copy when fn succeeds
+func (m *memory) Transaction(_ context.Context, fn func(tx IStockStore) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tx := m.clone()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	m.restore(tx)
+	return nil
+}
+
+// clone returns a copy of the state of m, changing the copy leaves m untouched.
+// The lock must be held.
+func (m *memory) clone() *memory {
+	c := &memory{
+		stocks:      make(map[string]map[string]*objects.Stock, len(m.stocks)),
+		events:      append([]*objects.StockEvent(nil), m.events...),
+		webhooks:    make(map[string]*objects.WebhookSubscription, len(m.webhooks)),
+		deliveries:  make(map[string]*objects.WebhookDelivery, len(m.deliveries)),
+		outbox:      make([]*objects.OutboxMessage, 0, len(m.outbox)),
+		suppliers:   make(map[string]*objects.Supplier, len(m.suppliers)),
+		orders:      make(map[string]*objects.PurchaseOrder, len(m.orders)),
+		salesOrders: make(map[string]*objects.SalesOrder, len(m.salesOrders)),
+		returns:     make(map[string]*objects.Return, len(m.returns)),
+		movements:   append([]*objects.StockMovement(nil), m.movements...),
+		lots:        make(map[string]*objects.Lot, len(m.lots)),
+		serials:     make(map[string]*objects.Serial, len(m.serials)),
+		products:    make(map[string]*objects.Product, len(m.products)),
+		bundles:     make(map[string]*objects.Bundle, len(m.bundles)),
+		externalIDs: make(map[externalKey]*objects.ExternalID, len(m.externalIDs)),
+		categories:  make(map[string]*objects.Category, len(m.categories)),
+		counts:      make(map[string]*objects.CountSession, len(m.counts)),
+	}
+	for tenantID, stocks := range m.stocks {
+		c.stocks[tenantID] = make(map[string]*objects.Stock, len(stocks))
+		for id, evt := range stocks {
+			cp := *evt
+			c.stocks[tenantID][id] = &cp
+		}
+	}
+	// events, subscriptions, suppliers, products, bundles, external identifiers,
+	// categories and movements are never changed in place
+	for id, s := range m.webhooks {
+		c.webhooks[id] = s
+	}
+	for id, s := range m.suppliers {
+		c.suppliers[id] = s
+	}
+	for id, product := range m.products {
+		c.products[id] = product
+	}
+	for id, bundle := range m.bundles {
+		c.bundles[id] = bundle
+	}
+	for key, ext := range m.externalIDs {
+		c.externalIDs[key] = ext
+	}
+	for id, category := range m.categories {
+		c.categories[id] = category
+	}
+	for id, order := range m.orders {
+		c.orders[id] = clonePurchaseOrder(order)
+	}
+	for id, order := range m.salesOrders {
+		c.salesOrders[id] = cloneSalesOrder(order)
+	}
+	for id, ret := range m.returns {
+		c.returns[id] = cloneReturn(ret)
+	}
+	for id, count := range m.counts {
+		c.counts[id] = cloneCount(count)
+	}
+	for id, lot := range m.lots {
+		cp := *lot
+		c.lots[id] = &cp
+	}
+	for key, s := range m.serials {
+		cp := *s
+		c.serials[key] = &cp
+	}
+	for id, d := range m.deliveries {
+		cp := *d
+		c.deliveries[id] = &cp
+	}
+	for _, msg := range m.outbox {
+		cp := *msg
+		c.outbox = append(c.outbox, &cp)
+	}
+	return c
+}
+
+// restore replaces the state of m with the one of c, the lock must be held
+func (m *memory) restore(c *memory) {
+	m.stocks = c.stocks
+	m.events = c.events
+	m.webhooks = c.webhooks
+	m.deliveries = c.deliveries
+	m.outbox = c.outbox
+	m.suppliers = c.suppliers
+	m.orders = c.orders
+	m.salesOrders = c.salesOrders
+	m.returns = c.returns
+	m.movements = c.movements
+	m.lots = c.lots
+	m.serials = c.serials
+	m.products = c.products
+	m.bundles = c.bundles
+	m.externalIDs = c.externalIDs
+	m.categories = c.categories
+	m.counts = c.counts
+}
+
+// appendEvents numbers and logs events, writes them to the outbox
+// and enqueues their webhook deliveries, the lock must be held
+func (m *memory) appendEvents(events []*objects.StockEvent) {
+	for _, e := range events {
+		e.Seq = int64(len(m.events) + 1)
+		m.events = append(m.events, e)
+	}
+	if len(events) == 0 {
+		return
+	}
+	// one message per event, numbered alike so that pruning never reuses a number
+	for i, msg := range outboxMessages(events, time.Now()) {
+		msg.Seq = events[i].Seq
+		m.outbox = append(m.outbox, msg)
+	}
+	var subs []*objects.WebhookSubscription
+	for _, s := range m.webhooks {
+		if s.TenantID == events[0].TenantID {
+			subs = append(subs, s)
+		}
+	}
+	for _, d := range newDeliveries(subs, events, time.Now()) {
+		m.deliveries[d.ID] = d
+	}
+}
+
+// contains reports whether v is in values, an empty filter matches everything
+func contains(values []string, v string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/tenant"
+)
+
+func (m *memory) CreateBundle(ctx context.Context, in *objects.CreateBundleRequest) (*objects.Bundle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenantID := tenant.FromContext(ctx)
+	bundle, err := newBundle(tenantID, in, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkStocks(tenantID, componentIDs(bundle)); err != nil {
+		return nil, err
+	}
+	m.bundles[bundle.ID] = cloneBundle(bundle)
+	bundle.Availability = buildable(bundle, m.stocks[tenantID])
+	return bundle, nil
+}
+
+func (m *memory) GetBundle(ctx context.Context, in *objects.GetRequest) (*objects.Bundle, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	bundle, ok := m.bundles[in.ID]
+	if !ok || bundle.TenantID != tenantID {
+		return nil, errors.ErrBundleNotFound
+	}
+	cp := cloneBundle(bundle)
+	cp.Availability = buildable(cp, m.stocks[tenantID])
+	return cp, nil
+}
+
+func (m *memory) ListBundles(ctx context.Context, in *objects.ListRequest) ([]*objects.Bundle, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	list := make([]*objects.Bundle, 0, in.Limit)
+	for _, bundle := range m.bundles {
+		if bundle.TenantID != tenantID || (in.After != "" && bundle.ID <= in.After) {
+			continue
+		}
+		if in.Name != "" && !strings.Contains(strings.ToLower(bundle.Name), strings.ToLower(in.Name)) {
+			continue
+		}
+		cp := cloneBundle(bundle)
+		cp.Availability = buildable(cp, m.stocks[tenantID])
+		list = append(list, cp)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	if len(list) > in.Limit {
+		list = list[:in.Limit]
+	}
+	return list, nil
+}
+
+func (m *memory) SellBundle(ctx context.Context, in *objects.SellBundleRequest) (*objects.SalesOrder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenantID := tenant.FromContext(ctx)
+	bundle, ok := m.bundles[in.ID]
+	if !ok || bundle.TenantID != tenantID {
+		return nil, errors.ErrBundleNotFound
+	}
+	// expired units cannot build bundles
+	if err := m.expireLots(tenantID, componentIDs(bundle), time.Now()); err != nil {
+		return nil, err
+	}
+	req, err := bundleOrder(bundle, m.stocks[tenantID], in)
+	if err != nil {
+		return nil, err
+	}
+	return m.createSalesOrder(tenantID, req)
+}
+
+func cloneBundle(bundle *objects.Bundle) *objects.Bundle {
+	cp := *bundle
+	cp.Components = make([]*objects.BundleComponent, 0, len(bundle.Components))
+	for _, c := range bundle.Components {
+		cc := *c
+		cp.Components = append(cp.Components, &cc)
+	}
+	return &cp
+}
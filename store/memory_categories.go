@@ -0,0 +1,230 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/tenant"
+)
+
+func (m *memory) CreateCategory(ctx context.Context, in *objects.CreateCategoryRequest) (*objects.Category, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenantID := tenant.FromContext(ctx)
+	if in.ParentID != "" && m.category(tenantID, in.ParentID) == nil {
+		return nil, errParentNotFound
+	}
+	if err := m.checkSiblings(tenantID, in.ParentID, in.Name, ""); err != nil {
+		return nil, err
+	}
+	category := newCategory(tenantID, in, time.Now())
+	cp := *category
+	m.categories[cp.ID] = &cp
+	return category, nil
+}
+
+func (m *memory) GetCategory(ctx context.Context, in *objects.GetRequest) (*objects.Category, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	category := m.category(tenant.FromContext(ctx), in.ID)
+	if category == nil {
+		return nil, errors.ErrCategoryNotFound
+	}
+	cp := *category
+	return &cp, nil
+}
+
+func (m *memory) ListCategories(ctx context.Context, in *objects.ListCategoriesRequest) ([]*objects.Category, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	list := make([]*objects.Category, 0, in.Limit)
+	for _, category := range m.categories {
+		if category.TenantID != tenantID || (in.After != "" && category.ID <= in.After) {
+			continue
+		}
+		if in.ParentID != "" && category.ParentID != in.ParentID {
+			continue
+		}
+		cp := *category
+		list = append(list, &cp)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	if len(list) > in.Limit {
+		list = list[:in.Limit]
+	}
+	return list, nil
+}
+
+func (m *memory) UpdateCategory(ctx context.Context, in *objects.UpdateCategoryRequest) (*objects.Category, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenantID := tenant.FromContext(ctx)
+	old := m.category(tenantID, in.ID)
+	if old == nil {
+		return nil, errors.ErrCategoryNotFound
+	}
+	moved := in.ParentID != old.ParentID
+	if moved && in.ParentID != "" {
+		if m.category(tenantID, in.ParentID) == nil {
+			return nil, errParentNotFound
+		}
+		if m.subtree(tenantID, old.ID)[in.ParentID] {
+			return nil, errCycle
+		}
+	}
+	if moved || in.Name != old.Name {
+		if err := m.checkSiblings(tenantID, in.ParentID, in.Name, old.ID); err != nil {
+			return nil, err
+		}
+	}
+	category := *old
+	category.Name = in.Name
+	category.ParentID = in.ParentID
+	category.UpdatedOn = time.Now()
+	// replaced, never changed in place
+	cp := category
+	m.categories[cp.ID] = &cp
+	return &category, nil
+}
+
+func (m *memory) DeleteCategory(ctx context.Context, in *objects.DeleteRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenantID := tenant.FromContext(ctx)
+	if m.category(tenantID, in.ID) == nil {
+		return errors.ErrCategoryNotFound
+	}
+	for _, category := range m.categories {
+		if category.TenantID == tenantID && category.ParentID == in.ID {
+			return errors.ErrCategoryNotEmpty
+		}
+	}
+	for _, evt := range m.stocks[tenantID] {
+		if evt.CategoryID == in.ID {
+			return errors.ErrCategoryNotEmpty
+		}
+	}
+	delete(m.categories, in.ID)
+	return nil
+}
+
+func (m *memory) ListCategoryStocks(ctx context.Context, in *objects.ListCategoryStocksRequest) ([]*objects.Stock, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	if m.category(tenantID, in.CategoryID) == nil {
+		return nil, errors.ErrCategoryNotFound
+	}
+	subtree := m.subtree(tenantID, in.CategoryID)
+	tag := normalizeTag(in.Tag)
+	return m.listStocksWhere(tenantID, in.Limit, in.After, func(s *objects.Stock) bool {
+		return subtree[s.CategoryID] && (tag == "" || hasTag(s, tag))
+	}), nil
+}
+
+func (m *memory) ListTagStocks(ctx context.Context, in *objects.ListTagStocksRequest) ([]*objects.Stock, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tag := normalizeTag(in.Tag)
+	return m.listStocksWhere(tenant.FromContext(ctx), in.Limit, in.After, func(s *objects.Stock) bool {
+		return hasTag(s, tag)
+	}), nil
+}
+
+func (m *memory) SetClassification(ctx context.Context, in *objects.SetClassificationRequest) (*objects.Stock, error) {
+	tags, err := normalizeTags(in.Tags)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenantID := tenant.FromContext(ctx)
+	evt, ok := m.stocks[tenantID][in.ID]
+	if !ok {
+		return nil, errors.ErrStockNotFound
+	}
+	if err := m.checkCategory(tenantID, in.CategoryID); err != nil {
+		return nil, err
+	}
+	old := *evt
+	evt.CategoryID = in.CategoryID
+	evt.Tags = tags
+	evt.UpdatedOn = time.Now()
+	m.appendEvents(objects.StockEvents(&old, evt))
+	cp := *evt
+	return &cp, nil
+}
+
+// category returns the category id of tenantID, nil when missing, the lock must be held
+func (m *memory) category(tenantID, id string) *objects.Category {
+	category, ok := m.categories[id]
+	if !ok || category.TenantID != tenantID {
+		return nil
+	}
+	return category
+}
+
+// checkCategory fails with errors.ErrCategoryNotFound when the category id is set
+// and missing, the lock must be held
+func (m *memory) checkCategory(tenantID, id string) error {
+	if id != "" && m.category(tenantID, id) == nil {
+		return errors.ErrCategoryNotFound.WithField("category_id", "category_not_found", "no such category")
+	}
+	return nil
+}
+
+// checkSiblings rejects name when another child of parentID than id has it,
+// the lock must be held
+func (m *memory) checkSiblings(tenantID, parentID, name, id string) error {
+	for _, category := range m.categories {
+		if category.TenantID == tenantID && category.ParentID == parentID && category.Name == name && category.ID != id {
+			return errors.ErrCategoryExists
+		}
+	}
+	return nil
+}
+
+// subtree returns the ids of the category id and of its descendants, the lock must be held
+func (m *memory) subtree(tenantID, id string) map[string]bool {
+	children := map[string][]string{}
+	for _, category := range m.categories {
+		if category.TenantID == tenantID {
+			children[category.ParentID] = append(children[category.ParentID], category.ID)
+		}
+	}
+	res := map[string]bool{id: true}
+	for queue := []string{id}; len(queue) > 0; queue = queue[1:] {
+		for _, child := range children[queue[0]] {
+			res[child] = true
+			queue = append(queue, child)
+		}
+	}
+	return res
+}
+
+// listStocksWhere lists the stocks of tenantID matching fn by id, the lock must be held
+func (m *memory) listStocksWhere(tenantID string, limit int, after string, fn func(s *objects.Stock) bool) []*objects.Stock {
+	if limit == 0 || limit > objects.MaxListLimit {
+		limit = objects.MaxListLimit
+	}
+	list := make([]*objects.Stock, 0, limit)
+	for _, evt := range m.stocks[tenantID] {
+		if (after != "" && evt.ID <= after) || !fn(evt) {
+			continue
+		}
+		cp := *evt
+		list = append(list, &cp)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	return list
+}
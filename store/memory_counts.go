@@ -0,0 +1,153 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/tenant"
+)
+
+func (m *memory) CreateCount(ctx context.Context, in *objects.CreateCountRequest) (*objects.CountSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenantID := tenant.FromContext(ctx)
+	var list []*objects.Stock
+	if in.CategoryID != "" {
+		if err := m.checkCategory(tenantID, in.CategoryID); err != nil {
+			return nil, err
+		}
+		ids := m.subtree(tenantID, in.CategoryID)
+		for _, s := range m.stocks[tenantID] {
+			if ids[s.CategoryID] {
+				list = append(list, s)
+			}
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	} else {
+		ids := countStockIDs(in)
+		if err := m.checkStocks(tenantID, ids); err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			list = append(list, m.stocks[tenantID][id])
+		}
+	}
+	count, err := newCount(tenantID, in, list, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	m.counts[count.ID] = cloneCount(count)
+	return count, nil
+}
+
+func (m *memory) GetCount(ctx context.Context, in *objects.GetRequest) (*objects.CountSession, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	count, ok := m.counts[in.ID]
+	if !ok || count.TenantID != tenant.FromContext(ctx) {
+		return nil, errors.ErrCountNotFound
+	}
+	return cloneCount(count), nil
+}
+
+func (m *memory) ListCounts(ctx context.Context, in *objects.ListCountsRequest) ([]*objects.CountSession, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	list := make([]*objects.CountSession, 0, in.Limit)
+	for _, count := range m.counts {
+		if count.TenantID != tenantID || (in.After != "" && count.ID <= in.After) {
+			continue
+		}
+		if in.Status != "" && count.Status != in.Status {
+			continue
+		}
+		list = append(list, cloneCount(count))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	if len(list) > in.Limit {
+		list = list[:in.Limit]
+	}
+	return list, nil
+}
+
+func (m *memory) SubmitCount(ctx context.Context, in *objects.SubmitCountRequest) (*objects.CountSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenantID := tenant.FromContext(ctx)
+	count, ok := m.counts[in.ID]
+	if !ok || count.TenantID != tenantID {
+		return nil, errors.ErrCountNotFound
+	}
+	cp := cloneCount(count)
+	if _, err := recordCounts(cp, in, m.stocks[tenantID], time.Now()); err != nil {
+		return nil, err
+	}
+	m.counts[cp.ID] = cp
+	return cloneCount(cp), nil
+}
+
+func (m *memory) ApproveCount(ctx context.Context, in *objects.GetRequest) (*objects.CountSession, []*objects.StockMovement, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenantID := tenant.FromContext(ctx)
+	count, ok := m.counts[in.ID]
+	if !ok || count.TenantID != tenantID {
+		return nil, nil, errors.ErrCountNotFound
+	}
+	cp := cloneCount(count)
+	movements, err := approveCount(cp, m.stocks[tenantID], time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	// found units are received, they serve backorders first
+	for _, mv := range movements {
+		if mv.AvailabilityDelta > 0 {
+			err = m.receiveStock(tenantID, mv.StockID, mv.AvailabilityDelta)
+		} else {
+			err = m.adjustStock(tenantID, mv.StockID, mv.AvailabilityDelta, 0)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	m.appendMovements(movements...)
+	res := make([]*objects.StockMovement, 0, len(movements))
+	for _, mv := range movements {
+		c := *mv
+		res = append(res, &c)
+	}
+	m.counts[cp.ID] = cp
+	return cloneCount(cp), res, nil
+}
+
+func (m *memory) CancelCount(ctx context.Context, in *objects.GetRequest) (*objects.CountSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	count, ok := m.counts[in.ID]
+	if !ok || count.TenantID != tenant.FromContext(ctx) {
+		return nil, errors.ErrCountNotFound
+	}
+	cp := cloneCount(count)
+	if err := cancelCount(cp, time.Now()); err != nil {
+		return nil, err
+	}
+	m.counts[cp.ID] = cp
+	return cloneCount(cp), nil
+}
+
+func cloneCount(count *objects.CountSession) *objects.CountSession {
+	cp := *count
+	cp.Lines = make([]*objects.CountLine, 0, len(count.Lines))
+	for _, l := range count.Lines {
+		lc := *l
+		cp.Lines = append(cp.Lines, &lc)
+	}
+	return &cp
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/tenant"
+)
+
+// externalKey key of the external identifiers of the memory store
+type externalKey struct {
+	tenantID, system, externalID string
+}
+
+func (m *memory) GetBySKU(ctx context.Context, in *objects.GetBySKURequest) (*objects.Stock, error) {
+	return m.getStockWhere(ctx, func(s *objects.Stock) bool { return s.SKU == in.SKU })
+}
+
+func (m *memory) GetByBarcode(ctx context.Context, in *objects.GetByBarcodeRequest) (*objects.Stock, error) {
+	code := gtin14(in.Barcode)
+	return m.getStockWhere(ctx, func(s *objects.Stock) bool { return s.GTIN == code })
+}
+
+func (m *memory) SetIdentifiers(ctx context.Context, in *objects.SetIdentifiersRequest) (*objects.Stock, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenantID := tenant.FromContext(ctx)
+	evt, ok := m.stocks[tenantID][in.ID]
+	if !ok {
+		return nil, errors.ErrStockNotFound
+	}
+	cur := *evt
+	cur.SKU = in.SKU
+	cur.Barcode = in.Barcode
+	cur.GTIN = gtin14(in.Barcode)
+	if err := m.checkIdentifiers(tenantID, &cur); err != nil {
+		return nil, err
+	}
+	cur.UpdatedOn = time.Now()
+	old := *evt
+	*evt = cur
+	m.appendEvents(objects.StockEvents(&old, evt))
+	return &cur, nil
+}
+
+func (m *memory) CreateExternalID(ctx context.Context, in *objects.CreateExternalIDRequest) (*objects.ExternalID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenantID := tenant.FromContext(ctx)
+	if err := m.checkStocks(tenantID, []string{in.StockID}); err != nil {
+		return nil, err
+	}
+	key := externalKey{tenantID, in.System, in.ExternalID}
+	if _, ok := m.externalIDs[key]; ok {
+		return nil, errors.ErrExternalIDExists
+	}
+	ext := newExternalID(tenantID, in, time.Now())
+	cp := *ext
+	m.externalIDs[key] = &cp
+	return ext, nil
+}
+
+func (m *memory) ListExternalIDs(ctx context.Context, in *objects.ListExternalIDsRequest) ([]*objects.ExternalID, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	if err := m.checkStocks(tenantID, []string{in.StockID}); err != nil {
+		return nil, err
+	}
+	list := []*objects.ExternalID{}
+	for key, ext := range m.externalIDs {
+		if key.tenantID == tenantID && ext.StockID == in.StockID {
+			cp := *ext
+			list = append(list, &cp)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].System != list[j].System {
+			return list[i].System < list[j].System
+		}
+		return list[i].ExternalID < list[j].ExternalID
+	})
+	return list, nil
+}
+
+func (m *memory) GetByExternalID(ctx context.Context, in *objects.ExternalIDRequest) (*objects.Stock, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	ext, ok := m.externalIDs[externalKey{tenantID, in.System, in.ExternalID}]
+	if !ok {
+		return nil, errors.ErrExternalIDNotFound
+	}
+	evt, ok := m.stocks[tenantID][ext.StockID]
+	if !ok {
+		return nil, errors.ErrStockNotFound
+	}
+	cp := *evt
+	return &cp, nil
+}
+
+func (m *memory) DeleteExternalID(ctx context.Context, in *objects.ExternalIDRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := externalKey{tenant.FromContext(ctx), in.System, in.ExternalID}
+	ext, ok := m.externalIDs[key]
+	if !ok || (in.StockID != "" && ext.StockID != in.StockID) {
+		return errors.ErrExternalIDNotFound
+	}
+	delete(m.externalIDs, key)
+	return nil
+}
+
+// getStockWhere returns the stock of the tenant of ctx matching fn
+func (m *memory) getStockWhere(ctx context.Context, fn func(s *objects.Stock) bool) (*objects.Stock, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, evt := range m.stocks[tenant.FromContext(ctx)] {
+		if fn(evt) {
+			cp := *evt
+			return &cp, nil
+		}
+	}
+	return nil, errors.ErrStockNotFound
+}
+
+// checkIdentifiers rejects the SKU and barcode of s when another stock has one of them,
+// the lock must be held
+func (m *memory) checkIdentifiers(tenantID string, s *objects.Stock) error {
+	var others []*objects.Stock
+	for _, evt := range m.stocks[tenantID] {
+		if (s.SKU != "" && evt.SKU == s.SKU) || (s.GTIN != "" && evt.GTIN == s.GTIN) {
+			others = append(others, evt)
+		}
+	}
+	return checkIdentifiers(s, others)
+}
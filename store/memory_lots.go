@@ -0,0 +1,158 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/tenant"
+)
+
+func (m *memory) CreateLot(ctx context.Context, in *objects.CreateLotRequest) (*objects.Lot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenantID := tenant.FromContext(ctx)
+	stock, ok := m.stocks[tenantID][in.StockID]
+	if !ok {
+		return nil, errors.ErrStockNotFound
+	}
+	if err := checkUnserialized(stock); err != nil {
+		return nil, err
+	}
+	qty, err := toBase(stock, in.Unit, in.Quantity, "quantity")
+	if err != nil {
+		return nil, err
+	}
+	in.Quantity, in.Unit = qty, ""
+	for _, lot := range m.lots {
+		if lot.TenantID == tenantID && lot.StockID == in.StockID && lot.LotNumber == in.LotNumber {
+			return nil, errors.ErrLotExists
+		}
+	}
+	now := time.Now()
+	lot := newLot(tenantID, in, now)
+	// stored first, backorders fulfilled by the receipt are picked from it
+	m.lots[lot.ID] = lot
+	if err := m.receiveStock(tenantID, lot.StockID, lot.Quantity); err != nil {
+		delete(m.lots, lot.ID)
+		return nil, err
+	}
+	m.appendMovements(lotMovement(lot, objects.MovementLotReceipt, in.Quantity, in.Quantity, now))
+	cp := *lot
+	return &cp, nil
+}
+
+func (m *memory) GetLot(ctx context.Context, in *objects.GetRequest) (*objects.Lot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	lot, ok := m.lots[in.ID]
+	if !ok || lot.TenantID != tenant.FromContext(ctx) {
+		return nil, errors.ErrLotNotFound
+	}
+	cp := *lot
+	return &cp, nil
+}
+
+func (m *memory) ListLots(ctx context.Context, in *objects.ListLotsRequest) ([]*objects.Lot, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	return m.findLots(in.Limit, func(lot *objects.Lot) bool {
+		return lot.TenantID == tenantID && lot.StockID == in.StockID && (in.Status == "" || lot.Status == in.Status)
+	}), nil
+}
+
+func (m *memory) ListExpiringLots(ctx context.Context, in *objects.ListExpiringLotsRequest) ([]*objects.Lot, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	until := time.Now().AddDate(0, 0, in.Days)
+	return m.findLots(in.Limit, func(lot *objects.Lot) bool {
+		return lot.TenantID == tenantID && lot.Status == objects.LotActive && lot.Quantity > 0 && !lot.ExpiresOn.After(until)
+	}), nil
+}
+
+func (m *memory) QuarantineExpiredLots(_ context.Context, limit int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	expired := m.findLots(limit, func(lot *objects.Lot) bool {
+		return lot.Status == objects.LotActive && lot.Expired(now)
+	})
+	for _, found := range expired {
+		lot := m.lots[found.ID]
+		stock, ok := m.stocks[lot.TenantID][lot.StockID]
+		if !ok {
+			return 0, errors.ErrStockNotFound
+		}
+		mv := quarantine(lot, stock.Availability, now)
+		if err := m.adjustStock(lot.TenantID, lot.StockID, mv.AvailabilityDelta, 0); err != nil {
+			return 0, err
+		}
+		m.appendMovements(mv)
+	}
+	return len(expired), nil
+}
+
+// findLots returns copies of up to limit lots matching keep, soonest expiry first,
+// the lock must be held
+func (m *memory) findLots(limit int, keep func(lot *objects.Lot) bool) []*objects.Lot {
+	list := make([]*objects.Lot, 0, limit)
+	for _, lot := range m.lots {
+		if keep(lot) {
+			cp := *lot
+			list = append(list, &cp)
+		}
+	}
+	fefo(list)
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	return list
+}
+
+// stockLots returns the lots of the stocks of ids, the lock must be held
+func (m *memory) stockLots(tenantID string, ids []string) []*objects.Lot {
+	var lots []*objects.Lot
+	for _, lot := range m.lots {
+		if lot.TenantID == tenantID && contains(ids, lot.StockID) {
+			lots = append(lots, lot)
+		}
+	}
+	return lots
+}
+
+// expireLots quarantines the expired lots of the stocks of ids, the lock must be held
+func (m *memory) expireLots(tenantID string, ids []string, now time.Time) error {
+	for _, id := range ids {
+		s, ok := m.stocks[tenantID][id]
+		if !ok {
+			continue
+		}
+		cp := *s
+		_, movements := expireLots(&cp, m.stockLots(tenantID, []string{id}), now)
+		if len(movements) == 0 {
+			continue
+		}
+		m.appendMovements(movements...)
+		if err := m.adjustStock(tenantID, id, cp.Availability-s.Availability, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendMovements numbers and records movements, the lock must be held
+func (m *memory) appendMovements(movements ...*objects.StockMovement) {
+	for _, mv := range movements {
+		mv.Seq = int64(len(m.movements) + 1)
+		m.movements = append(m.movements, mv)
+	}
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go-inventory/objects"
+)
+
+func (m *memory) ClaimOutbox(_ context.Context, topics []string, now time.Time, lease time.Duration, limit int) ([]*objects.OutboxMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]*objects.OutboxMessage, 0, limit)
+	for _, msg := range m.outbox {
+		if len(list) == limit {
+			break
+		}
+		if !dueMessage(msg, now) || !relayed(topics, msg.Topic) {
+			continue
+		}
+		until := now.Add(lease)
+		msg.NextAttemptOn = &until
+		cp := *msg
+		list = append(list, &cp)
+	}
+	return list, nil
+}
+
+func (m *memory) SaveOutboxMessage(_ context.Context, in *objects.OutboxMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// sorted by sequence number, a pruned message is gone
+	i := sort.Search(len(m.outbox), func(i int) bool { return m.outbox[i].Seq >= in.Seq })
+	if i == len(m.outbox) || m.outbox[i].Seq != in.Seq {
+		return nil
+	}
+	msg := m.outbox[i]
+	msg.PublishedOn = in.PublishedOn
+	msg.DeadOn = in.DeadOn
+	msg.Attempts = in.Attempts
+	msg.LastError = in.LastError
+	msg.NextAttemptOn = in.NextAttemptOn
+	return nil
+}
+
+func (m *memory) PruneOutbox(_ context.Context, topics []string, before time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.outbox[:0]
+	for _, msg := range m.outbox {
+		if !prunable(msg, topics, before) {
+			kept = append(kept, msg)
+		}
+	}
+	n := len(m.outbox) - len(kept)
+	m.outbox = kept
+	return n, nil
+}
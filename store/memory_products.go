@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/tenant"
+)
+
+func (m *memory) CreateProduct(ctx context.Context, in *objects.CreateProductRequest) (*objects.Product, error) {
+	if err := checkDefinitions(in.Attributes); err != nil {
+		return nil, err
+	}
+	product := newProduct(tenant.FromContext(ctx), in, time.Now())
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cp := *product
+	m.products[product.ID] = &cp
+	return product, nil
+}
+
+func (m *memory) GetProduct(ctx context.Context, in *objects.GetRequest) (*objects.Product, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	product, ok := m.products[in.ID]
+	if !ok || product.TenantID != tenant.FromContext(ctx) {
+		return nil, errors.ErrProductNotFound
+	}
+	cp := *product
+	return &cp, nil
+}
+
+func (m *memory) ListProducts(ctx context.Context, in *objects.ListRequest) ([]*objects.Product, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	list := make([]*objects.Product, 0, in.Limit)
+	for _, product := range m.products {
+		if product.TenantID != tenantID || (in.After != "" && product.ID <= in.After) {
+			continue
+		}
+		if in.Name != "" && !strings.Contains(strings.ToLower(product.Name), strings.ToLower(in.Name)) {
+			continue
+		}
+		cp := *product
+		list = append(list, &cp)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	if len(list) > in.Limit {
+		list = list[:in.Limit]
+	}
+	return list, nil
+}
+
+func (m *memory) CreateVariant(ctx context.Context, in *objects.CreateVariantRequest) (*objects.Stock, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenantID := tenant.FromContext(ctx)
+	product, ok := m.products[in.ProductID]
+	if !ok || product.TenantID != tenantID {
+		return nil, errors.ErrProductNotFound
+	}
+	variant, err := newVariant(product, m.variants(tenantID, product.ID), in, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkIdentifiers(tenantID, variant); err != nil {
+		return nil, err
+	}
+	if m.stocks[tenantID] == nil {
+		m.stocks[tenantID] = map[string]*objects.Stock{}
+	}
+	cp := *variant
+	m.stocks[tenantID][cp.ID] = &cp
+	m.appendEvents(objects.StockEvents(nil, &cp))
+	return variant, nil
+}
+
+func (m *memory) ListVariants(ctx context.Context, in *objects.ListVariantsRequest) ([]*objects.Stock, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	if product, ok := m.products[in.ProductID]; !ok || product.TenantID != tenantID {
+		return nil, errors.ErrProductNotFound
+	}
+	list := make([]*objects.Stock, 0, in.Limit)
+	for _, v := range m.variants(tenantID, in.ProductID) {
+		if in.After != "" && v.ID <= in.After {
+			continue
+		}
+		cp := *v
+		list = append(list, &cp)
+	}
+	if len(list) > in.Limit {
+		list = list[:in.Limit]
+	}
+	return list, nil
+}
+
+func (m *memory) ProductAvailability(ctx context.Context, in *objects.GetRequest) (*objects.ProductAvailability, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	if product, ok := m.products[in.ID]; !ok || product.TenantID != tenantID {
+		return nil, errors.ErrProductNotFound
+	}
+	return aggregate(in.ID, m.variants(tenantID, in.ID)), nil
+}
+
+// variants returns the stocks of a product ordered by id, the lock must be held
+func (m *memory) variants(tenantID, productID string) []*objects.Stock {
+	var list []*objects.Stock
+	for _, evt := range m.stocks[tenantID] {
+		if evt.ProductID == productID {
+			list = append(list, evt)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
@@ -0,0 +1,195 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/tenant"
+)
+
+func (m *memory) CreateSupplier(ctx context.Context, in *objects.Supplier) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	in.ID = GenerateUniqueID()
+	in.TenantID = tenant.FromContext(ctx)
+	in.CreatedOn = time.Now()
+	in.UpdatedOn = in.CreatedOn
+	cp := *in
+	m.suppliers[cp.ID] = &cp
+	return nil
+}
+
+func (m *memory) GetSupplier(ctx context.Context, in *objects.GetRequest) (*objects.Supplier, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.suppliers[in.ID]
+	if !ok || s.TenantID != tenant.FromContext(ctx) {
+		return nil, errors.ErrSupplierNotFound
+	}
+	cp := *s
+	return &cp, nil
+}
+
+func (m *memory) ListSuppliers(ctx context.Context, in *objects.ListRequest) ([]*objects.Supplier, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	list := make([]*objects.Supplier, 0, in.Limit)
+	for _, s := range m.suppliers {
+		if s.TenantID != tenantID || (in.After != "" && s.ID <= in.After) {
+			continue
+		}
+		if in.Name != "" && !strings.Contains(strings.ToLower(s.Name), strings.ToLower(in.Name)) {
+			continue
+		}
+		cp := *s
+		list = append(list, &cp)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	if len(list) > in.Limit {
+		list = list[:in.Limit]
+	}
+	return list, nil
+}
+
+func (m *memory) CreatePurchaseOrder(ctx context.Context, in *objects.CreatePurchaseOrderRequest) (*objects.PurchaseOrder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenantID := tenant.FromContext(ctx)
+	if s, ok := m.suppliers[in.SupplierID]; !ok || s.TenantID != tenantID {
+		return nil, errors.ErrSupplierNotFound
+	}
+	if err := m.checkStocks(tenantID, lineStockIDs(in)); err != nil {
+		return nil, err
+	}
+	if err := purchaseUnits(in, m.stocks[tenantID]); err != nil {
+		return nil, err
+	}
+	order, err := newPurchaseOrder(tenantID, in, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	m.orders[order.ID] = clonePurchaseOrder(order)
+	return order, nil
+}
+
+func (m *memory) GetPurchaseOrder(ctx context.Context, in *objects.GetRequest) (*objects.PurchaseOrder, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	order, ok := m.orders[in.ID]
+	if !ok || order.TenantID != tenant.FromContext(ctx) {
+		return nil, errors.ErrPurchaseOrderNotFound
+	}
+	return clonePurchaseOrder(order), nil
+}
+
+func (m *memory) ListPurchaseOrders(ctx context.Context, in *objects.ListPurchaseOrdersRequest) ([]*objects.PurchaseOrder, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	list := make([]*objects.PurchaseOrder, 0, in.Limit)
+	for _, order := range m.orders {
+		if order.TenantID != tenantID || (in.After != "" && order.ID <= in.After) {
+			continue
+		}
+		if (in.Status != "" && order.Status != in.Status) || (in.SupplierID != "" && order.SupplierID != in.SupplierID) {
+			continue
+		}
+		list = append(list, clonePurchaseOrder(order))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	if len(list) > in.Limit {
+		list = list[:in.Limit]
+	}
+	return list, nil
+}
+
+func (m *memory) SubmitPurchaseOrder(ctx context.Context, in *objects.SubmitPurchaseOrderRequest) (*objects.PurchaseOrder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	order, ok := m.orders[in.ID]
+	if !ok || order.TenantID != tenant.FromContext(ctx) {
+		return nil, errors.ErrPurchaseOrderNotFound
+	}
+	// work on a copy, the order is left untouched on failure
+	cp := clonePurchaseOrder(order)
+	if err := submit(cp, time.Now()); err != nil {
+		return nil, err
+	}
+	m.orders[cp.ID] = cp
+	return clonePurchaseOrder(cp), nil
+}
+
+func (m *memory) ReceivePurchaseOrder(ctx context.Context, in *objects.ReceivePurchaseOrderRequest) (*objects.PurchaseOrder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenantID := tenant.FromContext(ctx)
+	order, ok := m.orders[in.ID]
+	if !ok || order.TenantID != tenantID {
+		return nil, errors.ErrPurchaseOrderNotFound
+	}
+	cp := clonePurchaseOrder(order)
+	now := time.Now()
+	if err := receiveUnits(cp, in, m.stocks[tenantID]); err != nil {
+		return nil, err
+	}
+	_, received, err := receive(cp, in, now)
+	if err != nil {
+		return nil, err
+	}
+	// check every stock and serial first, nothing is applied on failure
+	ids := sortedKeys(received)
+	if err := m.checkStocks(tenantID, ids); err != nil {
+		return nil, err
+	}
+	serials, movements, err := receivedSerials(cp, in, m.stocks[tenantID], now)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range serials {
+		if _, ok := m.serials[serialKey(tenantID, s.SerialNumber)]; ok {
+			return nil, errors.ErrSerialExists.WithField("serials", "serial_exists", "serial "+s.SerialNumber+" was already received")
+		}
+	}
+	m.saveSerials(serials)
+	m.appendMovements(movements...)
+	for _, id := range ids {
+		if err := m.receiveStock(tenantID, id, received[id]); err != nil {
+			return nil, err
+		}
+	}
+	m.orders[cp.ID] = cp
+	return clonePurchaseOrder(cp), nil
+}
+
+// checkStocks fails with errors.ErrStockNotFound unless every stock of ids exists,
+// the lock must be held
+func (m *memory) checkStocks(tenantID string, ids []string) error {
+	found := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := m.stocks[tenantID][id]; ok {
+			found = append(found, id)
+		}
+	}
+	return missingStock(ids, found)
+}
+
+func clonePurchaseOrder(order *objects.PurchaseOrder) *objects.PurchaseOrder {
+	cp := *order
+	cp.Lines = make([]*objects.PurchaseOrderLine, 0, len(order.Lines))
+	for _, l := range order.Lines {
+		lc := *l
+		cp.Lines = append(cp.Lines, &lc)
+	}
+	return &cp
+}
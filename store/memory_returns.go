@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/tenant"
+)
+
+func (m *memory) CreateReturn(ctx context.Context, in *objects.CreateReturnRequest) (*objects.Return, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenantID := tenant.FromContext(ctx)
+	if in.SalesOrderID != "" {
+		if order, ok := m.salesOrders[in.SalesOrderID]; !ok || order.TenantID != tenantID {
+			return nil, errors.ErrSalesOrderNotFound
+		}
+	}
+	if err := m.checkStocks(tenantID, returnStockIDs(in)); err != nil {
+		return nil, err
+	}
+	if err := returnUnits(in, m.stocks[tenantID]); err != nil {
+		return nil, err
+	}
+	ret, err := newReturn(tenantID, in, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	m.returns[ret.ID] = cloneReturn(ret)
+	return ret, nil
+}
+
+func (m *memory) GetReturn(ctx context.Context, in *objects.GetRequest) (*objects.Return, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ret, ok := m.returns[in.ID]
+	if !ok || ret.TenantID != tenant.FromContext(ctx) {
+		return nil, errors.ErrReturnNotFound
+	}
+	return cloneReturn(ret), nil
+}
+
+func (m *memory) ListReturns(ctx context.Context, in *objects.ListReturnsRequest) ([]*objects.Return, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	list := make([]*objects.Return, 0, in.Limit)
+	for _, ret := range m.returns {
+		if ret.TenantID != tenantID || (in.After != "" && ret.ID <= in.After) {
+			continue
+		}
+		if (in.Status != "" && ret.Status != in.Status) || (in.SalesOrderID != "" && ret.SalesOrderID != in.SalesOrderID) {
+			continue
+		}
+		list = append(list, cloneReturn(ret))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	if len(list) > in.Limit {
+		list = list[:in.Limit]
+	}
+	return list, nil
+}
+
+func (m *memory) InspectReturn(ctx context.Context, in *objects.InspectReturnRequest) (*objects.Return, []*objects.StockMovement, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenantID := tenant.FromContext(ctx)
+	ret, ok := m.returns[in.ID]
+	if !ok || ret.TenantID != tenantID {
+		return nil, nil, errors.ErrReturnNotFound
+	}
+	cp := cloneReturn(ret)
+	stocks := m.stocks[tenantID]
+	now := time.Now()
+	_, movements, restock, err := inspect(cp, in, stocks, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	serials, serialMovements, err := restockedSerials(cp, in, stocks, m.findSerials(tenantID, inspectedSerials(in)), now)
+	if err != nil {
+		return nil, nil, err
+	}
+	movements = append(movements, serialMovements...)
+	m.saveSerials(serials)
+	// restocked units are received, they serve backorders first
+	for _, id := range sortedKeys(restock) {
+		if err := m.receiveStock(tenantID, id, restock[id]); err != nil {
+			return nil, nil, err
+		}
+	}
+	m.appendMovements(movements...)
+	res := make([]*objects.StockMovement, 0, len(movements))
+	for _, mv := range movements {
+		c := *mv
+		res = append(res, &c)
+	}
+	m.returns[cp.ID] = cp
+	return cloneReturn(cp), res, nil
+}
+
+func (m *memory) ListMovements(ctx context.Context, in *objects.ListMovementsRequest) ([]*objects.StockMovement, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxEventsLimit {
+		in.Limit = objects.MaxEventsLimit
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	list := make([]*objects.StockMovement, 0, in.Limit)
+	for _, mv := range m.movements {
+		if len(list) == in.Limit {
+			break
+		}
+		if mv.TenantID != tenantID || mv.Seq <= in.After {
+			continue
+		}
+		if (in.StockID != "" && mv.StockID != in.StockID) || (in.Reference != "" && mv.Reference != in.Reference) ||
+			(in.Type != "" && mv.Type != in.Type) {
+			continue
+		}
+		cp := *mv
+		list = append(list, &cp)
+	}
+	return list, nil
+}
+
+func cloneReturn(ret *objects.Return) *objects.Return {
+	cp := *ret
+	cp.Lines = make([]*objects.ReturnLine, 0, len(ret.Lines))
+	for _, l := range ret.Lines {
+		lc := *l
+		cp.Lines = append(cp.Lines, &lc)
+	}
+	return &cp
+}
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/tenant"
+)
+
+func (m *memory) CreateSalesOrder(ctx context.Context, in *objects.CreateSalesOrderRequest) (*objects.SalesOrder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.createSalesOrder(tenant.FromContext(ctx), in)
+}
+
+// createSalesOrder allocates and creates the order of in, the lock must be held
+func (m *memory) createSalesOrder(tenantID string, in *objects.CreateSalesOrderRequest) (*objects.SalesOrder, error) {
+	stocks := m.stocks[tenantID]
+	now := time.Now()
+	if err := m.expireLots(tenantID, salesStockIDs(in), now); err != nil {
+		return nil, err
+	}
+	if err := salesUnits(in, stocks); err != nil {
+		return nil, err
+	}
+	serials := m.findSerials(tenantID, requestedSerials(in))
+	if err := checkSerials(in, stocks, serials); err != nil {
+		return nil, err
+	}
+	allocs, err := allocate(in, stocks)
+	if err != nil {
+		return nil, err
+	}
+	order := newSalesOrder(tenantID, in, allocs, stocks, now)
+	pickLots(order, m.stockLots(tenantID, allocKeys(allocs)), now)
+	m.appendMovements(sellSerials(order, in, serials, now)...)
+	m.saveSerials(sortedSerials(serials))
+	for _, id := range allocKeys(allocs) {
+		if err := m.adjustStock(tenantID, id, -allocs[id].take, allocs[id].backorder); err != nil {
+			return nil, err
+		}
+	}
+	m.salesOrders[order.ID] = cloneSalesOrder(order)
+	return order, nil
+}
+
+func (m *memory) GetSalesOrder(ctx context.Context, in *objects.GetRequest) (*objects.SalesOrder, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	order, ok := m.salesOrders[in.ID]
+	if !ok || order.TenantID != tenant.FromContext(ctx) {
+		return nil, errors.ErrSalesOrderNotFound
+	}
+	return cloneSalesOrder(order), nil
+}
+
+func (m *memory) ListSalesOrders(ctx context.Context, in *objects.ListSalesOrdersRequest) ([]*objects.SalesOrder, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	list := make([]*objects.SalesOrder, 0, in.Limit)
+	for _, order := range m.salesOrders {
+		if order.TenantID != tenantID || (in.After != "" && order.ID <= in.After) {
+			continue
+		}
+		if (in.Status != "" && order.Status != in.Status) || (in.Reference != "" && order.Reference != in.Reference) {
+			continue
+		}
+		list = append(list, cloneSalesOrder(order))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	if len(list) > in.Limit {
+		list = list[:in.Limit]
+	}
+	return list, nil
+}
+
+func (m *memory) CancelSalesOrder(ctx context.Context, in *objects.CancelSalesOrderRequest) (*objects.SalesOrder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenantID := tenant.FromContext(ctx)
+	order, ok := m.salesOrders[in.ID]
+	if !ok || order.TenantID != tenantID {
+		return nil, errors.ErrSalesOrderNotFound
+	}
+	cp := cloneSalesOrder(order)
+	now := time.Now()
+	released, err := cancel(cp, now)
+	if err != nil {
+		return nil, err
+	}
+	withheld := unpick(cp, m.lots, now)
+	var sold []*objects.Serial
+	for _, s := range m.serials {
+		if s.TenantID == tenantID && s.OrderID == cp.ID {
+			c := *s
+			sold = append(sold, &c)
+		}
+	}
+	sort.Slice(sold, func(i, j int) bool { return sold[i].SerialNumber < sold[j].SerialNumber })
+	m.appendMovements(releaseSerials(sold, now)...)
+	m.saveSerials(sold)
+	// stored first, the cancelled order waits for no receipt
+	m.salesOrders[cp.ID] = cp
+	// released units are received, they serve the backorders of other orders first
+	for _, id := range allocKeys(released) {
+		if released[id].backorder > 0 {
+			if err := m.adjustStock(tenantID, id, 0, -released[id].backorder); err != nil {
+				return nil, err
+			}
+		}
+		// a line without allocated units releases nothing
+		if qty := released[id].take - withheld[id]; qty > 0 {
+			if err := m.receiveStock(tenantID, id, qty); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return cloneSalesOrder(cp), nil
+}
+
+func cloneSalesOrder(order *objects.SalesOrder) *objects.SalesOrder {
+	cp := *order
+	cp.Lines = make([]*objects.SalesOrderLine, 0, len(order.Lines))
+	for _, l := range order.Lines {
+		lc := *l
+		cp.Lines = append(cp.Lines, &lc)
+	}
+	cp.Picks = make([]*objects.SalesOrderPick, 0, len(order.Picks))
+	for _, p := range order.Picks {
+		pc := *p
+		cp.Picks = append(cp.Picks, &pc)
+	}
+	return &cp
+}
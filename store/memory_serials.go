@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/tenant"
+)
+
+func (m *memory) GetSerial(ctx context.Context, in *objects.GetSerialRequest) (*objects.Serial, []*objects.StockMovement, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	serial, ok := m.serials[serialKey(tenantID, in.SerialNumber)]
+	if !ok {
+		return nil, nil, errors.ErrSerialNotFound
+	}
+	movements := []*objects.StockMovement{}
+	for _, mv := range m.movements {
+		if mv.TenantID == tenantID && mv.SerialNumber == in.SerialNumber {
+			cp := *mv
+			movements = append(movements, &cp)
+		}
+	}
+	cp := *serial
+	return &cp, movements, nil
+}
+
+func (m *memory) ListSerials(ctx context.Context, in *objects.ListSerialsRequest) ([]*objects.Serial, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	list := make([]*objects.Serial, 0, in.Limit)
+	for _, s := range m.serials {
+		if s.TenantID != tenantID || s.StockID != in.StockID || (in.After != "" && s.SerialNumber <= in.After) {
+			continue
+		}
+		if in.Status != "" && s.Status != in.Status {
+			continue
+		}
+		cp := *s
+		list = append(list, &cp)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].SerialNumber < list[j].SerialNumber })
+	if len(list) > in.Limit {
+		list = list[:in.Limit]
+	}
+	return list, nil
+}
+
+// findSerials returns copies of the serials of tenantID among numbers, by number,
+// the lock must be held
+func (m *memory) findSerials(tenantID string, numbers []string) map[string]*objects.Serial {
+	found := make(map[string]*objects.Serial, len(numbers))
+	for _, sn := range numbers {
+		if s, ok := m.serials[serialKey(tenantID, sn)]; ok {
+			cp := *s
+			found[sn] = &cp
+		}
+	}
+	return found
+}
+
+// saveSerials stores serials, the lock must be held
+func (m *memory) saveSerials(serials []*objects.Serial) {
+	for _, s := range serials {
+		cp := *s
+		m.serials[serialKey(s.TenantID, s.SerialNumber)] = &cp
+	}
+}
+
+// serialKey key of a serial number of a tenant in memory.serials
+func serialKey(tenantID, serialNumber string) string {
+	return tenantID + "/" + serialNumber
+}
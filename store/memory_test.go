@@ -0,0 +1,872 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/tenant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryTenantIsolation(t *testing.T) {
+	st := NewMemoryStockStore()
+	acme := tenant.NewContext(context.Background(), "acme")
+	globex := tenant.NewContext(context.Background(), "globex")
+
+	evt := &objects.Stock{Name: "Meat Ball", Price: 100, Availability: 10}
+	if err := st.Create(acme, &objects.CreateRequest{Stock: evt}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "acme", evt.TenantID)
+
+	got, err := st.Get(acme, &objects.GetRequest{ID: evt.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, evt.Name, got.Name)
+
+	_, err = st.Get(globex, &objects.GetRequest{ID: evt.ID})
+	assert.Equal(t, errors.ErrStockNotFound, err)
+	_, err = st.Get(context.Background(), &objects.GetRequest{ID: evt.ID})
+	assert.Equal(t, errors.ErrStockNotFound, err)
+
+	list, err := st.List(globex, &objects.ListRequest{})
+	assert.Nil(t, err)
+	assert.Empty(t, list)
+
+	err = st.UpdateDetails(globex, &objects.UpdateDetailsRequest{ID: evt.ID, Name: "Stolen", Price: 1})
+	assert.Equal(t, errors.ErrStockNotFound, err)
+	got, _ = st.Get(acme, &objects.GetRequest{ID: evt.ID})
+	assert.Equal(t, "Meat Ball", got.Name)
+}
+
+func TestMemoryList(t *testing.T) {
+	st := NewMemoryStockStore()
+	ctx := context.Background()
+	var ids []string
+	for _, name := range []string{"One", "Two", "Three"} {
+		evt := &objects.Stock{Name: name, Price: 1}
+		if err := st.Create(ctx, &objects.CreateRequest{Stock: evt}); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, evt.ID)
+	}
+	tests := []struct {
+		name string
+		in   *objects.ListRequest
+		len  int
+	}{
+		{"All", &objects.ListRequest{}, 3},
+		{"Limited", &objects.ListRequest{Limit: 2}, 2},
+		{"After", &objects.ListRequest{After: ids[0]}, 2},
+		{"Name", &objects.ListRequest{Name: "T"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := st.List(ctx, tt.in)
+			assert.Nil(t, err)
+			assert.Len(t, list, tt.len)
+		})
+	}
+}
+
+func TestMemoryUpdateDetails(t *testing.T) {
+	st := NewMemoryStockStore()
+	ctx := context.Background()
+	restock := time.Now().AddDate(0, 1, 0)
+	evt := &objects.Stock{
+		Name: "One", Price: 1, Availability: 2, IsActive: true, ReorderPoint: 5, SafetyStock: 1,
+		Backorderable: true, BackorderLimit: 3, RestockOn: &restock,
+	}
+	assert.Nil(t, st.Create(ctx, &objects.CreateRequest{Stock: evt}))
+
+	// a full replace, the omitted fields are cleared
+	assert.Nil(t, st.UpdateDetails(ctx, &objects.UpdateDetailsRequest{ID: evt.ID, Name: "Two", Price: 2, Availability: 2, IsActive: true}))
+	got, err := st.Get(ctx, &objects.GetRequest{ID: evt.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, "Two", got.Name)
+	assert.Equal(t, 0, got.ReorderPoint)
+	assert.Equal(t, 0, got.SafetyStock)
+	assert.False(t, got.Backorderable)
+	assert.Equal(t, 0, got.BackorderLimit)
+	assert.Nil(t, got.RestockOn)
+}
+
+func TestMemoryBatchUpdate(t *testing.T) {
+	st := NewMemoryStockStore()
+	ctx := context.Background()
+	evt := &objects.Stock{Name: "One", Price: 1, IsActive: true}
+	assert.Nil(t, st.Create(ctx, &objects.CreateRequest{Stock: evt}))
+
+	// the items of an atomic batch apply in turn, the second one fails on the first
+	results, err := st.BatchUpdate(ctx, &objects.BatchUpdateRequest{Atomic: true, Items: []*objects.UpdateDetailsRequest{
+		{ID: evt.ID, Name: "One", Price: 1, Availability: 3, IsActive: true},
+		{ID: evt.ID, Name: "One", Price: 1, IsActive: true, Serialized: true},
+	}})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusConflict, results[0].Status)
+	assert.Equal(t, http.StatusBadRequest, results[1].Status)
+	got, _ := st.Get(ctx, &objects.GetRequest{ID: evt.ID})
+	assert.Equal(t, 0, got.Availability)
+	assert.False(t, got.Serialized)
+}
+
+func TestMemoryPurchaseOrder(t *testing.T) {
+	stores := NewMemoryStores()
+	ctx := context.Background()
+	evt := &objects.Stock{Name: "Meat Ball", Price: 1, Availability: 2}
+	assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: evt}))
+	sup := &objects.Supplier{Name: "Acme"}
+	assert.Nil(t, stores.Purchasing.CreateSupplier(ctx, sup))
+
+	_, err := stores.Purchasing.CreatePurchaseOrder(ctx, &objects.CreatePurchaseOrderRequest{
+		SupplierID: sup.ID,
+		Lines:      []*objects.PurchaseOrderLineRequest{{StockID: "fake", Quantity: 1}},
+	})
+	assert.True(t, errors.ErrStockNotFound.Is(err))
+	_, err = stores.Purchasing.CreatePurchaseOrder(ctx, &objects.CreatePurchaseOrderRequest{
+		SupplierID: sup.ID,
+		Lines:      []*objects.PurchaseOrderLineRequest{{StockID: evt.ID, Quantity: 0}},
+	})
+	assert.True(t, errors.ErrValidation.Is(err))
+
+	order, err := stores.Purchasing.CreatePurchaseOrder(ctx, &objects.CreatePurchaseOrderRequest{
+		SupplierID: sup.ID,
+		Lines:      []*objects.PurchaseOrderLineRequest{{StockID: evt.ID, Quantity: 10}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, objects.PurchaseOrderDraft, order.Status)
+
+	receive := func(qty int) error {
+		_, err := stores.Purchasing.ReceivePurchaseOrder(ctx, &objects.ReceivePurchaseOrderRequest{
+			ID: order.ID, Lines: []*objects.ReceiveLine{{Line: 1, Quantity: qty}},
+		})
+		return err
+	}
+	availability := func() int {
+		got, _ := stores.Stocks.Get(ctx, &objects.GetRequest{ID: evt.ID})
+		return got.Availability
+	}
+
+	assert.True(t, errors.ErrInvalidTransition.Is(receive(1)))
+	_, err = stores.Purchasing.SubmitPurchaseOrder(ctx, &objects.SubmitPurchaseOrderRequest{ID: order.ID})
+	assert.Nil(t, err)
+	_, err = stores.Purchasing.SubmitPurchaseOrder(ctx, &objects.SubmitPurchaseOrderRequest{ID: order.ID})
+	assert.True(t, errors.ErrInvalidTransition.Is(err))
+
+	assert.Nil(t, receive(4))
+	assert.Equal(t, 6, availability())
+	got, _ := stores.Purchasing.GetPurchaseOrder(ctx, &objects.GetRequest{ID: order.ID})
+	assert.Equal(t, objects.PurchaseOrderPartiallyReceived, got.Status)
+
+	assert.True(t, errors.ErrOverReceipt.Is(receive(7)))
+	assert.Equal(t, 6, availability())
+	assert.True(t, errors.ErrValidation.Is(receive(-5)))
+	assert.Equal(t, 6, availability())
+
+	assert.Nil(t, receive(6))
+	assert.Equal(t, 12, availability())
+	got, _ = stores.Purchasing.GetPurchaseOrder(ctx, &objects.GetRequest{ID: order.ID})
+	assert.Equal(t, objects.PurchaseOrderReceived, got.Status)
+	assert.Equal(t, 10, got.Lines[0].Received)
+	assert.True(t, errors.ErrInvalidTransition.Is(receive(1)))
+}
+
+func TestMemorySalesOrder(t *testing.T) {
+	stores := NewMemoryStores()
+	ctx := context.Background()
+	var ids []string
+	for _, s := range []*objects.Stock{
+		{Name: "One", Price: 1, Availability: 5, IsActive: true},
+		{Name: "Two", Price: 1, Availability: 1, IsActive: true},
+		{Name: "Off", Price: 1, Availability: 9},
+	} {
+		assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: s}))
+		ids = append(ids, s.ID)
+	}
+	availability := func() []int {
+		list, _ := stores.Stocks.BatchGet(ctx, &objects.BatchGetRequest{IDs: ids})
+		res := make([]int, 0, len(list))
+		for _, s := range list {
+			res = append(res, s.Availability)
+		}
+		return res
+	}
+
+	// lines of a stock share its availability, every failing line is reported
+	_, err := stores.Sales.CreateSalesOrder(ctx, &objects.CreateSalesOrderRequest{Lines: []*objects.SalesOrderLineRequest{
+		{StockID: ids[0], Quantity: 3},
+		{StockID: ids[0], Quantity: 3},
+		{StockID: ids[1], Quantity: 1},
+		{StockID: ids[2], Quantity: 1},
+		{StockID: "fake", Quantity: 1},
+	}})
+	if assert.True(t, errors.ErrAllocationFailed.Is(err)) {
+		var codes []string
+		for _, f := range errors.From(err).Errors {
+			codes = append(codes, f.Field+":"+f.Code)
+		}
+		assert.Equal(t, []string{
+			"lines[0].quantity:insufficient_stock",
+			"lines[1].quantity:insufficient_stock",
+			"lines[3].stock_id:stock_inactive",
+			"lines[4].stock_id:stock_not_found",
+		}, codes)
+	}
+	assert.Equal(t, []int{5, 1, 9}, availability())
+
+	// a negative line would add units
+	_, err = stores.Sales.CreateSalesOrder(ctx, &objects.CreateSalesOrderRequest{Lines: []*objects.SalesOrderLineRequest{
+		{StockID: ids[0], Quantity: -100},
+	}})
+	assert.True(t, errors.ErrValidation.Is(err))
+	assert.Equal(t, []int{5, 1, 9}, availability())
+
+	order, err := stores.Sales.CreateSalesOrder(ctx, &objects.CreateSalesOrderRequest{Lines: []*objects.SalesOrderLineRequest{
+		{StockID: ids[0], Quantity: 3},
+		{StockID: ids[1], Quantity: 1},
+		{StockID: ids[0], Quantity: 2},
+	}})
+	assert.Nil(t, err)
+	assert.Equal(t, objects.SalesOrderAllocated, order.Status)
+	assert.Equal(t, []int{0, 0, 9}, availability())
+
+	_, err = stores.Sales.CancelSalesOrder(ctx, &objects.CancelSalesOrderRequest{ID: order.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{5, 1, 9}, availability())
+	_, err = stores.Sales.CancelSalesOrder(ctx, &objects.CancelSalesOrderRequest{ID: order.ID})
+	assert.True(t, errors.ErrInvalidTransition.Is(err))
+	assert.Equal(t, []int{5, 1, 9}, availability())
+}
+
+func TestMemoryBackorders(t *testing.T) {
+	stores := NewMemoryStores()
+	ctx := context.Background()
+	restock := time.Now().Add(72 * time.Hour)
+	evt := &objects.Stock{Name: "One", Price: 1, Availability: 2, IsActive: true, Backorderable: true, BackorderLimit: 5, RestockOn: &restock}
+	assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: evt}))
+	order := func(qty int) (*objects.SalesOrder, error) {
+		return stores.Sales.CreateSalesOrder(ctx, &objects.CreateSalesOrderRequest{
+			Lines: []*objects.SalesOrderLineRequest{{StockID: evt.ID, Quantity: qty}},
+		})
+	}
+	stock := func() *objects.Stock {
+		got, _ := stores.Stocks.Get(ctx, &objects.GetRequest{ID: evt.ID})
+		return got
+	}
+
+	first, err := order(4)
+	assert.Nil(t, err)
+	assert.Equal(t, objects.SalesOrderBackordered, first.Status)
+	assert.Equal(t, 2, first.Lines[0].Allocated)
+	assert.Equal(t, 2, first.Lines[0].Backordered)
+	assert.True(t, restock.Equal(*first.Lines[0].ExpectedOn))
+	second, err := order(3)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, second.Lines[0].Allocated)
+	assert.Equal(t, -5, stock().AvailableToPromise())
+
+	// over the backorder limit
+	_, err = order(1)
+	assert.True(t, errors.ErrAllocationFailed.Is(err))
+
+	sup := &objects.Supplier{Name: "Acme"}
+	assert.Nil(t, stores.Purchasing.CreateSupplier(ctx, sup))
+	po, err := stores.Purchasing.CreatePurchaseOrder(ctx, &objects.CreatePurchaseOrderRequest{
+		SupplierID: sup.ID,
+		Lines:      []*objects.PurchaseOrderLineRequest{{StockID: evt.ID, Quantity: 10}},
+	})
+	assert.Nil(t, err)
+	_, err = stores.Purchasing.SubmitPurchaseOrder(ctx, &objects.SubmitPurchaseOrderRequest{ID: po.ID})
+	assert.Nil(t, err)
+	receive := func(qty int) {
+		_, err := stores.Purchasing.ReceivePurchaseOrder(ctx, &objects.ReceivePurchaseOrderRequest{
+			ID: po.ID, Lines: []*objects.ReceiveLine{{Line: 1, Quantity: qty}},
+		})
+		assert.Nil(t, err)
+	}
+
+	// oldest order first
+	receive(3)
+	got, _ := stores.Sales.GetSalesOrder(ctx, &objects.GetRequest{ID: first.ID})
+	assert.Equal(t, objects.SalesOrderAllocated, got.Status)
+	assert.Nil(t, got.Lines[0].ExpectedOn)
+	got, _ = stores.Sales.GetSalesOrder(ctx, &objects.GetRequest{ID: second.ID})
+	assert.Equal(t, objects.SalesOrderBackordered, got.Status)
+	assert.Equal(t, 1, got.Lines[0].Allocated)
+	assert.Equal(t, 0, stock().Availability)
+	assert.Equal(t, 2, stock().Backordered)
+
+	// cancelling gives back the allocated units and drops the backordered ones
+	_, err = stores.Sales.CancelSalesOrder(ctx, &objects.CancelSalesOrderRequest{ID: second.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, stock().Availability)
+	assert.Equal(t, 0, stock().Backordered)
+
+	receive(4)
+	assert.Equal(t, 5, stock().Availability)
+
+	// cancelled units serve the orders waiting for them
+	third, err := order(7)
+	assert.Nil(t, err)
+	fourth, err := order(2)
+	assert.Nil(t, err)
+	assert.Equal(t, objects.SalesOrderBackordered, fourth.Status)
+	_, err = stores.Sales.CancelSalesOrder(ctx, &objects.CancelSalesOrderRequest{ID: third.ID})
+	assert.Nil(t, err)
+	got, _ = stores.Sales.GetSalesOrder(ctx, &objects.GetRequest{ID: fourth.ID})
+	assert.Equal(t, objects.SalesOrderAllocated, got.Status)
+	assert.Equal(t, 2, got.Lines[0].Allocated)
+	assert.Equal(t, 3, stock().Availability)
+	assert.Equal(t, 0, stock().Backordered)
+	// a fully backordered order releases nothing, only its backorder is dropped
+	_, err = order(3)
+	assert.Nil(t, err)
+	waiting, err := order(2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, waiting.Lines[0].Allocated)
+	before, _ := stores.Stocks.ListEvents(ctx, &objects.ListEventsRequest{})
+	_, err = stores.Sales.CancelSalesOrder(ctx, &objects.CancelSalesOrderRequest{ID: waiting.ID})
+	assert.Nil(t, err)
+	after, _ := stores.Stocks.ListEvents(ctx, &objects.ListEventsRequest{})
+	assert.Len(t, after, len(before)+1)
+	assert.Equal(t, 0, stock().Backordered)
+}
+
+func TestMemoryReturn(t *testing.T) {
+	stores := NewMemoryStores()
+	ctx := context.Background()
+	evt := &objects.Stock{Name: "One", Price: 1, Availability: 1, IsActive: true}
+	assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: evt}))
+
+	_, err := stores.Returns.CreateReturn(ctx, &objects.CreateReturnRequest{
+		Lines: []*objects.ReturnLineRequest{{StockID: "unknown", Quantity: 1}},
+	})
+	assert.True(t, errors.ErrStockNotFound.Is(err))
+	_, err = stores.Returns.CreateReturn(ctx, &objects.CreateReturnRequest{
+		Lines: []*objects.ReturnLineRequest{{StockID: evt.ID, Quantity: -1}},
+	})
+	assert.True(t, errors.ErrValidation.Is(err))
+	ret, err := stores.Returns.CreateReturn(ctx, &objects.CreateReturnRequest{
+		Reason: "damaged box",
+		Lines:  []*objects.ReturnLineRequest{{StockID: evt.ID, Quantity: 5}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, objects.ReturnOpen, ret.Status)
+
+	inspect := func(lines ...*objects.InspectLine) (*objects.Return, []*objects.StockMovement, error) {
+		return stores.Returns.InspectReturn(ctx, &objects.InspectReturnRequest{ID: ret.ID, Lines: lines})
+	}
+	got, movements, err := inspect(
+		&objects.InspectLine{Line: 1, Outcome: objects.OutcomeRestock, Quantity: 2},
+		&objects.InspectLine{Line: 1, Outcome: objects.OutcomeScrap, Quantity: 1},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, objects.ReturnPartiallyInspected, got.Status)
+	assert.Equal(t, 2, got.Lines[0].Uninspected())
+	assert.Len(t, movements, 2)
+	assert.Equal(t, objects.MovementReturnRestock, movements[0].Type)
+	assert.Equal(t, 2, movements[0].AvailabilityDelta)
+	assert.Equal(t, 0, movements[1].AvailabilityDelta)
+	stock, _ := stores.Stocks.Get(ctx, &objects.GetRequest{ID: evt.ID})
+	assert.Equal(t, 3, stock.Availability)
+
+	// more than left to inspect, nothing is applied
+	_, _, err = inspect(
+		&objects.InspectLine{Line: 1, Outcome: objects.OutcomeRefurbish, Quantity: 1},
+		&objects.InspectLine{Line: 1, Outcome: objects.OutcomeRestock, Quantity: 2},
+	)
+	assert.True(t, errors.ErrOverInspection.Is(err))
+	_, _, err = inspect(&objects.InspectLine{Line: 1, Outcome: objects.OutcomeRestock, Quantity: -2})
+	assert.True(t, errors.ErrValidation.Is(err))
+	stock, _ = stores.Stocks.Get(ctx, &objects.GetRequest{ID: evt.ID})
+	assert.Equal(t, 3, stock.Availability)
+
+	got, _, err = inspect(&objects.InspectLine{Line: 1, Outcome: objects.OutcomeRefurbish, Quantity: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, objects.ReturnCompleted, got.Status)
+	_, _, err = inspect(&objects.InspectLine{Line: 1, Outcome: objects.OutcomeScrap, Quantity: 1})
+	assert.True(t, errors.ErrInvalidTransition.Is(err))
+
+	// the movements reconcile with the returned quantity
+	list, err := stores.Movements.ListMovements(ctx, &objects.ListMovementsRequest{Reference: ret.ID})
+	assert.Nil(t, err)
+	total := 0
+	for _, mv := range list {
+		total += mv.Quantity
+	}
+	assert.Equal(t, 5, total)
+	stock, _ = stores.Stocks.Get(ctx, &objects.GetRequest{ID: evt.ID})
+	assert.Equal(t, 3, stock.Availability)
+}
+
+func TestMemorySerials(t *testing.T) {
+	stores := NewMemoryStores()
+	ctx := context.Background()
+	assert.True(t, errors.ErrSerialsRequired.Is(stores.Stocks.Create(ctx, &objects.CreateRequest{
+		Stock: &objects.Stock{Name: "Phone", Price: 1, Availability: 2, IsActive: true, Serialized: true},
+	})))
+	evt := &objects.Stock{Name: "Phone", Price: 1, IsActive: true, Serialized: true}
+	assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: evt}))
+	err := stores.Stocks.UpdateDetails(ctx, &objects.UpdateDetailsRequest{ID: evt.ID, Name: "Phone", Price: 1, Availability: 3, IsActive: true, Serialized: true})
+	assert.True(t, errors.ErrSerialsRequired.Is(err))
+
+	sup := &objects.Supplier{Name: "Acme"}
+	assert.Nil(t, stores.Purchasing.CreateSupplier(ctx, sup))
+	po, _ := stores.Purchasing.CreatePurchaseOrder(ctx, &objects.CreatePurchaseOrderRequest{
+		SupplierID: sup.ID,
+		Lines:      []*objects.PurchaseOrderLineRequest{{StockID: evt.ID, Quantity: 5}},
+	})
+	_, err = stores.Purchasing.SubmitPurchaseOrder(ctx, &objects.SubmitPurchaseOrderRequest{ID: po.ID})
+	assert.Nil(t, err)
+	receive := func(qty int, serials ...string) error {
+		_, err := stores.Purchasing.ReceivePurchaseOrder(ctx, &objects.ReceivePurchaseOrderRequest{
+			ID: po.ID, Lines: []*objects.ReceiveLine{{Line: 1, Quantity: qty, Serials: serials}},
+		})
+		return err
+	}
+	assert.True(t, errors.ErrSerialsRequired.Is(receive(1)))
+	assert.Nil(t, receive(2, "SN-A", "SN-B"))
+	assert.True(t, errors.ErrSerialExists.Is(receive(1, "SN-A")))
+	stock := func() *objects.Stock {
+		got, _ := stores.Stocks.Get(ctx, &objects.GetRequest{ID: evt.ID})
+		return got
+	}
+	assert.Equal(t, 2, stock().Availability)
+
+	order := func(serials ...string) (*objects.SalesOrder, error) {
+		return stores.Sales.CreateSalesOrder(ctx, &objects.CreateSalesOrderRequest{
+			Lines: []*objects.SalesOrderLineRequest{{StockID: evt.ID, Quantity: 1, Serials: serials}},
+		})
+	}
+	_, err = order()
+	assert.True(t, errors.ErrAllocationFailed.Is(err))
+	sold, err := order("SN-A")
+	assert.Nil(t, err)
+	_, err = order("SN-A")
+	assert.True(t, errors.ErrAllocationFailed.Is(err))
+	serial, _, err := stores.Serials.GetSerial(ctx, &objects.GetSerialRequest{SerialNumber: "SN-A"})
+	assert.Nil(t, err)
+	assert.Equal(t, objects.SerialAllocated, serial.Status)
+	assert.Equal(t, sold.ID, serial.OrderID)
+	assert.Equal(t, 1, stock().Availability)
+
+	_, err = stores.Sales.CancelSalesOrder(ctx, &objects.CancelSalesOrderRequest{ID: sold.ID})
+	assert.Nil(t, err)
+	serial, movements, err := stores.Serials.GetSerial(ctx, &objects.GetSerialRequest{SerialNumber: "SN-A"})
+	assert.Nil(t, err)
+	assert.Equal(t, objects.SerialInStock, serial.Status)
+	assert.Equal(t, 2, stock().Availability)
+	types := []string{}
+	for _, mv := range movements {
+		types = append(types, mv.Type)
+	}
+	assert.Equal(t, []string{objects.MovementSerialReceipt, objects.MovementSerialAllocation, objects.MovementSerialRelease}, types)
+	_, _, err = stores.Serials.GetSerial(ctx, &objects.GetSerialRequest{SerialNumber: "SN-Z"})
+	assert.True(t, errors.ErrSerialNotFound.Is(err))
+
+	// returned units are restocked by serial number
+	sold, err = order("SN-B")
+	assert.Nil(t, err)
+	ret, err := stores.Returns.CreateReturn(ctx, &objects.CreateReturnRequest{
+		SalesOrderID: sold.ID,
+		Lines:        []*objects.ReturnLineRequest{{StockID: evt.ID, Quantity: 1}},
+	})
+	assert.Nil(t, err)
+	restock := func(serials ...string) ([]*objects.StockMovement, error) {
+		_, movements, err := stores.Returns.InspectReturn(ctx, &objects.InspectReturnRequest{
+			ID: ret.ID, Lines: []*objects.InspectLine{{Line: 1, Outcome: objects.OutcomeRestock, Quantity: 1, Serials: serials}},
+		})
+		return movements, err
+	}
+	_, err = restock()
+	assert.True(t, errors.ErrSerialsRequired.Is(err))
+	_, err = restock("SN-A")
+	assert.True(t, errors.ErrValidation.Is(err))
+	assert.Equal(t, 1, stock().Availability)
+	movements, err = restock("SN-B")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, stock().Availability)
+	if assert.Len(t, movements, 2) {
+		assert.Equal(t, 0, movements[0].AvailabilityDelta)
+		assert.Equal(t, objects.MovementSerialReturn, movements[1].Type)
+		assert.Equal(t, 1, movements[1].AvailabilityDelta)
+	}
+	serial, _, _ = stores.Serials.GetSerial(ctx, &objects.GetSerialRequest{SerialNumber: "SN-B"})
+	assert.Equal(t, objects.SerialInStock, serial.Status)
+	assert.Empty(t, serial.OrderID)
+}
+
+func TestMemoryProducts(t *testing.T) {
+	stores := NewMemoryStores()
+	ctx := context.Background()
+	_, err := stores.Products.CreateProduct(ctx, &objects.CreateProductRequest{
+		Name:       "Tee",
+		Attributes: []*objects.AttributeDefinition{{Name: "size", Type: objects.AttributeEnum}},
+	})
+	assert.True(t, errors.ErrValidation.Is(err))
+	product, err := stores.Products.CreateProduct(ctx, &objects.CreateProductRequest{
+		Name: "Tee",
+		Attributes: []*objects.AttributeDefinition{
+			{Name: "size", Type: objects.AttributeEnum, Values: []string{"S", "M", "L"}, Required: true},
+			{Name: "colour", Type: objects.AttributeString, Required: true},
+		},
+	})
+	assert.Nil(t, err)
+	variant := func(size, colour string, availability int) error {
+		_, err := stores.Products.CreateVariant(ctx, &objects.CreateVariantRequest{
+			ProductID: product.ID, Name: "Tee " + colour + " " + size, Price: 10, Availability: availability, IsActive: true,
+			Attributes: map[string]interface{}{"size": size, "colour": colour},
+		})
+		return err
+	}
+	assert.Nil(t, variant("L", "Red", 3))
+	assert.Nil(t, variant("M", "Red", 2))
+	assert.Nil(t, variant("L", "Blue", 4))
+	assert.True(t, errors.ErrVariantExists.Is(variant("L", "Red", 1)))
+	assert.True(t, errors.ErrValidation.Is(variant("XL", "Red", 1)))
+
+	list, err := stores.Products.ListVariants(ctx, &objects.ListVariantsRequest{ProductID: product.ID})
+	assert.Nil(t, err)
+	assert.Len(t, list, 3)
+	assert.Equal(t, product.ID, list[0].ProductID)
+
+	got, err := stores.Products.ProductAvailability(ctx, &objects.GetRequest{ID: product.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, got.Variants)
+	assert.Equal(t, 9, got.Availability)
+	assert.Equal(t, 5, got.ByAttribute["colour"]["Red"])
+	assert.Equal(t, 7, got.ByAttribute["size"]["L"])
+
+	// variants are only created through their product
+	err = stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: &objects.Stock{Name: "Tee", Price: 1, ProductID: product.ID}})
+	assert.True(t, errors.ErrValidation.Is(err))
+	_, err = stores.Products.ProductAvailability(ctx, &objects.GetRequest{ID: "unknown"})
+	assert.True(t, errors.ErrProductNotFound.Is(err))
+}
+
+func TestMemoryBundles(t *testing.T) {
+	stores := NewMemoryStores()
+	ctx := context.Background()
+	box := &objects.Stock{Name: "Box", Price: 1, Availability: 10, IsActive: true}
+	cup := &objects.Stock{Name: "Cup", Price: 2, Availability: 7, IsActive: true}
+	for _, s := range []*objects.Stock{box, cup} {
+		assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: s}))
+	}
+	_, err := stores.Bundles.CreateBundle(ctx, &objects.CreateBundleRequest{
+		Name: "Gift", Price: 9, Components: []*objects.BundleComponentRequest{{StockID: "unknown", Quantity: 1}},
+	})
+	assert.True(t, errors.ErrStockNotFound.Is(err))
+	_, err = stores.Bundles.CreateBundle(ctx, &objects.CreateBundleRequest{
+		Name: "Gift", Price: 9, Components: []*objects.BundleComponentRequest{{StockID: box.ID, Quantity: 1}, {StockID: box.ID, Quantity: 2}},
+	})
+	assert.True(t, errors.ErrValidation.Is(err))
+	for _, qty := range []int{0, -1} {
+		_, err = stores.Bundles.CreateBundle(ctx, &objects.CreateBundleRequest{
+			Name: "Gift", Price: 9, Components: []*objects.BundleComponentRequest{{StockID: box.ID, Quantity: qty}},
+		})
+		assert.True(t, errors.ErrValidation.Is(err))
+	}
+	bundle, err := stores.Bundles.CreateBundle(ctx, &objects.CreateBundleRequest{
+		Name: "Gift", Price: 9, Components: []*objects.BundleComponentRequest{{StockID: box.ID, Quantity: 1}, {StockID: cup.ID, Quantity: 2}},
+	})
+	assert.Nil(t, err)
+	// limited by the cups
+	assert.Equal(t, 3, bundle.Availability)
+
+	order, err := stores.Bundles.SellBundle(ctx, &objects.SellBundleRequest{ID: bundle.ID, Quantity: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, bundle.ID, order.BundleID)
+	assert.Len(t, order.Lines, 2)
+	got, err := stores.Bundles.GetBundle(ctx, &objects.GetRequest{ID: bundle.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, got.Availability)
+
+	// no component is allocated when one is short
+	_, err = stores.Bundles.SellBundle(ctx, &objects.SellBundleRequest{ID: bundle.ID, Quantity: 2})
+	assert.True(t, errors.ErrAllocationFailed.Is(err))
+	s, err := stores.Stocks.Get(ctx, &objects.GetRequest{ID: box.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, 8, s.Availability)
+
+	list, err := stores.Bundles.ListBundles(ctx, &objects.ListRequest{Name: "gift"})
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+	_, err = stores.Bundles.SellBundle(ctx, &objects.SellBundleRequest{ID: "unknown", Quantity: 1})
+	assert.True(t, errors.ErrBundleNotFound.Is(err))
+	_, err = stores.Bundles.SellBundle(ctx, &objects.SellBundleRequest{ID: bundle.ID, Quantity: objects.MaxQuantity + 1})
+	assert.True(t, errors.ErrValidation.Is(err))
+
+	// expired units build no bundle, backorderable components are not backordered
+	tea := &objects.Stock{Name: "Tea", Price: 1, IsActive: true, Backorderable: true, BackorderLimit: 10}
+	assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: tea}))
+	_, err = stores.Lots.CreateLot(ctx, &objects.CreateLotRequest{StockID: tea.ID, LotNumber: "L1", ExpiresOn: time.Now().AddDate(0, 0, -1), Quantity: 3})
+	assert.Nil(t, err)
+	tin, err := stores.Bundles.CreateBundle(ctx, &objects.CreateBundleRequest{
+		Name: "Tin", Price: 9, Components: []*objects.BundleComponentRequest{{StockID: tea.ID, Quantity: 1}},
+	})
+	assert.Nil(t, err)
+	_, err = stores.Bundles.SellBundle(ctx, &objects.SellBundleRequest{ID: tin.ID, Quantity: 2})
+	assert.True(t, errors.ErrAllocationFailed.Is(err))
+	s, _ = stores.Stocks.Get(ctx, &objects.GetRequest{ID: tea.ID})
+	assert.Equal(t, 0, s.Availability)
+	assert.Equal(t, 0, s.Backordered)
+}
+
+func TestMemoryUnits(t *testing.T) {
+	stores := NewMemoryStores()
+	ctx := context.Background()
+	err := stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: &objects.Stock{Name: "Can", Price: 1, Units: map[string]int{"each": 2}}})
+	assert.True(t, errors.ErrValidation.Is(err))
+	evt := &objects.Stock{Name: "Can", Price: 1, IsActive: true, Units: map[string]int{"case": 24}}
+	assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: evt}))
+	assert.Equal(t, objects.DefaultBaseUnit, evt.BaseUnit)
+	sup := &objects.Supplier{Name: "Acme"}
+	assert.Nil(t, stores.Purchasing.CreateSupplier(ctx, sup))
+
+	// bought by the case
+	order, err := stores.Purchasing.CreatePurchaseOrder(ctx, &objects.CreatePurchaseOrderRequest{
+		SupplierID: sup.ID,
+		Lines:      []*objects.PurchaseOrderLineRequest{{StockID: evt.ID, Quantity: 3, UnitCost: 12, Unit: "case"}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 72, order.Lines[0].Quantity)
+	assert.Equal(t, 0.5, order.Lines[0].UnitCost)
+	_, err = stores.Purchasing.SubmitPurchaseOrder(ctx, &objects.SubmitPurchaseOrderRequest{ID: order.ID})
+	assert.Nil(t, err)
+	_, err = stores.Purchasing.ReceivePurchaseOrder(ctx, &objects.ReceivePurchaseOrderRequest{
+		ID: order.ID, Lines: []*objects.ReceiveLine{{Line: 1, Quantity: 2, Unit: "case"}},
+	})
+	assert.Nil(t, err)
+
+	// sold by the unit
+	sale, err := stores.Sales.CreateSalesOrder(ctx, &objects.CreateSalesOrderRequest{
+		Lines: []*objects.SalesOrderLineRequest{{StockID: evt.ID, Quantity: 6}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 6, sale.Lines[0].Allocated)
+	_, err = stores.Sales.CreateSalesOrder(ctx, &objects.CreateSalesOrderRequest{
+		Lines: []*objects.SalesOrderLineRequest{{StockID: evt.ID, Quantity: 1, Unit: "pallet"}},
+	})
+	assert.True(t, errors.ErrValidation.Is(err))
+	// converted quantities cannot overflow
+	_, err = stores.Sales.CreateSalesOrder(ctx, &objects.CreateSalesOrderRequest{
+		Lines: []*objects.SalesOrderLineRequest{{StockID: evt.ID, Quantity: 1<<61 + 1, Unit: "case"}},
+	})
+	assert.True(t, errors.ErrValidation.Is(err))
+	_, err = stores.Lots.CreateLot(ctx, &objects.CreateLotRequest{
+		StockID: evt.ID, LotNumber: "L1", ExpiresOn: time.Now().AddDate(0, 1, 0), Quantity: objects.MaxQuantity/24 + 1, Unit: "case",
+	})
+	assert.True(t, errors.ErrValidation.Is(err))
+
+	got, err := stores.Stocks.Get(ctx, &objects.GetRequest{ID: evt.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, 42, got.Availability)
+	assert.True(t, got.Convert("case"))
+	assert.Equal(t, 1.75, got.InUnit.Availability)
+	assert.False(t, got.Convert("pallet"))
+
+	// the base unit only changes while the stock is empty
+	_, err = stores.Units.SetUnits(ctx, &objects.SetUnitsRequest{ID: evt.ID, BaseUnit: "can"})
+	assert.True(t, errors.ErrValidation.Is(err))
+	got, err = stores.Units.SetUnits(ctx, &objects.SetUnitsRequest{ID: evt.ID, BaseUnit: "each", Units: map[string]int{"case": 24, "pallet": 1920}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1920, got.Units["pallet"])
+	_, err = stores.Units.SetUnits(ctx, &objects.SetUnitsRequest{ID: "fake", BaseUnit: "each"})
+	assert.True(t, errors.ErrStockNotFound.Is(err))
+}
+
+func TestMemoryIdentifiers(t *testing.T) {
+	stores := NewMemoryStores()
+	ctx := context.Background()
+	evt := &objects.Stock{Name: "Cola", Price: 1, SKU: "COLA-33", Barcode: "036000291452"}
+	assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: evt}))
+	err := stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: &objects.Stock{Name: "Cola", Price: 1, SKU: "COLA-33"}})
+	assert.True(t, errors.ErrSKUExists.Is(err))
+	// the EAN-13 of the UPC-A
+	err = stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: &objects.Stock{Name: "Cola", Price: 1, Barcode: "0036000291452"}})
+	assert.True(t, errors.ErrBarcodeExists.Is(err))
+
+	got, err := stores.Identifiers.GetBySKU(ctx, &objects.GetBySKURequest{SKU: "COLA-33"})
+	assert.Nil(t, err)
+	assert.Equal(t, evt.ID, got.ID)
+	got, err = stores.Identifiers.GetByBarcode(ctx, &objects.GetByBarcodeRequest{Barcode: "0036000291452"})
+	assert.Nil(t, err)
+	assert.Equal(t, evt.ID, got.ID)
+	_, err = stores.Identifiers.GetBySKU(ctx, &objects.GetBySKURequest{SKU: "FANTA-33"})
+	assert.True(t, errors.ErrStockNotFound.Is(err))
+
+	got, err = stores.Identifiers.SetIdentifiers(ctx, &objects.SetIdentifiersRequest{ID: evt.ID, SKU: "COLA-33-CAN"})
+	assert.Nil(t, err)
+	assert.Equal(t, "", got.Barcode)
+	_, err = stores.Identifiers.GetByBarcode(ctx, &objects.GetByBarcodeRequest{Barcode: "036000291452"})
+	assert.True(t, errors.ErrStockNotFound.Is(err))
+
+	_, err = stores.Identifiers.CreateExternalID(ctx, &objects.CreateExternalIDRequest{StockID: evt.ID, System: "amazon", ExternalID: "B000123"})
+	assert.Nil(t, err)
+	_, err = stores.Identifiers.CreateExternalID(ctx, &objects.CreateExternalIDRequest{StockID: evt.ID, System: "amazon", ExternalID: "B000123"})
+	assert.True(t, errors.ErrExternalIDExists.Is(err))
+	_, err = stores.Identifiers.CreateExternalID(ctx, &objects.CreateExternalIDRequest{StockID: "fake", System: "ebay", ExternalID: "1"})
+	assert.True(t, errors.ErrStockNotFound.Is(err))
+	got, err = stores.Identifiers.GetByExternalID(ctx, &objects.ExternalIDRequest{System: "amazon", ExternalID: "B000123"})
+	assert.Nil(t, err)
+	assert.Equal(t, evt.ID, got.ID)
+	list, err := stores.Identifiers.ListExternalIDs(ctx, &objects.ListExternalIDsRequest{StockID: evt.ID})
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+
+	assert.Nil(t, stores.Identifiers.DeleteExternalID(ctx, &objects.ExternalIDRequest{StockID: evt.ID, System: "amazon", ExternalID: "B000123"}))
+	_, err = stores.Identifiers.GetByExternalID(ctx, &objects.ExternalIDRequest{System: "amazon", ExternalID: "B000123"})
+	assert.True(t, errors.ErrExternalIDNotFound.Is(err))
+}
+
+func TestMemoryCategories(t *testing.T) {
+	stores := NewMemoryStores()
+	ctx := context.Background()
+	category := func(name, parentID string) *objects.Category {
+		c, err := stores.Categories.CreateCategory(ctx, &objects.CreateCategoryRequest{Name: name, ParentID: parentID})
+		assert.Nil(t, err)
+		return c
+	}
+	food := category("Food", "")
+	drinks := category("Drinks", food.ID)
+	soda := category("Soda", drinks.ID)
+	toys := category("Toys", "")
+	_, err := stores.Categories.CreateCategory(ctx, &objects.CreateCategoryRequest{Name: "Soda", ParentID: drinks.ID})
+	assert.True(t, errors.ErrCategoryExists.Is(err))
+	_, err = stores.Categories.CreateCategory(ctx, &objects.CreateCategoryRequest{Name: "Tea", ParentID: "fake"})
+	assert.True(t, errors.ErrCategoryNotFound.Is(err))
+
+	cola := &objects.Stock{Name: "Cola", Price: 1, CategoryID: soda.ID, Tags: []string{" Sugar ", "sugar", "fizzy"}}
+	assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: cola}))
+	assert.Equal(t, []string{"fizzy", "sugar"}, cola.Tags)
+	bread := &objects.Stock{Name: "Bread", Price: 1, CategoryID: food.ID}
+	assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: bread}))
+	err = stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: &objects.Stock{Name: "Tea", Price: 1, CategoryID: "fake"}})
+	assert.True(t, errors.ErrCategoryNotFound.Is(err))
+
+	stocks := func(categoryID, tag string) []string {
+		list, err := stores.Categories.ListCategoryStocks(ctx, &objects.ListCategoryStocksRequest{CategoryID: categoryID, Tag: tag})
+		assert.Nil(t, err)
+		var names []string
+		for _, s := range list {
+			names = append(names, s.Name)
+		}
+		return names
+	}
+	assert.ElementsMatch(t, []string{"Cola", "Bread"}, stocks(food.ID, ""))
+	assert.Equal(t, []string{"Cola"}, stocks(drinks.ID, ""))
+	assert.Equal(t, []string{"Cola"}, stocks(food.ID, "Fizzy"))
+	assert.Empty(t, stocks(toys.ID, ""))
+
+	// a category cannot move under its own subtree
+	_, err = stores.Categories.UpdateCategory(ctx, &objects.UpdateCategoryRequest{ID: food.ID, Name: "Food", ParentID: soda.ID})
+	assert.True(t, errors.ErrValidation.Is(err))
+	_, err = stores.Categories.UpdateCategory(ctx, &objects.UpdateCategoryRequest{ID: drinks.ID, Name: "Drinks", ParentID: toys.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Bread"}, stocks(food.ID, ""))
+	assert.Equal(t, []string{"Cola"}, stocks(toys.ID, ""))
+
+	assert.True(t, errors.ErrCategoryNotEmpty.Is(stores.Categories.DeleteCategory(ctx, &objects.DeleteRequest{ID: soda.ID})))
+	got, err := stores.Categories.SetClassification(ctx, &objects.SetClassificationRequest{ID: cola.ID, Tags: []string{"fizzy"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "", got.CategoryID)
+	assert.Nil(t, stores.Categories.DeleteCategory(ctx, &objects.DeleteRequest{ID: soda.ID}))
+
+	list, err := stores.Categories.ListTagStocks(ctx, &objects.ListTagStocksRequest{Tag: "FIZZY"})
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+}
+
+func TestMemoryCounts(t *testing.T) {
+	stores := NewMemoryStores()
+	ctx := context.Background()
+	shelf, err := stores.Categories.CreateCategory(ctx, &objects.CreateCategoryRequest{Name: "Shelf"})
+	assert.Nil(t, err)
+	cola := &objects.Stock{Name: "Cola", Price: 1, Availability: 50, IsActive: true, CategoryID: shelf.ID, Units: map[string]int{"case": 12}}
+	assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: cola}))
+	chips := &objects.Stock{Name: "Chips", Price: 1, Availability: 5, IsActive: true, CategoryID: shelf.ID}
+	assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: chips}))
+	phone := &objects.Stock{Name: "Phone", Price: 1, IsActive: true, CategoryID: shelf.ID, Serialized: true}
+	assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: phone}))
+	submit := func(id string, lines ...*objects.CountedLine) (*objects.CountSession, error) {
+		return stores.Counts.SubmitCount(ctx, &objects.SubmitCountRequest{ID: id, Lines: lines})
+	}
+	sell := func(id string, qty int) {
+		_, err := stores.Sales.CreateSalesOrder(ctx, &objects.CreateSalesOrderRequest{
+			Lines: []*objects.SalesOrderLineRequest{{StockID: id, Quantity: qty}},
+		})
+		assert.Nil(t, err)
+	}
+	availability := func(id string) int {
+		got, _ := stores.Stocks.Get(ctx, &objects.GetRequest{ID: id})
+		return got.Availability
+	}
+
+	_, err = stores.Counts.CreateCount(ctx, &objects.CreateCountRequest{StockIDs: []string{phone.ID}})
+	assert.True(t, errors.ErrSerialsRequired.Is(err))
+	_, err = stores.Counts.CreateCount(ctx, &objects.CreateCountRequest{StockIDs: []string{"unknown"}})
+	assert.True(t, errors.ErrStockNotFound.Is(err))
+
+	// serialized stocks are left out of the sheet of a category
+	count, err := stores.Counts.CreateCount(ctx, &objects.CreateCountRequest{CategoryID: shelf.ID, Reference: "aisle 4"})
+	assert.Nil(t, err)
+	assert.Equal(t, objects.CountOpen, count.Status)
+	assert.Len(t, count.Lines, 2)
+	assert.Equal(t, cola.ID, count.Lines[0].StockID)
+	assert.Equal(t, 50, count.Lines[0].SystemQuantity)
+
+	got, err := submit(count.ID, &objects.CountedLine{Line: 1, Counted: 4, Unit: "case"})
+	assert.Nil(t, err)
+	assert.Equal(t, objects.CountOpen, got.Status)
+	assert.Equal(t, -2, *got.Lines[0].Variance)
+	assert.Nil(t, got.Lines[1].Counted)
+	_, _, err = stores.Counts.ApproveCount(ctx, &objects.GetRequest{ID: count.ID})
+	assert.True(t, errors.ErrInvalidTransition.Is(err))
+	got, err = submit(count.ID, &objects.CountedLine{Line: 2, Counted: 7})
+	assert.Nil(t, err)
+	assert.Equal(t, objects.CountSubmitted, got.Status)
+	assert.NotNil(t, got.SubmittedOn)
+	_, err = submit(count.ID, &objects.CountedLine{Line: 3, Counted: 1})
+	assert.True(t, errors.ErrValidation.Is(err))
+	_, err = submit(count.ID, &objects.CountedLine{Line: 2, Counted: -1})
+	assert.True(t, errors.ErrValidation.Is(err))
+
+	// variances apply to the availability at approval time
+	sell(cola.ID, 10)
+	got, movements, err := stores.Counts.ApproveCount(ctx, &objects.GetRequest{ID: count.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, objects.CountApproved, got.Status)
+	assert.Len(t, movements, 2)
+	assert.Equal(t, objects.MovementCountAdjustment, movements[0].Type)
+	assert.Equal(t, 2, movements[0].Quantity)
+	assert.Equal(t, -2, movements[0].AvailabilityDelta)
+	assert.Equal(t, 38, availability(cola.ID))
+	assert.Equal(t, 7, availability(chips.ID))
+	_, _, err = stores.Counts.ApproveCount(ctx, &objects.GetRequest{ID: count.ID})
+	assert.True(t, errors.ErrInvalidTransition.Is(err))
+	list, err := stores.Movements.ListMovements(ctx, &objects.ListMovementsRequest{Reference: count.ID})
+	assert.Nil(t, err)
+	assert.Len(t, list, 2)
+
+	// units sold since the snapshot would take the stock below zero
+	stale, err := stores.Counts.CreateCount(ctx, &objects.CreateCountRequest{StockIDs: []string{chips.ID, chips.ID}})
+	assert.Nil(t, err)
+	assert.Len(t, stale.Lines, 1)
+	_, err = submit(stale.ID, &objects.CountedLine{Line: 1, Counted: 0})
+	assert.Nil(t, err)
+	sell(chips.ID, 3)
+	_, _, err = stores.Counts.ApproveCount(ctx, &objects.GetRequest{ID: stale.ID})
+	assert.True(t, errors.ErrStaleCount.Is(err))
+	assert.Equal(t, 4, availability(chips.ID))
+	got, err = stores.Counts.CancelCount(ctx, &objects.GetRequest{ID: stale.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, objects.CountCancelled, got.Status)
+
+	counts, err := stores.Counts.ListCounts(ctx, &objects.ListCountsRequest{Status: objects.CountApproved})
+	assert.Nil(t, err)
+	assert.Len(t, counts, 1)
+}
@@ -0,0 +1,28 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/tenant"
+)
+
+func (m *memory) SetUnits(ctx context.Context, in *objects.SetUnitsRequest) (*objects.Stock, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	evt, ok := m.stocks[tenant.FromContext(ctx)][in.ID]
+	if !ok {
+		return nil, errors.ErrStockNotFound
+	}
+	old := *evt
+	if err := setUnits(evt, in); err != nil {
+		*evt = old
+		return nil, err
+	}
+	evt.UpdatedOn = time.Now()
+	m.appendEvents(objects.StockEvents(&old, evt))
+	cp := *evt
+	return &cp, nil
+}
@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/tenant"
+)
+
+func (m *memory) CreateWebhook(ctx context.Context, in *objects.WebhookSubscription) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	in.ID = GenerateUniqueID()
+	in.TenantID = tenant.FromContext(ctx)
+	in.CreatedOn = time.Now()
+	cp := *in
+	m.webhooks[cp.ID] = &cp
+	return nil
+}
+
+func (m *memory) ListWebhooks(ctx context.Context) ([]*objects.WebhookSubscription, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	list := []*objects.WebhookSubscription{}
+	for _, s := range m.webhooks {
+		if s.TenantID == tenantID {
+			cp := *s
+			list = append(list, &cp)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+func (m *memory) DeleteWebhook(ctx context.Context, in *objects.DeleteRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.webhooks[in.ID]
+	if !ok || s.TenantID != tenant.FromContext(ctx) {
+		return errors.ErrWebhookNotFound
+	}
+	delete(m.webhooks, in.ID)
+	// nobody is left to deliver to
+	for id, d := range m.deliveries {
+		if d.SubscriptionID == in.ID && d.Status != objects.DeliveryDelivered {
+			delete(m.deliveries, id)
+		}
+	}
+	return nil
+}
+
+func (m *memory) ListDeliveries(ctx context.Context, in *objects.ListDeliveriesRequest) ([]*objects.WebhookDelivery, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantID := tenant.FromContext(ctx)
+	list := []*objects.WebhookDelivery{}
+	for _, d := range m.deliveries {
+		if d.TenantID == tenantID && (in.Status == "" || d.Status == in.Status) {
+			cp := *d
+			list = append(list, &cp)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID > list[j].ID })
+	if len(list) > in.Limit {
+		list = list[:in.Limit]
+	}
+	return list, nil
+}
+
+func (m *memory) Redeliver(ctx context.Context, in *objects.RedeliverRequest) (*objects.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.deliveries[in.ID]
+	if !ok || d.TenantID != tenant.FromContext(ctx) {
+		return nil, errors.ErrDeliveryNotFound
+	}
+	redeliver(d, time.Now())
+	cp := *d
+	return &cp, nil
+}
+
+func (m *memory) ClaimDeliveries(_ context.Context, now time.Time, lease time.Duration, limit int) ([]*objects.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	due := []*objects.WebhookDelivery{}
+	for _, d := range m.deliveries {
+		if d.Status == objects.DeliveryPending && !d.NextAttemptOn.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptOn.Before(due[j].NextAttemptOn) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	list := make([]*objects.WebhookDelivery, 0, len(due))
+	for _, d := range due {
+		d.NextAttemptOn = now.Add(lease)
+		cp := *d
+		if s, ok := m.webhooks[d.SubscriptionID]; ok {
+			sc := *s
+			cp.Subscription = &sc
+		}
+		list = append(list, &cp)
+	}
+	return list, nil
+}
+
+func (m *memory) SaveDelivery(_ context.Context, in *objects.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.deliveries[in.ID]
+	if !ok {
+		return errors.ErrDeliveryNotFound
+	}
+	d.Status = in.Status
+	d.Attempts = in.Attempts
+	d.NextAttemptOn = in.NextAttemptOn
+	d.LastError = in.LastError
+	d.UpdatedOn = time.Now()
+	return nil
+}
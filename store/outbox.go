@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go-inventory/objects"
+)
+
+// IOutboxStore is the database interface of the outbox, the other stores
+// write a message in the transaction of each change
+type IOutboxStore interface {
+	// ClaimOutbox leases up to limit due messages of topics of every tenant, oldest
+	// first, they are not claimed again before the lease expires. Nil topics claim
+	// every topic, the messages of other topics wait for a publisher routing them.
+	// Published and dead messages are never claimed.
+	ClaimOutbox(ctx context.Context, topics []string, now time.Time, lease time.Duration, limit int) ([]*objects.OutboxMessage, error)
+	// SaveOutboxMessage records the outcome of a publication
+	SaveOutboxMessage(ctx context.Context, in *objects.OutboxMessage) error
+	// PruneOutbox deletes the messages published or dead before before, and the ones
+	// of topics other than topics created before it. It returns how many were deleted.
+	PruneOutbox(ctx context.Context, topics []string, before time.Time) (int, error)
+}
+
+// relayed reports whether messages of topic are relayed to a publisher of topics
+func relayed(topics []string, topic string) bool {
+	return topics == nil || contains(topics, topic)
+}
+
+// dueMessage reports whether msg may be claimed at now
+func dueMessage(msg *objects.OutboxMessage, now time.Time) bool {
+	return msg.PublishedOn == nil && msg.DeadOn == nil && (msg.NextAttemptOn == nil || !msg.NextAttemptOn.After(now))
+}
+
+// prunable reports whether msg is deleted by a prune of the messages before before
+func prunable(msg *objects.OutboxMessage, topics []string, before time.Time) bool {
+	switch {
+	case msg.PublishedOn != nil:
+		return msg.PublishedOn.Before(before)
+	case msg.DeadOn != nil:
+		return msg.DeadOn.Before(before)
+	}
+	return !relayed(topics, msg.Topic) && msg.CreatedOn.Before(before)
+}
+
+// outboxMessages returns the outbox messages of events
+func outboxMessages(events []*objects.StockEvent, now time.Time) []*objects.OutboxMessage {
+	res := make([]*objects.OutboxMessage, 0, len(events))
+	for _, e := range events {
+		payload, _ := json.Marshal(e)
+		res = append(res, &objects.OutboxMessage{
+			ID:        GenerateUniqueID(),
+			TenantID:  e.TenantID,
+			Topic:     e.Type,
+			Key:       e.StockID,
+			Payload:   string(payload),
+			CreatedOn: now,
+		})
+	}
+	return res
+}
@@ -7,18 +7,40 @@ import (
 
 	"go-inventory/errors"
 	"go-inventory/objects"
+	"go-inventory/tenant"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
 type pg struct {
 	db *gorm.DB
+	// use postgres row level security on top of the tenant filters
+	rls bool
+	// transaction of a unit of work, statements run outside of it when nil
+	tx *gorm.DB
+}
+
+// PostgresOption configures the postgres store
+type PostgresOption func(p *pg)
+
+// WithRowLevelSecurity backs tenant isolation with a postgres row level
+// security policy, every statement then runs in a transaction bound to the tenant
+func WithRowLevelSecurity() PostgresOption {
+	return func(p *pg) {
+		p.rls = true
+	}
 }
 
 // NewPostgresStockStore returns a postgres implementation of Stock store
-func NewPostgresStockStore(conn string) IStockStore {
+func NewPostgresStockStore(conn string, opts ...PostgresOption) IStockStore {
+	return NewPostgresStores(conn, opts...).Stocks
+}
+
+// NewPostgresStores returns the postgres implementation of every store
+func NewPostgresStores(conn string, opts ...PostgresOption) *Stores {
 	// create database connection
 	db, err := gorm.Open(postgres.Open(conn),
 		&gorm.Config{
@@ -34,17 +56,190 @@ func NewPostgresStockStore(conn string) IStockStore {
 	if err != nil {
 		panic("Enable to connect to database: " + err.Error())
 	}
-	if err := db.AutoMigrate(&objects.Stock{}); err != nil {
+	if err := db.Use(gormTracing{}); err != nil {
+		panic("Enable to register tracing: " + err.Error())
+	}
+	if err := db.AutoMigrate(
+		&objects.Stock{},
+		&objects.StockEvent{},
+		&objects.WebhookSubscription{},
+		&objects.WebhookDelivery{},
+		&objects.OutboxMessage{},
+		&objects.Supplier{},
+		&objects.PurchaseOrder{},
+		&objects.PurchaseOrderLine{},
+		&objects.SalesOrder{},
+		&objects.SalesOrderLine{},
+		&objects.Return{},
+		&objects.ReturnLine{},
+		&objects.StockMovement{},
+		&objects.Lot{},
+		&objects.SalesOrderPick{},
+		&objects.Serial{},
+		&objects.Product{},
+		&objects.Bundle{},
+		&objects.BundleComponent{},
+		&objects.ExternalID{},
+		&objects.Category{},
+		&objects.CategoryClosure{},
+		&objects.CountSession{},
+		&objects.CountLine{},
+	); err != nil {
 		panic("Enable to migrate database: " + err.Error())
 	}
+	if err := createIndexes(db); err != nil {
+		panic("Enable to create indexes: " + err.Error())
+	}
+	p := &pg{db: db}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.rls {
+		if err := enableRowLevelSecurity(db); err != nil {
+			panic("Enable to set up row level security: " + err.Error())
+		}
+	}
 	// return store implementation
-	return &pg{db: db}
+	return &Stores{Stocks: p, Webhooks: p, Outbox: p, Purchasing: p, Sales: p, Returns: p, Movements: p, Lots: p, Serials: p, Products: p, Bundles: p, Units: p, Identifiers: p, Categories: p, Counts: p}
+}
+
+// tenantTables tables holding a tenant_id column
+var tenantTables = []string{
+	"stocks", "stock_events", "suppliers", "purchase_orders", "purchase_order_lines",
+	"sales_orders", "sales_order_lines", "returns", "return_lines", "stock_movements",
+	"sales_order_picks", "serials", "products", "bundles", "bundle_components", "external_ids",
+	"categories", "category_closures", "count_sessions", "count_lines", "lots",
+	"webhook_subscriptions", "webhook_deliveries", "outbox_messages",
+}
+
+// indexes gorm tags cannot express, identifiers are only unique when set
+// and tags are matched by containment
+var indexes = []string{
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_stock_sku ON stocks (tenant_id, sku) WHERE sku <> ''`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_stock_gtin ON stocks (tenant_id, gtin) WHERE gtin <> ''`,
+	`CREATE INDEX IF NOT EXISTS idx_stock_tags ON stocks USING gin (tags)`,
+}
+
+// createIndexes creates the indexes missing from the database
+func createIndexes(db *gorm.DB) error {
+	for _, stmt := range indexes {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enableRowLevelSecurity restricts the rows of the tenant tables to the tenant
+// set in the `app.tenant_id` setting of the transaction
+func enableRowLevelSecurity(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range tenantTables {
+			for _, stmt := range []string{
+				`ALTER TABLE ` + table + ` ENABLE ROW LEVEL SECURITY`,
+				`ALTER TABLE ` + table + ` FORCE ROW LEVEL SECURITY`,
+				`DROP POLICY IF EXISTS tenant_isolation ON ` + table,
+				`CREATE POLICY tenant_isolation ON ` + table + `
+					USING (tenant_id = current_setting('app.tenant_id', true))
+					WITH CHECK (tenant_id = current_setting('app.tenant_id', true))`,
+			} {
+				if err := tx.Exec(stmt).Error; err != nil {
+					return err
+				}
+			}
+		}
+		// the sweeper finds the expired lots of every tenant, it can only read them and
+		// quarantines each one in a transaction of its tenant. The webhook dispatcher and
+		// the outbox relay serve every tenant, each one only reaches its own table.
+		for _, stmt := range []string{
+			`DROP POLICY IF EXISTS expired_lots_sweep ON lots`,
+			`CREATE POLICY expired_lots_sweep ON lots FOR SELECT
+				USING (current_setting('app.sweep', true) = 'lots' AND status = 'active' AND expires_on <= now())`,
+			`DROP POLICY IF EXISTS webhook_dispatch_subscriptions ON webhook_subscriptions`,
+			`CREATE POLICY webhook_dispatch_subscriptions ON webhook_subscriptions FOR SELECT
+				USING (current_setting('app.sweep', true) = 'webhooks')`,
+			`DROP POLICY IF EXISTS webhook_dispatch_read ON webhook_deliveries`,
+			`CREATE POLICY webhook_dispatch_read ON webhook_deliveries FOR SELECT
+				USING (current_setting('app.sweep', true) = 'webhooks')`,
+			`DROP POLICY IF EXISTS webhook_dispatch_write ON webhook_deliveries`,
+			`CREATE POLICY webhook_dispatch_write ON webhook_deliveries FOR UPDATE
+				USING (current_setting('app.sweep', true) = 'webhooks')`,
+			`DROP POLICY IF EXISTS outbox_relay_read ON outbox_messages`,
+			`CREATE POLICY outbox_relay_read ON outbox_messages FOR SELECT
+				USING (current_setting('app.sweep', true) = 'outbox')`,
+			`DROP POLICY IF EXISTS outbox_relay_write ON outbox_messages`,
+			`CREATE POLICY outbox_relay_write ON outbox_messages FOR UPDATE
+				USING (current_setting('app.sweep', true) = 'outbox')`,
+			`DROP POLICY IF EXISTS outbox_relay_prune ON outbox_messages`,
+			`CREATE POLICY outbox_relay_prune ON outbox_messages FOR DELETE
+				USING (current_setting('app.sweep', true) = 'outbox')`,
+		} {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// session runs fn with a database handle scoped to the tenant of ctx
+func (p *pg) session(ctx context.Context, fn func(db *gorm.DB, tenantID string) error) error {
+	tenantID := tenant.FromContext(ctx)
+	if p.tx != nil {
+		// already bound to the tenant by the unit of work
+		return fn(p.tx.WithContext(ctx), tenantID)
+	}
+	db := p.db.WithContext(ctx)
+	if !p.rls {
+		return fn(db, tenantID)
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT set_config('app.tenant_id', ?, true)", tenantID).Error; err != nil {
+			return err
+		}
+		return fn(tx, tenantID)
+	})
+}
+
+// sweep runs fn in a transaction of a background worker serving every tenant, it
+// reaches their rows through the policies of name
+func (p *pg) sweep(ctx context.Context, name string, fn func(tx *gorm.DB) error) error {
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if p.rls {
+			if err := tx.Exec("SELECT set_config('app.sweep', ?, true)", name).Error; err != nil {
+				return err
+			}
+		}
+		return fn(tx)
+	})
+}
+
+// changeLogLock advisory lock class of the change log of a tenant
+const changeLogLock = 1
+
+// transaction runs fn in a transaction scoped to the tenant of ctx. The writers of
+// a tenant take turns on its change log lock before any row lock, events are
+// numbered in commit order and readers resuming after a sequence miss none.
+func (p *pg) transaction(ctx context.Context, fn func(tx *gorm.DB, tenantID string) error) error {
+	return p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			if p.tx == nil {
+				// held by the unit of work otherwise
+				if err := tx.Exec("SELECT pg_advisory_xact_lock(?, hashtext(?))", changeLogLock, tenantID).Error; err != nil {
+					return err
+				}
+			}
+			return fn(tx, tenantID)
+		})
+	})
 }
 
 func (p *pg) Get(ctx context.Context, in *objects.GetRequest) (*objects.Stock, error) {
 	evt := &objects.Stock{}
-	// take event where id == uid from database
-	err := p.db.WithContext(ctx).Take(evt, "id = ?", in.ID).Error
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		// take event where id == uid from database
+		return db.Take(evt, "id = ? AND tenant_id = ?", in.ID, tenantID).Error
+	})
 	if err == gorm.ErrRecordNotFound {
 		// not found
 		return nil, errors.ErrStockNotFound
@@ -56,15 +251,17 @@ func (p *pg) List(ctx context.Context, in *objects.ListRequest) ([]*objects.Stoc
 	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
 		in.Limit = objects.MaxListLimit
 	}
-	query := p.db.WithContext(ctx).Limit(in.Limit)
-	if in.After != "" {
-		query = query.Where("id > ?", in.After)
-	}
-	if in.Name != "" {
-		query = query.Where("name ilike ?", "%"+in.Name+"%")
-	}
 	list := make([]*objects.Stock, 0, in.Limit)
-	err := query.Order("id").Find(&list).Error
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		query := db.Limit(in.Limit).Where("tenant_id = ?", tenantID)
+		if in.After != "" {
+			query = query.Where("id > ?", in.After)
+		}
+		if in.Name != "" {
+			query = query.Where("name ilike ?", "%"+in.Name+"%")
+		}
+		return query.Order("id").Find(&list).Error
+	})
 	return list, err
 }
 
@@ -72,26 +269,286 @@ func (p *pg) Create(ctx context.Context, in *objects.CreateRequest) error {
 	if in.Stock == nil {
 		return errors.ErrObjectIsRequired
 	}
-	in.Stock.ID = GenerateUniqueID()
-	in.Stock.CreatedOn = p.db.NowFunc()
-	in.Stock.UpdatedOn = p.db.NowFunc()
-	return p.db.WithContext(ctx).
-		Create(in.Stock).
-		Error
+	if err := checkNewStock(in.Stock); err != nil {
+		return err
+	}
+	return p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		in.Stock.ID = GenerateUniqueID()
+		in.Stock.TenantID = tenantID
+		in.Stock.CreatedOn = p.db.NowFunc()
+		in.Stock.UpdatedOn = p.db.NowFunc()
+		if err := p.checkIdentifiers(tx, tenantID, in.Stock); err != nil {
+			return err
+		}
+		if in.Stock.CategoryID != "" {
+			if err := checkCategory(tx, tenantID, in.Stock.CategoryID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(in.Stock).Error; err != nil {
+			return err
+		}
+		// change log, committed with the stock
+		return p.logChanges(tx, tenantID, objects.StockEvents(nil, in.Stock))
+	})
 }
 
 func (p *pg) UpdateDetails(ctx context.Context, in *objects.UpdateDetailsRequest) error {
-	evt := &objects.Stock{
-		ID:           in.ID,
-		Name:         in.Name,
-		Price:        in.Price,
-		Availability: in.Availability,
-		IsActive:     in.IsActive,
-		UpdatedOn:    p.db.NowFunc(),
-	}
-	log.Println(evt)
-	return p.db.WithContext(ctx).Model(evt).
-		Select("id", "name", "price", "availability", "is_active", "updated_on").
-		Updates(evt).
-		Error
+	log.Println(in)
+	return p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		return p.updateDetails(tx, tenantID, in)
+	})
+}
+
+func (p *pg) BatchGet(ctx context.Context, in *objects.BatchGetRequest) ([]*objects.Stock, error) {
+	list := make([]*objects.Stock, 0, len(in.IDs))
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		return db.Where("tenant_id = ? AND id IN ?", tenantID, in.IDs).Find(&list).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inOrder(list, in.IDs), nil
+}
+
+func (p *pg) BatchUpdate(ctx context.Context, in *objects.BatchUpdateRequest) ([]*objects.BatchUpdateResult, error) {
+	results := make([]*objects.BatchUpdateResult, len(in.Items))
+	failed := false
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		for i, item := range in.Items {
+			var err error
+			if in.Atomic {
+				err = p.updateDetails(tx, tenantID, item)
+			} else {
+				// savepoint, a failing item leaves the others applied
+				err = tx.Transaction(func(sp *gorm.DB) error {
+					return p.updateDetails(sp, tenantID, item)
+				})
+			}
+			results[i] = batchResult(item.ID, err)
+			if err != nil && in.Atomic {
+				failed = true
+				abortBatch(in, results, i)
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && !failed {
+		return nil, err
+	}
+	return results, nil
+}
+
+// updateDetails updates the stock of in owned by tenantID and logs the change,
+// tx must be a transaction
+func (p *pg) updateDetails(tx *gorm.DB, tenantID string, in *objects.UpdateDetailsRequest) error {
+	old, err := lockStock(tx, tenantID, in.ID)
+	if err != nil {
+		return err
+	}
+	if err := checkSerialized(old, in); err != nil {
+		return err
+	}
+	evt := *old
+	evt.Name = in.Name
+	evt.Price = in.Price
+	evt.Availability = in.Availability
+	evt.IsActive = in.IsActive
+	evt.ReorderPoint = in.ReorderPoint
+	evt.SafetyStock = in.SafetyStock
+	evt.Backorderable = in.Backorderable
+	evt.BackorderLimit = in.BackorderLimit
+	evt.RestockOn = in.RestockOn
+	evt.Serialized = in.Serialized
+	evt.UpdatedOn = p.db.NowFunc()
+	err = tx.Model(&evt).
+		Select("name", "price", "availability", "is_active", "reorder_point", "safety_stock",
+			"backorderable", "backorder_limit", "restock_on", "serialized", "updated_on").
+		Updates(&evt).Error
+	if err != nil {
+		return err
+	}
+	return p.logChanges(tx, tenantID, objects.StockEvents(old, &evt))
+}
+
+// adjustStock adds to the availability and the backordered units of a stock
+// and logs the change, tx must be a transaction
+func (p *pg) adjustStock(tx *gorm.DB, tenantID, id string, availability, backordered int) error {
+	old, err := lockStock(tx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	evt := *old
+	evt.Availability += availability
+	evt.Backordered += backordered
+	return p.saveStock(tx, tenantID, old, &evt)
+}
+
+// receiveStock adds qty received units to the availability of a stock and fulfils
+// its backorders with them, tx must be a transaction
+func (p *pg) receiveStock(tx *gorm.DB, tenantID, id string, qty int) error {
+	old, err := lockStock(tx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	evt := *old
+	evt.Availability += qty
+	if evt.Backordered > 0 {
+		// expired units serve no backorder
+		now := p.db.NowFunc()
+		expired, err := expiredLots(tx, tenantID, []string{id}, now)
+		if err != nil {
+			return err
+		}
+		changed, movements := expireLots(&evt, expired, now)
+		if err := saveQuarantined(changed, movements, tx); err != nil {
+			return err
+		}
+		lines := []*objects.SalesOrderLine{}
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Joins("JOIN sales_orders ON sales_orders.id = sales_order_lines.order_id").
+			Where("sales_order_lines.tenant_id = ? AND sales_order_lines.stock_id = ? AND sales_order_lines.backordered > 0", tenantID, id).
+			Order("sales_orders.created_on, sales_order_lines.order_id, sales_order_lines.line").
+			Find(&lines).Error
+		if err != nil {
+			return err
+		}
+		fulfilled := fulfil(&evt, lines)
+		var lots []*objects.Lot
+		if len(fulfilled) > 0 {
+			if lots, err = pickableLots(tx, tenantID, []string{id}, now); err != nil {
+				return err
+			}
+		}
+		var picked []*objects.Lot
+		orderIDs := []string{}
+		for _, f := range fulfilled {
+			l := f.line
+			err := tx.Model(l).Select("allocated", "backordered", "expected_on").Updates(l).Error
+			if err != nil {
+				return err
+			}
+			orderIDs = append(orderIDs, l.OrderID)
+			// picks of the line add up with those of earlier receipts
+			order := &objects.SalesOrder{ID: l.OrderID, TenantID: tenantID}
+			picked = pickLine(order, l, f.qty, lots, now, picked)
+			if len(order.Picks) == 0 {
+				continue
+			}
+			err = tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "order_id"}, {Name: "line"}, {Name: "lot_id"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{"quantity": gorm.Expr("sales_order_picks.quantity + excluded.quantity")}),
+			}).Create(order.Picks).Error
+			if err != nil {
+				return err
+			}
+		}
+		if err := saveLots(tx, picked); err != nil {
+			return err
+		}
+		if len(orderIDs) > 0 {
+			// orders without any line left waiting are fully allocated
+			err := tx.Model(&objects.SalesOrder{}).
+				Where("id IN ? AND status = ?", orderIDs, objects.SalesOrderBackordered).
+				Where("NOT EXISTS (SELECT 1 FROM sales_order_lines WHERE order_id = sales_orders.id AND backordered > 0)").
+				Updates(map[string]interface{}{"status": objects.SalesOrderAllocated, "updated_on": p.db.NowFunc()}).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return p.saveStock(tx, tenantID, old, &evt)
+}
+
+// saveStock saves the quantities of evt, changed from old, and logs the change
+func (p *pg) saveStock(tx *gorm.DB, tenantID string, old, evt *objects.Stock) error {
+	evt.UpdatedOn = p.db.NowFunc()
+	err := tx.Model(evt).Select("availability", "backordered", "updated_on").Updates(evt).Error
+	if err != nil {
+		return err
+	}
+	return p.logChanges(tx, tenantID, objects.StockEvents(old, evt))
+}
+
+// lockStock loads a stock of tenantID for update
+func lockStock(tx *gorm.DB, tenantID, id string) (*objects.Stock, error) {
+	evt := &objects.Stock{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Take(evt, "id = ? AND tenant_id = ?", id, tenantID).Error
+	if err == gorm.ErrRecordNotFound {
+		// missing or owned by another tenant
+		return nil, errors.ErrStockNotFound
+	}
+	return evt, err
+}
+
+// Transaction nested units of work run in savepoints
+func (p *pg) Transaction(ctx context.Context, fn func(tx IStockStore) error) error {
+	return p.transaction(ctx, func(tx *gorm.DB, _ string) error {
+		return fn(&pg{db: p.db, rls: p.rls, tx: tx})
+	})
+}
+
+// logChanges appends events to the change log and the outbox
+// and enqueues their webhook deliveries
+func (p *pg) logChanges(tx *gorm.DB, tenantID string, events []*objects.StockEvent) error {
+	if err := tx.Create(events).Error; err != nil {
+		return err
+	}
+	if err := tx.Create(outboxMessages(events, p.db.NowFunc())).Error; err != nil {
+		return err
+	}
+	subs := []*objects.WebhookSubscription{}
+	if err := tx.Find(&subs, "tenant_id = ?", tenantID).Error; err != nil {
+		return err
+	}
+	deliveries := newDeliveries(subs, events, p.db.NowFunc())
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return tx.Create(deliveries).Error
+}
+
+// lowConditions conditions of the stocks below each low stock level
+var lowConditions = map[string]string{
+	objects.LevelReorderPoint: "availability < reorder_point",
+	objects.LevelSafetyStock:  "availability < safety_stock",
+}
+
+func (p *pg) ListLow(ctx context.Context, in *objects.ListLowRequest) ([]*objects.Stock, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	cond, ok := lowConditions[in.Level]
+	if !ok {
+		cond = lowConditions[objects.LevelReorderPoint]
+	}
+	list := make([]*objects.Stock, 0, in.Limit)
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		query := db.Limit(in.Limit).Where("tenant_id = ?", tenantID).Where(cond)
+		if in.After != "" {
+			query = query.Where("id > ?", in.After)
+		}
+		return query.Order("id").Find(&list).Error
+	})
+	return list, err
+}
+
+func (p *pg) ListEvents(ctx context.Context, in *objects.ListEventsRequest) ([]*objects.StockEvent, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxEventsLimit {
+		in.Limit = objects.MaxEventsLimit
+	}
+	list := make([]*objects.StockEvent, 0, in.Limit)
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		query := db.Limit(in.Limit).Where("tenant_id = ? AND seq > ?", tenantID, in.After)
+		if len(in.StockIDs) > 0 {
+			query = query.Where("stock_id IN ?", in.StockIDs)
+		}
+		if len(in.Types) > 0 {
+			query = query.Where("type IN ?", in.Types)
+		}
+		return query.Order("seq").Find(&list).Error
+	})
+	return list, err
 }
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+
+	"gorm.io/gorm"
+)
+
+func (p *pg) CreateBundle(ctx context.Context, in *objects.CreateBundleRequest) (*objects.Bundle, error) {
+	var bundle *objects.Bundle
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		var err error
+		if bundle, err = newBundle(tenantID, in, p.db.NowFunc()); err != nil {
+			return err
+		}
+		if err := p.checkStocks(tx, tenantID, componentIDs(bundle)); err != nil {
+			return err
+		}
+		if err := tx.Create(bundle).Error; err != nil {
+			return err
+		}
+		return withAvailability(tx, tenantID, bundle)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+func (p *pg) GetBundle(ctx context.Context, in *objects.GetRequest) (*objects.Bundle, error) {
+	bundle := &objects.Bundle{}
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		if err := preloadComponents(db).Take(bundle, "id = ? AND tenant_id = ?", in.ID, tenantID).Error; err != nil {
+			return err
+		}
+		return withAvailability(db, tenantID, bundle)
+	})
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrBundleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+func (p *pg) ListBundles(ctx context.Context, in *objects.ListRequest) ([]*objects.Bundle, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	list := make([]*objects.Bundle, 0, in.Limit)
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		query := preloadComponents(db).Limit(in.Limit).Where("tenant_id = ?", tenantID)
+		if in.After != "" {
+			query = query.Where("id > ?", in.After)
+		}
+		if in.Name != "" {
+			query = query.Where("name ilike ?", "%"+in.Name+"%")
+		}
+		if err := query.Order("id").Find(&list).Error; err != nil {
+			return err
+		}
+		return withAvailability(db, tenantID, list...)
+	})
+	return list, err
+}
+
+func (p *pg) SellBundle(ctx context.Context, in *objects.SellBundleRequest) (*objects.SalesOrder, error) {
+	var order *objects.SalesOrder
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		bundle := &objects.Bundle{}
+		err := preloadComponents(tx).Take(bundle, "id = ? AND tenant_id = ?", in.ID, tenantID).Error
+		if err == gorm.ErrRecordNotFound {
+			return errors.ErrBundleNotFound
+		}
+		if err != nil {
+			return err
+		}
+		// components are locked in id order, as when allocating
+		stocks := make(map[string]*objects.Stock, len(bundle.Components))
+		for _, id := range componentIDs(bundle) {
+			evt, err := lockStock(tx, tenantID, id)
+			if err == errors.ErrStockNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			stocks[id] = evt
+		}
+		// expired units cannot build bundles
+		if err := p.expireLots(tx, tenantID, stocks, p.db.NowFunc()); err != nil {
+			return err
+		}
+		req, err := bundleOrder(bundle, stocks, in)
+		if err != nil {
+			return err
+		}
+		order, err = p.createSalesOrder(tx, tenantID, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// withAvailability computes the availability of bundles from the stocks of their components
+func withAvailability(db *gorm.DB, tenantID string, bundles ...*objects.Bundle) error {
+	var ids []string
+	for _, b := range bundles {
+		ids = append(ids, componentIDs(b)...)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	list := []*objects.Stock{}
+	if err := db.Where("tenant_id = ? AND id IN ?", tenantID, ids).Find(&list).Error; err != nil {
+		return err
+	}
+	stocks := make(map[string]*objects.Stock, len(list))
+	for _, evt := range list {
+		stocks[evt.ID] = evt
+	}
+	for _, b := range bundles {
+		b.Availability = buildable(b, stocks)
+	}
+	return nil
+}
+
+func preloadComponents(db *gorm.DB) *gorm.DB {
+	return db.Preload("Components", func(db *gorm.DB) *gorm.DB {
+		return db.Order("stock_id")
+	})
+}
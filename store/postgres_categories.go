@@ -0,0 +1,285 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+
+	"gorm.io/gorm"
+)
+
+func (p *pg) CreateCategory(ctx context.Context, in *objects.CreateCategoryRequest) (*objects.Category, error) {
+	var category *objects.Category
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		if err := lockTree(tx, tenantID, false); err != nil {
+			return err
+		}
+		parentRows := []*objects.CategoryClosure{}
+		if in.ParentID != "" {
+			err := tx.Where("tenant_id = ? AND descendant_id = ?", tenantID, in.ParentID).Find(&parentRows).Error
+			if err != nil {
+				return err
+			}
+			if len(parentRows) == 0 {
+				return errParentNotFound
+			}
+		}
+		if err := checkSiblings(tx, tenantID, in.ParentID, in.Name, ""); err != nil {
+			return err
+		}
+		category = newCategory(tenantID, in, p.db.NowFunc())
+		if err := tx.Create(category).Error; err != nil {
+			return err
+		}
+		return tx.Create(closureRows(category, parentRows)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+func (p *pg) GetCategory(ctx context.Context, in *objects.GetRequest) (*objects.Category, error) {
+	category := &objects.Category{}
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		return db.Take(category, "id = ? AND tenant_id = ?", in.ID, tenantID).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrCategoryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+func (p *pg) ListCategories(ctx context.Context, in *objects.ListCategoriesRequest) ([]*objects.Category, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	list := make([]*objects.Category, 0, in.Limit)
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		query := db.Limit(in.Limit).Where("tenant_id = ?", tenantID)
+		if in.After != "" {
+			query = query.Where("id > ?", in.After)
+		}
+		if in.ParentID != "" {
+			query = query.Where("parent_id = ?", in.ParentID)
+		}
+		return query.Order("id").Find(&list).Error
+	})
+	return list, err
+}
+
+func (p *pg) UpdateCategory(ctx context.Context, in *objects.UpdateCategoryRequest) (*objects.Category, error) {
+	category := &objects.Category{}
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		if err := lockTree(tx, tenantID, false); err != nil {
+			return err
+		}
+		err := tx.Take(category, "id = ? AND tenant_id = ?", in.ID, tenantID).Error
+		if err == gorm.ErrRecordNotFound {
+			return errors.ErrCategoryNotFound
+		}
+		if err != nil {
+			return err
+		}
+		moved := in.ParentID != category.ParentID
+		if moved && in.ParentID != "" {
+			// the parent must exist outside of the subtree of the category
+			rows := []*objects.CategoryClosure{}
+			err := tx.Where("tenant_id = ? AND descendant_id = ?", tenantID, in.ParentID).Find(&rows).Error
+			if err != nil {
+				return err
+			}
+			if len(rows) == 0 {
+				return errParentNotFound
+			}
+			for _, r := range rows {
+				if r.AncestorID == category.ID {
+					return errCycle
+				}
+			}
+		}
+		if moved || in.Name != category.Name {
+			if err := checkSiblings(tx, tenantID, in.ParentID, in.Name, category.ID); err != nil {
+				return err
+			}
+		}
+		category.Name = in.Name
+		category.ParentID = in.ParentID
+		category.UpdatedOn = p.db.NowFunc()
+		if err := tx.Model(category).Select("name", "parent_id", "updated_on").Updates(category).Error; err != nil {
+			return err
+		}
+		if !moved {
+			return nil
+		}
+		// detach the subtree from its former ancestors then link it to the new ones
+		err = tx.Exec(`DELETE FROM category_closures
+			WHERE tenant_id = ?
+			AND descendant_id IN (SELECT descendant_id FROM category_closures WHERE tenant_id = ? AND ancestor_id = ?)
+			AND ancestor_id NOT IN (SELECT descendant_id FROM category_closures WHERE tenant_id = ? AND ancestor_id = ?)`,
+			tenantID, tenantID, category.ID, tenantID, category.ID).Error
+		if err != nil || category.ParentID == "" {
+			return err
+		}
+		return tx.Exec(`INSERT INTO category_closures (ancestor_id, descendant_id, tenant_id, depth)
+			SELECT super.ancestor_id, sub.descendant_id, super.tenant_id, super.depth + sub.depth + 1
+			FROM category_closures super, category_closures sub
+			WHERE super.tenant_id = ? AND super.descendant_id = ?
+			AND sub.tenant_id = ? AND sub.ancestor_id = ?`,
+			tenantID, category.ParentID, tenantID, category.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+func (p *pg) DeleteCategory(ctx context.Context, in *objects.DeleteRequest) error {
+	return p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		if err := lockTree(tx, tenantID, false); err != nil {
+			return err
+		}
+		category := &objects.Category{}
+		err := tx.Take(category, "id = ? AND tenant_id = ?", in.ID, tenantID).Error
+		if err == gorm.ErrRecordNotFound {
+			return errors.ErrCategoryNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var children, stocks int64
+		if err := tx.Model(&objects.Category{}).Where("tenant_id = ? AND parent_id = ?", tenantID, in.ID).Count(&children).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&objects.Stock{}).Where("tenant_id = ? AND category_id = ?", tenantID, in.ID).Count(&stocks).Error; err != nil {
+			return err
+		}
+		if children > 0 || stocks > 0 {
+			return errors.ErrCategoryNotEmpty
+		}
+		if err := tx.Where("tenant_id = ? AND descendant_id = ?", tenantID, in.ID).Delete(&objects.CategoryClosure{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(category).Error
+	})
+}
+
+func (p *pg) ListCategoryStocks(ctx context.Context, in *objects.ListCategoryStocksRequest) ([]*objects.Stock, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	list := make([]*objects.Stock, 0, in.Limit)
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		if err := checkCategory(db, tenantID, in.CategoryID); err != nil {
+			return err
+		}
+		query := db.Limit(in.Limit).
+			Where("tenant_id = ? AND category_id IN (SELECT descendant_id FROM category_closures WHERE tenant_id = ? AND ancestor_id = ?)",
+				tenantID, tenantID, in.CategoryID)
+		if in.After != "" {
+			query = query.Where("id > ?", in.After)
+		}
+		if in.Tag != "" {
+			query = query.Where("tags @> ?::jsonb", tagsJSON(in.Tag))
+		}
+		return query.Order("id").Find(&list).Error
+	})
+	return list, err
+}
+
+func (p *pg) ListTagStocks(ctx context.Context, in *objects.ListTagStocksRequest) ([]*objects.Stock, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	list := make([]*objects.Stock, 0, in.Limit)
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		query := db.Limit(in.Limit).Where("tenant_id = ? AND tags @> ?::jsonb", tenantID, tagsJSON(in.Tag))
+		if in.After != "" {
+			query = query.Where("id > ?", in.After)
+		}
+		return query.Order("id").Find(&list).Error
+	})
+	return list, err
+}
+
+func (p *pg) SetClassification(ctx context.Context, in *objects.SetClassificationRequest) (*objects.Stock, error) {
+	tags, err := normalizeTags(in.Tags)
+	if err != nil {
+		return nil, err
+	}
+	var evt objects.Stock
+	err = p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		old, err := lockStock(tx, tenantID, in.ID)
+		if err != nil {
+			return err
+		}
+		if in.CategoryID != "" {
+			if err := checkCategory(tx, tenantID, in.CategoryID); err != nil {
+				return err
+			}
+		}
+		evt = *old
+		evt.CategoryID = in.CategoryID
+		evt.Tags = tags
+		evt.UpdatedOn = p.db.NowFunc()
+		if err := tx.Model(&evt).Select("category_id", "tags", "updated_on").Updates(&evt).Error; err != nil {
+			return err
+		}
+		return p.logChanges(tx, tenantID, objects.StockEvents(old, &evt))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &evt, nil
+}
+
+// lockTree serializes the changes of the category tree of tenantID, shared locks are
+// taken by the stocks joining a category, which cannot be deleted meanwhile
+func lockTree(tx *gorm.DB, tenantID string, shared bool) error {
+	fn := "pg_advisory_xact_lock"
+	if shared {
+		fn = "pg_advisory_xact_lock_shared"
+	}
+	return tx.Exec(`SELECT `+fn+`(hashtext(?))`, "categories/"+tenantID).Error
+}
+
+// checkCategory fails with errors.ErrCategoryNotFound unless the category id exists
+func checkCategory(tx *gorm.DB, tenantID, id string) error {
+	if err := lockTree(tx, tenantID, true); err != nil {
+		return err
+	}
+	var n int64
+	if err := tx.Model(&objects.Category{}).Where("id = ? AND tenant_id = ?", id, tenantID).Count(&n).Error; err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.ErrCategoryNotFound.WithField("category_id", "category_not_found", "no such category")
+	}
+	return nil
+}
+
+// checkSiblings rejects name when another child of parentID than id has it
+func checkSiblings(tx *gorm.DB, tenantID, parentID, name, id string) error {
+	var n int64
+	err := tx.Model(&objects.Category{}).
+		Where("tenant_id = ? AND parent_id = ? AND name = ? AND id <> ?", tenantID, parentID, name, id).
+		Count(&n).Error
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return errors.ErrCategoryExists
+	}
+	return nil
+}
+
+// tagsJSON returns the jsonb array holding tag, normalized
+func tagsJSON(tag string) string {
+	b, _ := json.Marshal([]string{normalizeTag(tag)})
+	return string(b)
+}
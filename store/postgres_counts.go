@@ -0,0 +1,184 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (p *pg) CreateCount(ctx context.Context, in *objects.CreateCountRequest) (*objects.CountSession, error) {
+	var count *objects.CountSession
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		list := []*objects.Stock{}
+		if in.CategoryID != "" {
+			if err := checkCategory(tx, tenantID, in.CategoryID); err != nil {
+				return err
+			}
+			// one more than allowed to report oversized sheets
+			err := tx.Limit(objects.MaxCountLines+1).
+				Where("tenant_id = ? AND NOT serialized AND category_id IN (SELECT descendant_id FROM category_closures WHERE tenant_id = ? AND ancestor_id = ?)",
+					tenantID, tenantID, in.CategoryID).
+				Order("id").Find(&list).Error
+			if err != nil {
+				return err
+			}
+		} else {
+			ids := countStockIDs(in)
+			stocks, err := p.findStocks(tx, tenantID, ids)
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				list = append(list, stocks[id])
+			}
+		}
+		var err error
+		if count, err = newCount(tenantID, in, list, p.db.NowFunc()); err != nil {
+			return err
+		}
+		return tx.Create(count).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return count, nil
+}
+
+func (p *pg) GetCount(ctx context.Context, in *objects.GetRequest) (*objects.CountSession, error) {
+	count := &objects.CountSession{}
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		return preloadLines(db).Take(count, "id = ? AND tenant_id = ?", in.ID, tenantID).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrCountNotFound
+	}
+	return count, err
+}
+
+func (p *pg) ListCounts(ctx context.Context, in *objects.ListCountsRequest) ([]*objects.CountSession, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	list := make([]*objects.CountSession, 0, in.Limit)
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		query := preloadLines(db).Limit(in.Limit).Where("tenant_id = ?", tenantID)
+		if in.After != "" {
+			query = query.Where("id > ?", in.After)
+		}
+		if in.Status != "" {
+			query = query.Where("status = ?", in.Status)
+		}
+		return query.Order("id").Find(&list).Error
+	})
+	return list, err
+}
+
+func (p *pg) SubmitCount(ctx context.Context, in *objects.SubmitCountRequest) (*objects.CountSession, error) {
+	count := &objects.CountSession{}
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		if err := lockCount(tx, tenantID, in.ID, count); err != nil {
+			return err
+		}
+		stocks, err := p.findStocks(tx, tenantID, countLineStockIDs(count))
+		if err != nil {
+			return err
+		}
+		changed, err := recordCounts(count, in, stocks, p.db.NowFunc())
+		if err != nil {
+			return err
+		}
+		for _, l := range changed {
+			if err := tx.Model(l).Select("counted", "variance").Updates(l).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(count).Select("status", "submitted_on", "updated_on").Updates(count).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return count, nil
+}
+
+func (p *pg) ApproveCount(ctx context.Context, in *objects.GetRequest) (*objects.CountSession, []*objects.StockMovement, error) {
+	count := &objects.CountSession{}
+	var movements []*objects.StockMovement
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		if err := lockCount(tx, tenantID, in.ID, count); err != nil {
+			return err
+		}
+		// current availability, locked in id order until the adjustments are applied
+		ids := countLineStockIDs(count)
+		sort.Strings(ids)
+		stocks := make(map[string]*objects.Stock, len(ids))
+		for _, id := range ids {
+			stock, err := lockStock(tx, tenantID, id)
+			if err != nil {
+				return err
+			}
+			stocks[id] = stock
+		}
+		posted, err := approveCount(count, stocks, p.db.NowFunc())
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(count).Select("status", "approved_on", "updated_on").Updates(count).Error; err != nil {
+			return err
+		}
+		if len(posted) == 0 {
+			return nil
+		}
+		if err := tx.Create(posted).Error; err != nil {
+			return err
+		}
+		movements = posted
+		// found units are received, they serve backorders first
+		for _, mv := range posted {
+			if mv.AvailabilityDelta > 0 {
+				err = p.receiveStock(tx, tenantID, mv.StockID, mv.AvailabilityDelta)
+			} else {
+				err = p.adjustStock(tx, tenantID, mv.StockID, mv.AvailabilityDelta, 0)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return count, movements, nil
+}
+
+func (p *pg) CancelCount(ctx context.Context, in *objects.GetRequest) (*objects.CountSession, error) {
+	count := &objects.CountSession{}
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		if err := lockCount(tx, tenantID, in.ID, count); err != nil {
+			return err
+		}
+		if err := cancelCount(count, p.db.NowFunc()); err != nil {
+			return err
+		}
+		return tx.Model(count).Select("status", "updated_on").Updates(count).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return count, nil
+}
+
+// lockCount loads the count session id with its lines into count and locks it
+func lockCount(tx *gorm.DB, tenantID, id string, count *objects.CountSession) error {
+	err := preloadLines(tx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Take(count, "id = ? AND tenant_id = ?", id, tenantID).Error
+	if err == gorm.ErrRecordNotFound {
+		return errors.ErrCountNotFound
+	}
+	return err
+}
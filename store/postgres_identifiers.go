@@ -0,0 +1,153 @@
+package store
+
+import (
+	"context"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+
+	"gorm.io/gorm"
+)
+
+func (p *pg) GetBySKU(ctx context.Context, in *objects.GetBySKURequest) (*objects.Stock, error) {
+	return p.getStockWhere(ctx, "sku = ?", in.SKU)
+}
+
+func (p *pg) GetByBarcode(ctx context.Context, in *objects.GetByBarcodeRequest) (*objects.Stock, error) {
+	return p.getStockWhere(ctx, "gtin = ?", gtin14(in.Barcode))
+}
+
+func (p *pg) SetIdentifiers(ctx context.Context, in *objects.SetIdentifiersRequest) (*objects.Stock, error) {
+	var evt objects.Stock
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		old, err := lockStock(tx, tenantID, in.ID)
+		if err != nil {
+			return err
+		}
+		evt = *old
+		evt.SKU = in.SKU
+		evt.Barcode = in.Barcode
+		evt.GTIN = gtin14(in.Barcode)
+		if err := p.checkIdentifiers(tx, tenantID, &evt); err != nil {
+			return err
+		}
+		evt.UpdatedOn = p.db.NowFunc()
+		if err := tx.Model(&evt).Select("sku", "barcode", "gtin", "updated_on").Updates(&evt).Error; err != nil {
+			return err
+		}
+		return p.logChanges(tx, tenantID, objects.StockEvents(old, &evt))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &evt, nil
+}
+
+func (p *pg) CreateExternalID(ctx context.Context, in *objects.CreateExternalIDRequest) (*objects.ExternalID, error) {
+	var ext *objects.ExternalID
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		if err := p.checkStocks(tx, tenantID, []string{in.StockID}); err != nil {
+			return err
+		}
+		var n int64
+		err := tx.Model(&objects.ExternalID{}).
+			Where("tenant_id = ? AND system = ? AND external_id = ?", tenantID, in.System, in.ExternalID).
+			Count(&n).Error
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return errors.ErrExternalIDExists
+		}
+		ext = newExternalID(tenantID, in, p.db.NowFunc())
+		return tx.Create(ext).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ext, nil
+}
+
+func (p *pg) ListExternalIDs(ctx context.Context, in *objects.ListExternalIDsRequest) ([]*objects.ExternalID, error) {
+	list := []*objects.ExternalID{}
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		if err := p.checkStocks(db, tenantID, []string{in.StockID}); err != nil {
+			return err
+		}
+		return db.Where("tenant_id = ? AND stock_id = ?", tenantID, in.StockID).
+			Order("system, external_id").
+			Find(&list).Error
+	})
+	return list, err
+}
+
+func (p *pg) GetByExternalID(ctx context.Context, in *objects.ExternalIDRequest) (*objects.Stock, error) {
+	evt := &objects.Stock{}
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		ext := &objects.ExternalID{}
+		err := db.Take(ext, "tenant_id = ? AND system = ? AND external_id = ?", tenantID, in.System, in.ExternalID).Error
+		if err == gorm.ErrRecordNotFound {
+			return errors.ErrExternalIDNotFound
+		}
+		if err != nil {
+			return err
+		}
+		err = db.Take(evt, "id = ? AND tenant_id = ?", ext.StockID, tenantID).Error
+		if err == gorm.ErrRecordNotFound {
+			return errors.ErrStockNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return evt, nil
+}
+
+func (p *pg) DeleteExternalID(ctx context.Context, in *objects.ExternalIDRequest) error {
+	return p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		query := db.Where("tenant_id = ? AND system = ? AND external_id = ?", tenantID, in.System, in.ExternalID)
+		if in.StockID != "" {
+			query = query.Where("stock_id = ?", in.StockID)
+		}
+		res := query.Delete(&objects.ExternalID{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errors.ErrExternalIDNotFound
+		}
+		return nil
+	})
+}
+
+// getStockWhere returns the stock of the tenant of ctx matching query
+func (p *pg) getStockWhere(ctx context.Context, query string, args ...interface{}) (*objects.Stock, error) {
+	evt := &objects.Stock{}
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		return db.Where("tenant_id = ?", tenantID).Where(query, args...).Take(evt).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrStockNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return evt, nil
+}
+
+// checkIdentifiers rejects the SKU and barcode of s when another stock has one of them,
+// the unique indexes catch the races
+func (p *pg) checkIdentifiers(tx *gorm.DB, tenantID string, s *objects.Stock) error {
+	if s.SKU == "" && s.GTIN == "" {
+		return nil
+	}
+	others := []*objects.Stock{}
+	err := tx.Where("tenant_id = ?", tenantID).
+		Where(tx.Where("sku = ? AND sku <> ''", s.SKU).Or("gtin = ? AND gtin <> ''", s.GTIN)).
+		Limit(2).Find(&others).Error
+	if err != nil {
+		return err
+	}
+	return checkIdentifiers(s, others)
+}
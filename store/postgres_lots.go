@@ -0,0 +1,244 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+	"go-inventory/tenant"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (p *pg) CreateLot(ctx context.Context, in *objects.CreateLotRequest) (*objects.Lot, error) {
+	var lot *objects.Lot
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		stock, err := lockStock(tx, tenantID, in.StockID)
+		if err != nil {
+			return err
+		}
+		if err := checkUnserialized(stock); err != nil {
+			return err
+		}
+		if in.Quantity, err = toBase(stock, in.Unit, in.Quantity, "quantity"); err != nil {
+			return err
+		}
+		in.Unit = ""
+		var n int64
+		err = tx.Model(&objects.Lot{}).
+			Where("tenant_id = ? AND stock_id = ? AND lot_number = ?", tenantID, in.StockID, in.LotNumber).
+			Count(&n).Error
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return errors.ErrLotExists
+		}
+		now := p.db.NowFunc()
+		lot = newLot(tenantID, in, now)
+		if err := tx.Create(lot).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(lotMovement(lot, objects.MovementLotReceipt, lot.Quantity, lot.Quantity, now)).Error; err != nil {
+			return err
+		}
+		if err := p.receiveStock(tx, tenantID, lot.StockID, lot.Quantity); err != nil {
+			return err
+		}
+		// backorders fulfilled by the receipt are picked from the lot
+		return tx.Take(lot, "id = ?", lot.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lot, nil
+}
+
+func (p *pg) GetLot(ctx context.Context, in *objects.GetRequest) (*objects.Lot, error) {
+	lot := &objects.Lot{}
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		return db.Take(lot, "id = ? AND tenant_id = ?", in.ID, tenantID).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrLotNotFound
+	}
+	return lot, err
+}
+
+func (p *pg) ListLots(ctx context.Context, in *objects.ListLotsRequest) ([]*objects.Lot, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	list := make([]*objects.Lot, 0, in.Limit)
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		query := db.Limit(in.Limit).Where("tenant_id = ? AND stock_id = ?", tenantID, in.StockID)
+		if in.Status != "" {
+			query = query.Where("status = ?", in.Status)
+		}
+		return query.Order("expires_on, created_on, id").Find(&list).Error
+	})
+	return list, err
+}
+
+func (p *pg) ListExpiringLots(ctx context.Context, in *objects.ListExpiringLotsRequest) ([]*objects.Lot, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	until := p.db.NowFunc().AddDate(0, 0, in.Days)
+	list := make([]*objects.Lot, 0, in.Limit)
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		return db.Limit(in.Limit).
+			Where("tenant_id = ? AND status = ? AND quantity > 0 AND expires_on <= ?", tenantID, objects.LotActive, until).
+			Order("expires_on, created_on, id").
+			Find(&list).Error
+	})
+	return list, err
+}
+
+func (p *pg) QuarantineExpiredLots(ctx context.Context, limit int) (int, error) {
+	// expired lots are found across tenants, under row level security through the
+	// read only sweep policy
+	expired := []*objects.Lot{}
+	err := p.sweep(ctx, "lots", func(tx *gorm.DB) error {
+		return tx.Select("id", "tenant_id", "stock_id").
+			Where("status = ? AND expires_on <= ?", objects.LotActive, p.db.NowFunc()).
+			Order("expires_on, id").
+			Limit(limit).
+			Find(&expired).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	quarantined := 0
+	for _, candidate := range expired {
+		// counted once committed
+		done := false
+		err := p.transaction(tenant.NewContext(ctx, candidate.TenantID), func(tx *gorm.DB, tenantID string) error {
+			// the stock is locked before the lot, as when allocating
+			stock, err := lockStock(tx, tenantID, candidate.StockID)
+			if err != nil {
+				return err
+			}
+			lot := &objects.Lot{}
+			err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Take(lot, "id = ? AND status = ?", candidate.ID, objects.LotActive).Error
+			if err == gorm.ErrRecordNotFound {
+				// quarantined meanwhile
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			mv := quarantine(lot, stock.Availability, p.db.NowFunc())
+			if err := saveQuarantined([]*objects.Lot{lot}, []*objects.StockMovement{mv}, tx); err != nil {
+				return err
+			}
+			if err := p.adjustStock(tx, tenantID, lot.StockID, mv.AvailabilityDelta, 0); err != nil {
+				return err
+			}
+			done = true
+			return nil
+		})
+		if err != nil {
+			return quarantined, err
+		}
+		if done {
+			quarantined++
+		}
+	}
+	return quarantined, nil
+}
+
+// expiredLots loads the expired active lots of the stocks of ids for update, the
+// stocks must be locked first
+func expiredLots(tx *gorm.DB, tenantID string, ids []string, now time.Time) ([]*objects.Lot, error) {
+	lots := []*objects.Lot{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("tenant_id = ? AND stock_id IN ? AND status = ? AND expires_on <= ?", tenantID, ids, objects.LotActive, now).
+		Order("id").
+		Find(&lots).Error
+	return lots, err
+}
+
+// pickableLots loads the lots of the stocks of ids units are picked from for update,
+// first expired first out, the stocks must be locked first
+func pickableLots(tx *gorm.DB, tenantID string, ids []string, now time.Time) ([]*objects.Lot, error) {
+	lots := []*objects.Lot{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("tenant_id = ? AND stock_id IN ? AND status = ? AND quantity > 0 AND expires_on > ?", tenantID, ids, objects.LotActive, now).
+		Order("expires_on, created_on, id").
+		Find(&lots).Error
+	return lots, err
+}
+
+// saveQuarantined saves the status of quarantined lots and their movements
+func saveQuarantined(lots []*objects.Lot, movements []*objects.StockMovement, tx *gorm.DB) error {
+	for _, lot := range lots {
+		if err := tx.Model(lot).Select("status", "quarantined_on", "updated_on").Updates(lot).Error; err != nil {
+			return err
+		}
+	}
+	if len(movements) == 0 {
+		return nil
+	}
+	return tx.Create(movements).Error
+}
+
+// expireLots quarantines the expired lots of the locked stocks, by id, and takes
+// their units out of availability, the stocks are updated in place
+func (p *pg) expireLots(tx *gorm.DB, tenantID string, stocks map[string]*objects.Stock, now time.Time) error {
+	ids := make([]string, 0, len(stocks))
+	for id := range stocks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	lots, err := expiredLots(tx, tenantID, ids, now)
+	if err != nil || len(lots) == 0 {
+		return err
+	}
+	for _, id := range ids {
+		s := stocks[id]
+		before := s.Availability
+		changed, movements := expireLots(s, lots, now)
+		if len(changed) == 0 {
+			continue
+		}
+		if err := saveQuarantined(changed, movements, tx); err != nil {
+			return err
+		}
+		if err := p.adjustStock(tx, tenantID, id, s.Availability-before, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockLots loads the lots of ids for update, by id
+func lockLots(tx *gorm.DB, tenantID string, ids []string) (map[string]*objects.Lot, error) {
+	lots := make(map[string]*objects.Lot, len(ids))
+	if len(ids) == 0 {
+		return lots, nil
+	}
+	list := []*objects.Lot{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("tenant_id = ? AND id IN ?", tenantID, ids).
+		Order("id").
+		Find(&list).Error
+	for _, lot := range list {
+		lots[lot.ID] = lot
+	}
+	return lots, err
+}
+
+// saveLots saves the quantities of lots
+func saveLots(tx *gorm.DB, lots []*objects.Lot) error {
+	for _, lot := range lots {
+		if err := tx.Model(lot).Select("quantity", "updated_on").Updates(lot).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go-inventory/objects"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (p *pg) ClaimOutbox(ctx context.Context, topics []string, now time.Time, lease time.Duration, limit int) ([]*objects.OutboxMessage, error) {
+	list := []*objects.OutboxMessage{}
+	if topics != nil && len(topics) == 0 {
+		return list, nil
+	}
+	err := p.sweep(ctx, "outbox", func(tx *gorm.DB) error {
+		// several relays may run, skip the rows another one holds
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("published_on IS NULL AND dead_on IS NULL").
+			Where("next_attempt_on IS NULL OR next_attempt_on <= ?", now)
+		if topics != nil {
+			query = query.Where("topic IN ?", topics)
+		}
+		err := query.Order("seq").
+			Limit(limit).
+			Find(&list).Error
+		if err != nil || len(list) == 0 {
+			return err
+		}
+		seqs := make([]int64, 0, len(list))
+		for _, msg := range list {
+			seqs = append(seqs, msg.Seq)
+		}
+		until := now.Add(lease)
+		for _, msg := range list {
+			msg.NextAttemptOn = &until
+		}
+		return tx.Model(&objects.OutboxMessage{}).
+			Where("seq IN ?", seqs).
+			Update("next_attempt_on", until).Error
+	})
+	return list, err
+}
+
+func (p *pg) SaveOutboxMessage(ctx context.Context, in *objects.OutboxMessage) error {
+	return p.sweep(ctx, "outbox", func(tx *gorm.DB) error {
+		return tx.Model(in).
+			Select("published_on", "dead_on", "attempts", "last_error", "next_attempt_on").
+			Updates(in).Error
+	})
+}
+
+func (p *pg) PruneOutbox(ctx context.Context, topics []string, before time.Time) (int, error) {
+	pruned := 0
+	err := p.sweep(ctx, "outbox", func(tx *gorm.DB) error {
+		query := tx.Where("published_on < ? OR dead_on < ?", before, before)
+		if topics != nil {
+			// unrouted messages are kept as long as the published ones
+			unrouted := p.db.Where("published_on IS NULL AND dead_on IS NULL AND created_on < ?", before)
+			if len(topics) > 0 {
+				unrouted = unrouted.Where("topic NOT IN ?", topics)
+			}
+			query = query.Or(unrouted)
+		}
+		res := query.Delete(&objects.OutboxMessage{})
+		pruned = int(res.RowsAffected)
+		return res.Error
+	})
+	return pruned, err
+}
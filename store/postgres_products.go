@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (p *pg) CreateProduct(ctx context.Context, in *objects.CreateProductRequest) (*objects.Product, error) {
+	if err := checkDefinitions(in.Attributes); err != nil {
+		return nil, err
+	}
+	var product *objects.Product
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		product = newProduct(tenantID, in, p.db.NowFunc())
+		return db.Create(product).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func (p *pg) GetProduct(ctx context.Context, in *objects.GetRequest) (*objects.Product, error) {
+	product := &objects.Product{}
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		return db.Take(product, "id = ? AND tenant_id = ?", in.ID, tenantID).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrProductNotFound
+	}
+	return product, err
+}
+
+func (p *pg) ListProducts(ctx context.Context, in *objects.ListRequest) ([]*objects.Product, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	list := make([]*objects.Product, 0, in.Limit)
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		query := db.Limit(in.Limit).Where("tenant_id = ?", tenantID)
+		if in.After != "" {
+			query = query.Where("id > ?", in.After)
+		}
+		if in.Name != "" {
+			query = query.Where("name ilike ?", "%"+in.Name+"%")
+		}
+		return query.Order("id").Find(&list).Error
+	})
+	return list, err
+}
+
+func (p *pg) CreateVariant(ctx context.Context, in *objects.CreateVariantRequest) (*objects.Stock, error) {
+	var variant *objects.Stock
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		// the product is locked, concurrent variants are checked one after the other
+		product := &objects.Product{}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Take(product, "id = ? AND tenant_id = ?", in.ProductID, tenantID).Error
+		if err == gorm.ErrRecordNotFound {
+			return errors.ErrProductNotFound
+		}
+		if err != nil {
+			return err
+		}
+		variants := []*objects.Stock{}
+		if err := tx.Where("tenant_id = ? AND product_id = ?", tenantID, product.ID).Find(&variants).Error; err != nil {
+			return err
+		}
+		if variant, err = newVariant(product, variants, in, p.db.NowFunc()); err != nil {
+			return err
+		}
+		if err := p.checkIdentifiers(tx, tenantID, variant); err != nil {
+			return err
+		}
+		if err := tx.Create(variant).Error; err != nil {
+			return err
+		}
+		return p.logChanges(tx, tenantID, objects.StockEvents(nil, variant))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return variant, nil
+}
+
+func (p *pg) ListVariants(ctx context.Context, in *objects.ListVariantsRequest) ([]*objects.Stock, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	list := make([]*objects.Stock, 0, in.Limit)
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		if err := p.checkProduct(db, tenantID, in.ProductID); err != nil {
+			return err
+		}
+		query := db.Limit(in.Limit).Where("tenant_id = ? AND product_id = ?", tenantID, in.ProductID)
+		if in.After != "" {
+			query = query.Where("id > ?", in.After)
+		}
+		return query.Order("id").Find(&list).Error
+	})
+	return list, err
+}
+
+func (p *pg) ProductAvailability(ctx context.Context, in *objects.GetRequest) (*objects.ProductAvailability, error) {
+	variants := []*objects.Stock{}
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		if err := p.checkProduct(db, tenantID, in.ID); err != nil {
+			return err
+		}
+		return db.Where("tenant_id = ? AND product_id = ? AND is_active", tenantID, in.ID).Find(&variants).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return aggregate(in.ID, variants), nil
+}
+
+// checkProduct fails with errors.ErrProductNotFound unless the product exists
+func (p *pg) checkProduct(db *gorm.DB, tenantID, id string) error {
+	var n int64
+	if err := db.Model(&objects.Product{}).Where("id = ? AND tenant_id = ?", id, tenantID).Count(&n).Error; err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.ErrProductNotFound
+	}
+	return nil
+}
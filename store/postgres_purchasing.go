@@ -0,0 +1,236 @@
+package store
+
+import (
+	"context"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (p *pg) CreateSupplier(ctx context.Context, in *objects.Supplier) error {
+	return p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		in.ID = GenerateUniqueID()
+		in.TenantID = tenantID
+		in.CreatedOn = p.db.NowFunc()
+		in.UpdatedOn = in.CreatedOn
+		return db.Create(in).Error
+	})
+}
+
+func (p *pg) GetSupplier(ctx context.Context, in *objects.GetRequest) (*objects.Supplier, error) {
+	s := &objects.Supplier{}
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		return db.Take(s, "id = ? AND tenant_id = ?", in.ID, tenantID).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrSupplierNotFound
+	}
+	return s, err
+}
+
+func (p *pg) ListSuppliers(ctx context.Context, in *objects.ListRequest) ([]*objects.Supplier, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	list := make([]*objects.Supplier, 0, in.Limit)
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		query := db.Limit(in.Limit).Where("tenant_id = ?", tenantID)
+		if in.After != "" {
+			query = query.Where("id > ?", in.After)
+		}
+		if in.Name != "" {
+			query = query.Where("name ilike ?", "%"+in.Name+"%")
+		}
+		return query.Order("id").Find(&list).Error
+	})
+	return list, err
+}
+
+func (p *pg) CreatePurchaseOrder(ctx context.Context, in *objects.CreatePurchaseOrderRequest) (*objects.PurchaseOrder, error) {
+	var order *objects.PurchaseOrder
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		var n int64
+		if err := tx.Model(&objects.Supplier{}).Where("id = ? AND tenant_id = ?", in.SupplierID, tenantID).Count(&n).Error; err != nil {
+			return err
+		}
+		if n == 0 {
+			return errors.ErrSupplierNotFound
+		}
+		stocks, err := p.findStocks(tx, tenantID, lineStockIDs(in))
+		if err != nil {
+			return err
+		}
+		if err := purchaseUnits(in, stocks); err != nil {
+			return err
+		}
+		if order, err = newPurchaseOrder(tenantID, in, p.db.NowFunc()); err != nil {
+			return err
+		}
+		return tx.Create(order).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (p *pg) GetPurchaseOrder(ctx context.Context, in *objects.GetRequest) (*objects.PurchaseOrder, error) {
+	order := &objects.PurchaseOrder{}
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		return preloadLines(db).Take(order, "id = ? AND tenant_id = ?", in.ID, tenantID).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrPurchaseOrderNotFound
+	}
+	return order, err
+}
+
+func (p *pg) ListPurchaseOrders(ctx context.Context, in *objects.ListPurchaseOrdersRequest) ([]*objects.PurchaseOrder, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	list := make([]*objects.PurchaseOrder, 0, in.Limit)
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		query := preloadLines(db).Limit(in.Limit).Where("tenant_id = ?", tenantID)
+		if in.After != "" {
+			query = query.Where("id > ?", in.After)
+		}
+		if in.Status != "" {
+			query = query.Where("status = ?", in.Status)
+		}
+		if in.SupplierID != "" {
+			query = query.Where("supplier_id = ?", in.SupplierID)
+		}
+		return query.Order("id").Find(&list).Error
+	})
+	return list, err
+}
+
+func (p *pg) SubmitPurchaseOrder(ctx context.Context, in *objects.SubmitPurchaseOrderRequest) (*objects.PurchaseOrder, error) {
+	var order *objects.PurchaseOrder
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		var err error
+		if order, err = lockPurchaseOrder(tx, tenantID, in.ID); err != nil {
+			return err
+		}
+		if err := submit(order, p.db.NowFunc()); err != nil {
+			return err
+		}
+		return tx.Model(order).Select("status", "submitted_on", "updated_on").Updates(order).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (p *pg) ReceivePurchaseOrder(ctx context.Context, in *objects.ReceivePurchaseOrderRequest) (*objects.PurchaseOrder, error) {
+	var order *objects.PurchaseOrder
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		var err error
+		if order, err = lockPurchaseOrder(tx, tenantID, in.ID); err != nil {
+			return err
+		}
+		// locked before the receipt, their units convert its quantities
+		ids := receivedStockIDs(order, in)
+		stocks := make(map[string]*objects.Stock, len(ids))
+		for _, id := range ids {
+			if stocks[id], err = lockStock(tx, tenantID, id); err != nil {
+				return err
+			}
+		}
+		if err := receiveUnits(order, in, stocks); err != nil {
+			return err
+		}
+		now := p.db.NowFunc()
+		changed, received, err := receive(order, in, now)
+		if err != nil {
+			return err
+		}
+		serials, movements, err := receivedSerials(order, in, stocks, now)
+		if err != nil {
+			return err
+		}
+		if len(serials) > 0 {
+			var known []string
+			err := tx.Model(&objects.Serial{}).
+				Where("tenant_id = ? AND serial_number IN ?", tenantID, serialNumbers(serials)).
+				Limit(1).Pluck("serial_number", &known).Error
+			if err != nil {
+				return err
+			}
+			if len(known) > 0 {
+				return errors.ErrSerialExists.WithField("serials", "serial_exists", "serial "+known[0]+" was already received")
+			}
+			if err := tx.Create(serials).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(movements).Error; err != nil {
+				return err
+			}
+		}
+		for _, l := range changed {
+			if err := tx.Model(l).Update("received", l.Received).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(order).Select("status", "updated_on").Updates(order).Error; err != nil {
+			return err
+		}
+		for _, id := range sortedKeys(received) {
+			if err := p.receiveStock(tx, tenantID, id, received[id]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// lockPurchaseOrder loads an order and its lines for update
+func lockPurchaseOrder(tx *gorm.DB, tenantID, id string) (*objects.PurchaseOrder, error) {
+	order := &objects.PurchaseOrder{}
+	err := preloadLines(tx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Take(order, "id = ? AND tenant_id = ?", id, tenantID).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrPurchaseOrderNotFound
+	}
+	return order, err
+}
+
+func preloadLines(db *gorm.DB) *gorm.DB {
+	return db.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("line")
+	})
+}
+
+// findStocks returns the stocks of ids by id, errors.ErrStockNotFound when one is missing
+func (p *pg) findStocks(tx *gorm.DB, tenantID string, ids []string) (map[string]*objects.Stock, error) {
+	list := []*objects.Stock{}
+	if err := tx.Where("tenant_id = ? AND id IN ?", tenantID, ids).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	stocks := make(map[string]*objects.Stock, len(list))
+	found := make([]string, 0, len(list))
+	for _, s := range list {
+		stocks[s.ID] = s
+		found = append(found, s.ID)
+	}
+	return stocks, missingStock(ids, found)
+}
+
+// checkStocks fails with errors.ErrStockNotFound unless every stock of ids exists
+func (p *pg) checkStocks(tx *gorm.DB, tenantID string, ids []string) error {
+	var found []string
+	err := tx.Model(&objects.Stock{}).Where("tenant_id = ? AND id IN ?", tenantID, ids).Pluck("id", &found).Error
+	if err != nil {
+		return err
+	}
+	return missingStock(ids, found)
+}
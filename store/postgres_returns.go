@@ -0,0 +1,163 @@
+package store
+
+import (
+	"context"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (p *pg) CreateReturn(ctx context.Context, in *objects.CreateReturnRequest) (*objects.Return, error) {
+	var ret *objects.Return
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		if in.SalesOrderID != "" {
+			var n int64
+			err := tx.Model(&objects.SalesOrder{}).Where("id = ? AND tenant_id = ?", in.SalesOrderID, tenantID).Count(&n).Error
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				return errors.ErrSalesOrderNotFound
+			}
+		}
+		stocks, err := p.findStocks(tx, tenantID, returnStockIDs(in))
+		if err != nil {
+			return err
+		}
+		if err := returnUnits(in, stocks); err != nil {
+			return err
+		}
+		if ret, err = newReturn(tenantID, in, p.db.NowFunc()); err != nil {
+			return err
+		}
+		return tx.Create(ret).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (p *pg) GetReturn(ctx context.Context, in *objects.GetRequest) (*objects.Return, error) {
+	ret := &objects.Return{}
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		return preloadLines(db).Take(ret, "id = ? AND tenant_id = ?", in.ID, tenantID).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrReturnNotFound
+	}
+	return ret, err
+}
+
+func (p *pg) ListReturns(ctx context.Context, in *objects.ListReturnsRequest) ([]*objects.Return, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	list := make([]*objects.Return, 0, in.Limit)
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		query := preloadLines(db).Limit(in.Limit).Where("tenant_id = ?", tenantID)
+		if in.After != "" {
+			query = query.Where("id > ?", in.After)
+		}
+		if in.Status != "" {
+			query = query.Where("status = ?", in.Status)
+		}
+		if in.SalesOrderID != "" {
+			query = query.Where("sales_order_id = ?", in.SalesOrderID)
+		}
+		return query.Order("id").Find(&list).Error
+	})
+	return list, err
+}
+
+func (p *pg) InspectReturn(ctx context.Context, in *objects.InspectReturnRequest) (*objects.Return, []*objects.StockMovement, error) {
+	ret := &objects.Return{}
+	var movements []*objects.StockMovement
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		err := preloadLines(tx).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Take(ret, "id = ? AND tenant_id = ?", in.ID, tenantID).Error
+		if err == gorm.ErrRecordNotFound {
+			return errors.ErrReturnNotFound
+		}
+		if err != nil {
+			return err
+		}
+		ids := inspectStockIDs(ret, in)
+		stocks := make(map[string]*objects.Stock, len(ids))
+		for _, id := range ids {
+			stock, err := lockStock(tx, tenantID, id)
+			if err == errors.ErrStockNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			stocks[id] = stock
+		}
+		now := p.db.NowFunc()
+		changed, posted, restock, err := inspect(ret, in, stocks, now)
+		if err != nil {
+			return err
+		}
+		serials, err := lockSerials(tx, tenantID, inspectedSerials(in))
+		if err != nil {
+			return err
+		}
+		restocked, serialMovements, err := restockedSerials(ret, in, stocks, serials, now)
+		if err != nil {
+			return err
+		}
+		if err := saveSerials(tx, restocked); err != nil {
+			return err
+		}
+		posted = append(posted, serialMovements...)
+		for _, l := range changed {
+			err := tx.Model(l).Select("restocked", "refurbished", "scrapped").Updates(l).Error
+			if err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(ret).Select("status", "updated_on").Updates(ret).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(posted).Error; err != nil {
+			return err
+		}
+		movements = posted
+		// restocked units are received, they serve backorders first
+		for _, id := range sortedKeys(restock) {
+			if err := p.receiveStock(tx, tenantID, id, restock[id]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return ret, movements, nil
+}
+
+func (p *pg) ListMovements(ctx context.Context, in *objects.ListMovementsRequest) ([]*objects.StockMovement, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxEventsLimit {
+		in.Limit = objects.MaxEventsLimit
+	}
+	list := make([]*objects.StockMovement, 0, in.Limit)
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		query := db.Limit(in.Limit).Where("tenant_id = ? AND seq > ?", tenantID, in.After)
+		if in.StockID != "" {
+			query = query.Where("stock_id = ?", in.StockID)
+		}
+		if in.Reference != "" {
+			query = query.Where("reference = ?", in.Reference)
+		}
+		if in.Type != "" {
+			query = query.Where("type = ?", in.Type)
+		}
+		return query.Order("seq").Find(&list).Error
+	})
+	return list, err
+}
@@ -0,0 +1,209 @@
+package store
+
+import (
+	"context"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (p *pg) CreateSalesOrder(ctx context.Context, in *objects.CreateSalesOrderRequest) (*objects.SalesOrder, error) {
+	var order *objects.SalesOrder
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		var err error
+		order, err = p.createSalesOrder(tx, tenantID, in)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// createSalesOrder allocates and creates the order of in, tx must be a transaction
+func (p *pg) createSalesOrder(tx *gorm.DB, tenantID string, in *objects.CreateSalesOrderRequest) (*objects.SalesOrder, error) {
+	// locked in id order, concurrent orders cannot deadlock
+	ids := salesStockIDs(in)
+	stocks := make(map[string]*objects.Stock, len(ids))
+	for _, id := range ids {
+		evt, err := lockStock(tx, tenantID, id)
+		if err == errors.ErrStockNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		stocks[id] = evt
+	}
+	now := p.db.NowFunc()
+	if err := p.expireLots(tx, tenantID, stocks, now); err != nil {
+		return nil, err
+	}
+	if err := salesUnits(in, stocks); err != nil {
+		return nil, err
+	}
+	serials, err := lockSerials(tx, tenantID, requestedSerials(in))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSerials(in, stocks, serials); err != nil {
+		return nil, err
+	}
+	allocs, err := allocate(in, stocks)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if err := p.adjustStock(tx, tenantID, id, -allocs[id].take, allocs[id].backorder); err != nil {
+			return nil, err
+		}
+	}
+	order := newSalesOrder(tenantID, in, allocs, stocks, now)
+	lots, err := pickableLots(tx, tenantID, ids, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := saveLots(tx, pickLots(order, lots, now)); err != nil {
+		return nil, err
+	}
+	if err := tx.Create(order).Error; err != nil {
+		return nil, err
+	}
+	if movements := sellSerials(order, in, serials, now); len(movements) > 0 {
+		if err := saveSerials(tx, sortedSerials(serials)); err != nil {
+			return nil, err
+		}
+		if err := tx.Create(movements).Error; err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+func (p *pg) GetSalesOrder(ctx context.Context, in *objects.GetRequest) (*objects.SalesOrder, error) {
+	order := &objects.SalesOrder{}
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		return preloadSalesOrder(db).Take(order, "id = ? AND tenant_id = ?", in.ID, tenantID).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrSalesOrderNotFound
+	}
+	return order, err
+}
+
+func (p *pg) ListSalesOrders(ctx context.Context, in *objects.ListSalesOrdersRequest) ([]*objects.SalesOrder, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	list := make([]*objects.SalesOrder, 0, in.Limit)
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		query := preloadSalesOrder(db).Limit(in.Limit).Where("tenant_id = ?", tenantID)
+		if in.After != "" {
+			query = query.Where("id > ?", in.After)
+		}
+		if in.Status != "" {
+			query = query.Where("status = ?", in.Status)
+		}
+		if in.Reference != "" {
+			query = query.Where("reference = ?", in.Reference)
+		}
+		return query.Order("id").Find(&list).Error
+	})
+	return list, err
+}
+
+func (p *pg) CancelSalesOrder(ctx context.Context, in *objects.CancelSalesOrderRequest) (*objects.SalesOrder, error) {
+	order := &objects.SalesOrder{}
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		err := preloadSalesOrder(tx).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Take(order, "id = ? AND tenant_id = ?", in.ID, tenantID).Error
+		if err == gorm.ErrRecordNotFound {
+			return errors.ErrSalesOrderNotFound
+		}
+		if err != nil {
+			return err
+		}
+		now := p.db.NowFunc()
+		picked := lotIDs(order)
+		released, err := cancel(order, now)
+		if err != nil {
+			return err
+		}
+		// the stocks are locked before the lots, as when allocating
+		for _, id := range allocKeys(released) {
+			if _, err := lockStock(tx, tenantID, id); err != nil {
+				return err
+			}
+		}
+		lots, err := lockLots(tx, tenantID, picked)
+		if err != nil {
+			return err
+		}
+		withheld := unpick(order, lots, now)
+		changed := make([]*objects.Lot, 0, len(lots))
+		for _, id := range picked {
+			if lot, ok := lots[id]; ok {
+				changed = append(changed, lot)
+			}
+		}
+		if err := saveLots(tx, changed); err != nil {
+			return err
+		}
+		if err := tx.Where("order_id = ?", order.ID).Delete(&objects.SalesOrderPick{}).Error; err != nil {
+			return err
+		}
+		sold := []*objects.Serial{}
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("tenant_id = ? AND order_id = ?", tenantID, order.ID).
+			Order("serial_number").
+			Find(&sold).Error
+		if err != nil {
+			return err
+		}
+		if movements := releaseSerials(sold, now); len(movements) > 0 {
+			if err := saveSerials(tx, sold); err != nil {
+				return err
+			}
+			if err := tx.Create(movements).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(order).Select("status", "cancelled_on", "updated_on").Updates(order).Error; err != nil {
+			return err
+		}
+		err = tx.Model(&objects.SalesOrderLine{}).Where("order_id = ?", order.ID).
+			Updates(map[string]interface{}{"allocated": 0, "backordered": 0, "expected_on": nil}).Error
+		if err != nil {
+			return err
+		}
+		// released units are received, they serve the backorders of other orders first
+		for _, id := range allocKeys(released) {
+			if released[id].backorder > 0 {
+				if err := p.adjustStock(tx, tenantID, id, 0, -released[id].backorder); err != nil {
+					return err
+				}
+			}
+			// a line without allocated units releases nothing
+			if qty := released[id].take - withheld[id]; qty > 0 {
+				if err := p.receiveStock(tx, tenantID, id, qty); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// preloadSalesOrder loads the lines and the picks of sales orders
+func preloadSalesOrder(db *gorm.DB) *gorm.DB {
+	return preloadLines(db).Preload("Picks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("line, expires_on, lot_id")
+	})
+}
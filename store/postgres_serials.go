@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (p *pg) GetSerial(ctx context.Context, in *objects.GetSerialRequest) (*objects.Serial, []*objects.StockMovement, error) {
+	serial := &objects.Serial{}
+	movements := []*objects.StockMovement{}
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		err := db.Take(serial, "tenant_id = ? AND serial_number = ?", tenantID, in.SerialNumber).Error
+		if err != nil {
+			return err
+		}
+		return db.Where("tenant_id = ? AND serial_number = ?", tenantID, in.SerialNumber).
+			Order("seq").Find(&movements).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil, errors.ErrSerialNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return serial, movements, nil
+}
+
+func (p *pg) ListSerials(ctx context.Context, in *objects.ListSerialsRequest) ([]*objects.Serial, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	list := make([]*objects.Serial, 0, in.Limit)
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		query := db.Limit(in.Limit).Where("tenant_id = ? AND stock_id = ?", tenantID, in.StockID)
+		if in.After != "" {
+			query = query.Where("serial_number > ?", in.After)
+		}
+		if in.Status != "" {
+			query = query.Where("status = ?", in.Status)
+		}
+		return query.Order("serial_number").Find(&list).Error
+	})
+	return list, err
+}
+
+// lockSerials loads the serials of numbers for update, by number
+func lockSerials(tx *gorm.DB, tenantID string, numbers []string) (map[string]*objects.Serial, error) {
+	serials := make(map[string]*objects.Serial, len(numbers))
+	if len(numbers) == 0 {
+		return serials, nil
+	}
+	list := []*objects.Serial{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("tenant_id = ? AND serial_number IN ?", tenantID, numbers).
+		Order("serial_number").
+		Find(&list).Error
+	for _, s := range list {
+		serials[s.SerialNumber] = s
+	}
+	return serials, err
+}
+
+// saveSerials saves the allocation of serials
+func saveSerials(tx *gorm.DB, serials []*objects.Serial) error {
+	for _, s := range serials {
+		if err := tx.Model(s).Select("status", "order_id", "line", "updated_on").Updates(s).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
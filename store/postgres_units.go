@@ -0,0 +1,32 @@
+package store
+
+import (
+	"context"
+
+	"go-inventory/objects"
+
+	"gorm.io/gorm"
+)
+
+func (p *pg) SetUnits(ctx context.Context, in *objects.SetUnitsRequest) (*objects.Stock, error) {
+	var evt objects.Stock
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		old, err := lockStock(tx, tenantID, in.ID)
+		if err != nil {
+			return err
+		}
+		evt = *old
+		if err := setUnits(&evt, in); err != nil {
+			return err
+		}
+		evt.UpdatedOn = p.db.NowFunc()
+		if err := tx.Model(&evt).Select("base_unit", "units", "updated_on").Updates(&evt).Error; err != nil {
+			return err
+		}
+		return p.logChanges(tx, tenantID, objects.StockEvents(old, &evt))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &evt, nil
+}
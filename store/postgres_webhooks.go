@@ -0,0 +1,132 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (p *pg) CreateWebhook(ctx context.Context, in *objects.WebhookSubscription) error {
+	return p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		in.ID = GenerateUniqueID()
+		in.TenantID = tenantID
+		in.CreatedOn = p.db.NowFunc()
+		return db.Create(in).Error
+	})
+}
+
+func (p *pg) ListWebhooks(ctx context.Context) ([]*objects.WebhookSubscription, error) {
+	list := []*objects.WebhookSubscription{}
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		return db.Order("id").Find(&list, "tenant_id = ?", tenantID).Error
+	})
+	return list, err
+}
+
+func (p *pg) DeleteWebhook(ctx context.Context, in *objects.DeleteRequest) error {
+	return p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		res := tx.Delete(&objects.WebhookSubscription{}, "id = ? AND tenant_id = ?", in.ID, tenantID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errors.ErrWebhookNotFound
+		}
+		// nobody is left to deliver to
+		return tx.Delete(&objects.WebhookDelivery{}, "subscription_id = ? AND status <> ?", in.ID, objects.DeliveryDelivered).Error
+	})
+}
+
+func (p *pg) ListDeliveries(ctx context.Context, in *objects.ListDeliveriesRequest) ([]*objects.WebhookDelivery, error) {
+	if in.Limit == 0 || in.Limit > objects.MaxListLimit {
+		in.Limit = objects.MaxListLimit
+	}
+	list := make([]*objects.WebhookDelivery, 0, in.Limit)
+	err := p.session(ctx, func(db *gorm.DB, tenantID string) error {
+		query := db.Limit(in.Limit).Where("tenant_id = ?", tenantID)
+		if in.Status != "" {
+			query = query.Where("status = ?", in.Status)
+		}
+		return query.Order("created_on DESC").Find(&list).Error
+	})
+	return list, err
+}
+
+func (p *pg) Redeliver(ctx context.Context, in *objects.RedeliverRequest) (*objects.WebhookDelivery, error) {
+	d := &objects.WebhookDelivery{}
+	err := p.transaction(ctx, func(tx *gorm.DB, tenantID string) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Take(d, "id = ? AND tenant_id = ?", in.ID, tenantID).Error
+		if err == gorm.ErrRecordNotFound {
+			return errors.ErrDeliveryNotFound
+		}
+		if err != nil {
+			return err
+		}
+		redeliver(d, p.db.NowFunc())
+		return tx.Save(d).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (p *pg) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*objects.WebhookDelivery, error) {
+	list := []*objects.WebhookDelivery{}
+	err := p.sweep(ctx, "webhooks", func(tx *gorm.DB) error {
+		// several dispatchers may run, skip the rows another one holds
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_on <= ?", objects.DeliveryPending, now).
+			Order("next_attempt_on").
+			Limit(limit).
+			Find(&list).Error
+		if err != nil || len(list) == 0 {
+			return err
+		}
+		ids := make([]string, 0, len(list))
+		subIDs := make([]string, 0, len(list))
+		for _, d := range list {
+			ids = append(ids, d.ID)
+			subIDs = append(subIDs, d.SubscriptionID)
+		}
+		err = tx.Model(&objects.WebhookDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_on", now.Add(lease)).Error
+		if err != nil {
+			return err
+		}
+		subs := []*objects.WebhookSubscription{}
+		if err := tx.Find(&subs, "id IN ?", subIDs).Error; err != nil {
+			return err
+		}
+		attach(list, subs)
+		return nil
+	})
+	return list, err
+}
+
+func (p *pg) SaveDelivery(ctx context.Context, in *objects.WebhookDelivery) error {
+	in.UpdatedOn = p.db.NowFunc()
+	return p.sweep(ctx, "webhooks", func(tx *gorm.DB) error {
+		return tx.Model(in).
+			Select("status", "attempts", "next_attempt_on", "last_error", "updated_on").
+			Updates(in).Error
+	})
+}
+
+// attach sets the subscription of each delivery
+func attach(list []*objects.WebhookDelivery, subs []*objects.WebhookSubscription) {
+	byID := make(map[string]*objects.WebhookSubscription, len(subs))
+	for _, s := range subs {
+		byID[s.ID] = s
+	}
+	for _, d := range list {
+		d.Subscription = byID[d.SubscriptionID]
+	}
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+)
+
+// IProductStore is the database interface for products and their variants
+type IProductStore interface {
+	CreateProduct(ctx context.Context, in *objects.CreateProductRequest) (*objects.Product, error)
+	GetProduct(ctx context.Context, in *objects.GetRequest) (*objects.Product, error)
+	// ListProducts returns products ordered by id, optionally matching in.Name
+	ListProducts(ctx context.Context, in *objects.ListRequest) ([]*objects.Product, error)
+	// CreateVariant creates a stock of the product, its attributes must match the
+	// definitions of the product and differ from the ones of the other variants
+	CreateVariant(ctx context.Context, in *objects.CreateVariantRequest) (*objects.Stock, error)
+	ListVariants(ctx context.Context, in *objects.ListVariantsRequest) ([]*objects.Stock, error)
+	// ProductAvailability sums the availability of the active variants of a product
+	ProductAvailability(ctx context.Context, in *objects.GetRequest) (*objects.ProductAvailability, error)
+}
+
+// checkDefinitions validates the attribute definitions of a product
+func checkDefinitions(defs []*objects.AttributeDefinition) error {
+	seen := map[string]bool{}
+	for i, d := range defs {
+		field := fmt.Sprintf("attributes[%d]", i)
+		switch {
+		case d == nil:
+			return errors.ErrValidation.WithField(field, "required", "is required")
+		case seen[d.Name]:
+			return errors.ErrValidation.WithField(field+".name", "duplicate_attribute", "attribute "+d.Name+" is defined twice")
+		case d.Type == objects.AttributeEnum && len(d.Values) == 0:
+			return errors.ErrValidation.WithField(field+".values", "required", "enum attributes list their values")
+		case d.Type != objects.AttributeEnum && len(d.Values) > 0:
+			return errors.ErrValidation.WithField(field+".values", "unexpected_values", "only enum attributes list values")
+		case d.Type != objects.AttributeString && d.Type != objects.AttributeNumber &&
+			d.Type != objects.AttributeBoolean && d.Type != objects.AttributeEnum:
+			return errors.ErrValidation.WithField(field+".type", "unknown_type", "unknown attribute type "+d.Type)
+		}
+		seen[d.Name] = true
+	}
+	return nil
+}
+
+// checkAttributes validates the attribute values of a variant of product
+func checkAttributes(product *objects.Product, attrs map[string]interface{}) error {
+	defs := make(map[string]*objects.AttributeDefinition, len(product.Attributes))
+	for _, d := range product.Attributes {
+		defs[d.Name] = d
+		if _, ok := attrs[d.Name]; d.Required && !ok {
+			return errors.ErrValidation.WithField("attributes."+d.Name, "required", "is required")
+		}
+	}
+	for name, v := range attrs {
+		field := "attributes." + name
+		d, ok := defs[name]
+		if !ok {
+			return errors.ErrValidation.WithField(field, "unknown_attribute", "the product has no attribute "+name)
+		}
+		valid := false
+		switch d.Type {
+		case objects.AttributeString:
+			_, valid = v.(string)
+		case objects.AttributeNumber:
+			// json numbers
+			_, valid = v.(float64)
+		case objects.AttributeBoolean:
+			_, valid = v.(bool)
+		case objects.AttributeEnum:
+			s, _ := v.(string)
+			valid = contains(d.Values, s)
+		}
+		if !valid {
+			return errors.ErrValidation.WithField(field, "invalid_value", fmt.Sprintf("%v is not a valid %s", v, d.Type))
+		}
+	}
+	return nil
+}
+
+// newProduct returns the product of in
+func newProduct(tenantID string, in *objects.CreateProductRequest, now time.Time) *objects.Product {
+	defs := in.Attributes
+	if defs == nil {
+		defs = []*objects.AttributeDefinition{}
+	}
+	return &objects.Product{
+		ID:         GenerateUniqueID(),
+		TenantID:   tenantID,
+		Name:       in.Name,
+		Attributes: defs,
+		CreatedOn:  now,
+		UpdatedOn:  now,
+	}
+}
+
+// newVariant checks in against product and its variants and returns the stock of in
+func newVariant(product *objects.Product, variants []*objects.Stock, in *objects.CreateVariantRequest, now time.Time) (*objects.Stock, error) {
+	if err := checkAttributes(product, in.Attributes); err != nil {
+		return nil, err
+	}
+	for _, v := range variants {
+		if reflect.DeepEqual(v.Attributes, in.Attributes) || (len(v.Attributes) == 0 && len(in.Attributes) == 0) {
+			return nil, errors.ErrVariantExists.WithMessage("Variant " + v.ID + " has the same attributes")
+		}
+	}
+	return &objects.Stock{
+		ID:           GenerateUniqueID(),
+		TenantID:     product.TenantID,
+		Name:         in.Name,
+		Price:        in.Price,
+		Availability: in.Availability,
+		IsActive:     in.IsActive,
+		SKU:          in.SKU,
+		Barcode:      in.Barcode,
+		GTIN:         gtin14(in.Barcode),
+		ProductID:    product.ID,
+		Attributes:   in.Attributes,
+		BaseUnit:     objects.DefaultBaseUnit,
+		CreatedOn:    now,
+		UpdatedOn:    now,
+	}, nil
+}
+
+// aggregate sums the availability of the active variants of a product
+func aggregate(productID string, variants []*objects.Stock) *objects.ProductAvailability {
+	res := &objects.ProductAvailability{ProductID: productID, ByAttribute: map[string]map[string]int{}}
+	for _, v := range variants {
+		if !v.IsActive {
+			continue
+		}
+		res.Variants++
+		res.Availability += v.Availability
+		res.Backordered += v.Backordered
+		for name, value := range v.Attributes {
+			if res.ByAttribute[name] == nil {
+				res.ByAttribute[name] = map[string]int{}
+			}
+			res.ByAttribute[name][fmt.Sprint(value)] += v.Availability
+		}
+	}
+	res.AvailableToPromise = res.Availability - res.Backordered
+	return res
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+)
+
+// IPurchasingStore is the database interface for suppliers and purchase orders
+type IPurchasingStore interface {
+	CreateSupplier(ctx context.Context, in *objects.Supplier) error
+	GetSupplier(ctx context.Context, in *objects.GetRequest) (*objects.Supplier, error)
+	// ListSuppliers returns suppliers ordered by id, optionally matching in.Name
+	ListSuppliers(ctx context.Context, in *objects.ListRequest) ([]*objects.Supplier, error)
+	// CreatePurchaseOrder creates a draft order, its supplier and stocks must exist
+	CreatePurchaseOrder(ctx context.Context, in *objects.CreatePurchaseOrderRequest) (*objects.PurchaseOrder, error)
+	GetPurchaseOrder(ctx context.Context, in *objects.GetRequest) (*objects.PurchaseOrder, error)
+	ListPurchaseOrders(ctx context.Context, in *objects.ListPurchaseOrdersRequest) ([]*objects.PurchaseOrder, error)
+	SubmitPurchaseOrder(ctx context.Context, in *objects.SubmitPurchaseOrderRequest) (*objects.PurchaseOrder, error)
+	// ReceivePurchaseOrder records received quantities and adds them to the
+	// availability of the stocks, in one transaction
+	ReceivePurchaseOrder(ctx context.Context, in *objects.ReceivePurchaseOrderRequest) (*objects.PurchaseOrder, error)
+}
+
+// newPurchaseOrder returns the draft order of in
+func newPurchaseOrder(tenantID string, in *objects.CreatePurchaseOrderRequest, now time.Time) (*objects.PurchaseOrder, error) {
+	order := &objects.PurchaseOrder{
+		ID:         GenerateUniqueID(),
+		TenantID:   tenantID,
+		SupplierID: in.SupplierID,
+		Status:     objects.PurchaseOrderDraft,
+		Lines:      make([]*objects.PurchaseOrderLine, 0, len(in.Lines)),
+		CreatedOn:  now,
+		UpdatedOn:  now,
+	}
+	for i, l := range in.Lines {
+		if err := checkQuantity(l.Quantity, fmt.Sprintf("lines[%d].quantity", i)); err != nil {
+			return nil, err
+		}
+		order.Lines = append(order.Lines, &objects.PurchaseOrderLine{
+			OrderID:  order.ID,
+			Line:     i + 1,
+			TenantID: tenantID,
+			StockID:  l.StockID,
+			Quantity: l.Quantity,
+			UnitCost: l.UnitCost,
+		})
+	}
+	return order, nil
+}
+
+// submit moves a draft order to submitted
+func submit(order *objects.PurchaseOrder, now time.Time) error {
+	if order.Status != objects.PurchaseOrderDraft {
+		return errors.ErrInvalidTransition.WithMessage("Only draft purchase orders can be submitted, this one is " + order.Status)
+	}
+	order.Status = objects.PurchaseOrderSubmitted
+	order.SubmittedOn = &now
+	order.UpdatedOn = now
+	return nil
+}
+
+// receive applies the quantities of in to the lines of order and updates its status,
+// it returns the changed lines and the quantity received by stock
+func receive(order *objects.PurchaseOrder, in *objects.ReceivePurchaseOrderRequest, now time.Time) ([]*objects.PurchaseOrderLine, map[string]int, error) {
+	if order.Status != objects.PurchaseOrderSubmitted && order.Status != objects.PurchaseOrderPartiallyReceived {
+		return nil, nil, errors.ErrInvalidTransition.WithMessage("Only submitted purchase orders can be received, this one is " + order.Status)
+	}
+	byLine := make(map[int]*objects.PurchaseOrderLine, len(order.Lines))
+	for _, l := range order.Lines {
+		byLine[l.Line] = l
+	}
+	var changed []*objects.PurchaseOrderLine
+	received := map[string]int{}
+	for i, r := range in.Lines {
+		l, ok := byLine[r.Line]
+		if !ok {
+			return nil, nil, errors.ErrValidation.WithField("lines", "unknown_line", "the order has no such line")
+		}
+		if err := checkQuantity(r.Quantity, fmt.Sprintf("lines[%d].quantity", i)); err != nil {
+			return nil, nil, err
+		}
+		if r.Quantity > l.Outstanding() {
+			return nil, nil, errors.ErrOverReceipt.WithField("lines", "over_receipt", "line exceeds its outstanding quantity")
+		}
+		if !containsLine(changed, l) {
+			changed = append(changed, l)
+		}
+		l.Received += r.Quantity
+		received[l.StockID] += r.Quantity
+	}
+	order.Status = objects.PurchaseOrderReceived
+	for _, l := range order.Lines {
+		if l.Outstanding() > 0 {
+			order.Status = objects.PurchaseOrderPartiallyReceived
+			break
+		}
+	}
+	order.UpdatedOn = now
+	return changed, received, nil
+}
+
+func containsLine(lines []*objects.PurchaseOrderLine, l *objects.PurchaseOrderLine) bool {
+	for _, c := range lines {
+		if c == l {
+			return true
+		}
+	}
+	return false
+}
+
+// lineStockIDs returns the stocks ordered by in
+func lineStockIDs(in *objects.CreatePurchaseOrderRequest) []string {
+	ids := make([]string, 0, len(in.Lines))
+	for _, l := range in.Lines {
+		ids = append(ids, l.StockID)
+	}
+	return ids
+}
+
+// missingStock returns errors.ErrStockNotFound naming the first of ids not in found
+func missingStock(ids, found []string) error {
+	set := make(map[string]bool, len(found))
+	for _, id := range found {
+		set[id] = true
+	}
+	for _, id := range ids {
+		if !set[id] {
+			return errors.ErrStockNotFound.WithMessage("Stock " + id + " not found")
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of m in order, so that rows are always locked in the same order
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,223 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+)
+
+// IReturnsStore is the database interface for customer returns
+type IReturnsStore interface {
+	// CreateReturn opens a return, its stocks and sales order must exist
+	CreateReturn(ctx context.Context, in *objects.CreateReturnRequest) (*objects.Return, error)
+	GetReturn(ctx context.Context, in *objects.GetRequest) (*objects.Return, error)
+	ListReturns(ctx context.Context, in *objects.ListReturnsRequest) ([]*objects.Return, error)
+	// InspectReturn records inspection outcomes, posts a movement for each of them
+	// and adds the restocked units to availability, in one transaction
+	InspectReturn(ctx context.Context, in *objects.InspectReturnRequest) (*objects.Return, []*objects.StockMovement, error)
+}
+
+// IMovementStore is the database interface for stock movements
+type IMovementStore interface {
+	ListMovements(ctx context.Context, in *objects.ListMovementsRequest) ([]*objects.StockMovement, error)
+}
+
+// outcomeMovements movement type of each inspection outcome
+var outcomeMovements = map[string]string{
+	objects.OutcomeRestock:   objects.MovementReturnRestock,
+	objects.OutcomeRefurbish: objects.MovementReturnRefurbish,
+	objects.OutcomeScrap:     objects.MovementReturnScrap,
+}
+
+// newReturn returns the open return of in
+func newReturn(tenantID string, in *objects.CreateReturnRequest, now time.Time) (*objects.Return, error) {
+	ret := &objects.Return{
+		ID:           GenerateUniqueID(),
+		TenantID:     tenantID,
+		SalesOrderID: in.SalesOrderID,
+		Reason:       in.Reason,
+		Status:       objects.ReturnOpen,
+		Lines:        make([]*objects.ReturnLine, 0, len(in.Lines)),
+		CreatedOn:    now,
+		UpdatedOn:    now,
+	}
+	for i, l := range in.Lines {
+		if err := checkQuantity(l.Quantity, fmt.Sprintf("lines[%d].quantity", i)); err != nil {
+			return nil, err
+		}
+		ret.Lines = append(ret.Lines, &objects.ReturnLine{
+			ReturnID: ret.ID,
+			Line:     i + 1,
+			TenantID: tenantID,
+			StockID:  l.StockID,
+			Quantity: l.Quantity,
+		})
+	}
+	return ret, nil
+}
+
+// returnStockIDs returns the stocks returned by in
+func returnStockIDs(in *objects.CreateReturnRequest) []string {
+	ids := make([]string, 0, len(in.Lines))
+	for _, l := range in.Lines {
+		ids = append(ids, l.StockID)
+	}
+	return ids
+}
+
+// inspectStockIDs returns the stocks of the lines of ret inspected by in
+func inspectStockIDs(ret *objects.Return, in *objects.InspectReturnRequest) []string {
+	stocks := map[string]int{}
+	for _, r := range in.Lines {
+		for _, l := range ret.Lines {
+			if l.Line == r.Line {
+				stocks[l.StockID]++
+			}
+		}
+	}
+	return sortedKeys(stocks)
+}
+
+// inspectedSerials returns the serial numbers named by in
+func inspectedSerials(in *objects.InspectReturnRequest) []string {
+	var numbers []string
+	for _, r := range in.Lines {
+		numbers = append(numbers, r.Serials...)
+	}
+	return numbers
+}
+
+// inspect applies the outcomes of in to the lines of ret, whose stocks are given by id,
+// and updates its status, it returns the changed lines, the movements to post and the
+// units to restock by stock
+func inspect(ret *objects.Return, in *objects.InspectReturnRequest, stocks map[string]*objects.Stock, now time.Time) ([]*objects.ReturnLine, []*objects.StockMovement, map[string]int, error) {
+	if ret.Status == objects.ReturnCompleted {
+		return nil, nil, nil, errors.ErrInvalidTransition.WithMessage("Every unit of the return is already inspected")
+	}
+	byLine := make(map[int]*objects.ReturnLine, len(ret.Lines))
+	for _, l := range ret.Lines {
+		byLine[l.Line] = l
+	}
+	var (
+		changed   []*objects.ReturnLine
+		movements []*objects.StockMovement
+	)
+	restock := map[string]int{}
+	for i, r := range in.Lines {
+		l, ok := byLine[r.Line]
+		if !ok {
+			return nil, nil, nil, errors.ErrValidation.WithField("lines", "unknown_line", "the return has no such line")
+		}
+		if err := checkQuantity(r.Quantity, fmt.Sprintf("lines[%d].quantity", i)); err != nil {
+			return nil, nil, nil, err
+		}
+		typ, ok := outcomeMovements[r.Outcome]
+		if !ok {
+			return nil, nil, nil, errors.ErrValidation.WithField("lines", "unknown_outcome", "unknown outcome "+r.Outcome)
+		}
+		if r.Quantity > l.Uninspected() {
+			return nil, nil, nil, errors.ErrOverInspection.WithField("lines", "over_inspection", "line exceeds its uninspected quantity")
+		}
+		m := &objects.StockMovement{
+			TenantID:  ret.TenantID,
+			StockID:   l.StockID,
+			Type:      typ,
+			Quantity:  r.Quantity,
+			Reference: ret.ID,
+			Line:      l.Line,
+			CreatedOn: now,
+		}
+		switch r.Outcome {
+		case objects.OutcomeRestock:
+			s, ok := stocks[l.StockID]
+			if !ok {
+				return nil, nil, nil, errors.ErrStockNotFound.WithMessage("Stock " + l.StockID + " not found")
+			}
+			l.Restocked += r.Quantity
+			if !s.Serialized {
+				// serialized units are counted by the movements of their serials
+				m.AvailabilityDelta = r.Quantity
+			}
+			restock[l.StockID] += r.Quantity
+		case objects.OutcomeRefurbish:
+			l.Refurbished += r.Quantity
+		case objects.OutcomeScrap:
+			l.Scrapped += r.Quantity
+		}
+		movements = append(movements, m)
+		if !containsReturnLine(changed, l) {
+			changed = append(changed, l)
+		}
+	}
+	ret.Status = objects.ReturnCompleted
+	for _, l := range ret.Lines {
+		if l.Uninspected() > 0 {
+			ret.Status = objects.ReturnPartiallyInspected
+			break
+		}
+	}
+	ret.UpdatedOn = now
+	return changed, movements, restock, nil
+}
+
+// restockedSerials puts the serials restocked by in back in stock and returns their
+// movements, stocks and serials are given by id and by number. Restocked lines of
+// serialized stocks need one distinct serial number by unit, sold by the order of ret
+// when it has one, the other lines none.
+func restockedSerials(ret *objects.Return, in *objects.InspectReturnRequest, stocks map[string]*objects.Stock, serials map[string]*objects.Serial, now time.Time) ([]*objects.Serial, []*objects.StockMovement, error) {
+	byLine := make(map[int]*objects.ReturnLine, len(ret.Lines))
+	for _, l := range ret.Lines {
+		byLine[l.Line] = l
+	}
+	var (
+		restocked []*objects.Serial
+		movements []*objects.StockMovement
+	)
+	seen := map[string]bool{}
+	for i, r := range in.Lines {
+		field := fmt.Sprintf("lines[%d].serials", i)
+		l, ok := byLine[r.Line]
+		if !ok {
+			// reported by inspect
+			continue
+		}
+		s := stocks[l.StockID]
+		if r.Outcome != objects.OutcomeRestock || s == nil || !s.Serialized {
+			if len(r.Serials) > 0 {
+				return nil, nil, errors.ErrValidation.WithField(field, "not_serialized", "only restocked units of serialized stocks name their serials")
+			}
+			continue
+		}
+		if len(r.Serials) != r.Quantity {
+			return nil, nil, errors.ErrSerialsRequired.WithField(field, "serials_required",
+				fmt.Sprintf("%d serial numbers for %d units", len(r.Serials), r.Quantity))
+		}
+		for _, sn := range r.Serials {
+			serial, ok := serials[sn]
+			if !ok || seen[sn] || serial.StockID != s.ID || serial.Status != objects.SerialAllocated ||
+				(ret.SalesOrderID != "" && serial.OrderID != ret.SalesOrderID) {
+				return nil, nil, errors.ErrValidation.WithField(field, "serial_not_sold", "serial "+sn+" was not sold")
+			}
+			seen[sn] = true
+			serial.Status = objects.SerialInStock
+			serial.OrderID = ""
+			serial.Line = 0
+			serial.UpdatedOn = now
+			restocked = append(restocked, serial)
+			movements = append(movements, serialMovement(serial, objects.MovementSerialReturn, 1, ret.ID, l.Line, now))
+		}
+	}
+	return restocked, movements, nil
+}
+
+func containsReturnLine(lines []*objects.ReturnLine, l *objects.ReturnLine) bool {
+	for _, c := range lines {
+		if c == l {
+			return true
+		}
+	}
+	return false
+}
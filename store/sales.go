@@ -0,0 +1,198 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+)
+
+// ISalesStore is the database interface for sales orders
+type ISalesStore interface {
+	// CreateSalesOrder allocates every line of in against the availability of its stock
+	// or fails with errors.ErrAllocationFailed listing the shortfall of each line
+	CreateSalesOrder(ctx context.Context, in *objects.CreateSalesOrderRequest) (*objects.SalesOrder, error)
+	GetSalesOrder(ctx context.Context, in *objects.GetRequest) (*objects.SalesOrder, error)
+	ListSalesOrders(ctx context.Context, in *objects.ListSalesOrdersRequest) ([]*objects.SalesOrder, error)
+	// CancelSalesOrder cancels an order and gives its allocated quantities back to availability
+	CancelSalesOrder(ctx context.Context, in *objects.CancelSalesOrderRequest) (*objects.SalesOrder, error)
+}
+
+// salesStockIDs returns the distinct stocks of in, in order
+func salesStockIDs(in *objects.CreateSalesOrderRequest) []string {
+	demand := make(map[string]int, len(in.Lines))
+	for _, l := range in.Lines {
+		demand[l.StockID] += l.Quantity
+	}
+	return sortedKeys(demand)
+}
+
+// allocation units of a stock taken by an order
+type allocation struct {
+	// taken from availability
+	take int
+	// queued until a receipt
+	backorder int
+}
+
+// allocate checks the lines of in against stocks, by id, and returns the allocation of
+// each stock. Lines of the same stock share it, availability is taken first then, once
+// the stock has nothing left to promise, units are backordered within the stock limit.
+func allocate(in *objects.CreateSalesOrderRequest, stocks map[string]*objects.Stock) (map[string]*allocation, error) {
+	demand := make(map[string]int, len(in.Lines))
+	for i, l := range in.Lines {
+		if err := checkQuantity(l.Quantity, fmt.Sprintf("lines[%d].quantity", i)); err != nil {
+			return nil, err
+		}
+		demand[l.StockID] += l.Quantity
+	}
+	err := errors.ErrAllocationFailed
+	failed := false
+	for i, l := range in.Lines {
+		field := fmt.Sprintf("lines[%d]", i)
+		s, ok := stocks[l.StockID]
+		switch {
+		case !ok:
+			err = err.WithField(field+".stock_id", "stock_not_found", "stock "+l.StockID+" not found")
+		case !s.IsActive:
+			err = err.WithField(field+".stock_id", "stock_inactive", "stock "+l.StockID+" is not active")
+		case demand[l.StockID] > promisable(s)+s.BackorderRoom():
+			msg := fmt.Sprintf("%d requested, %d available", demand[l.StockID], promisable(s))
+			if s.Backorderable {
+				msg += fmt.Sprintf(", %d backorderable", s.BackorderRoom())
+			}
+			err = err.WithField(field+".quantity", "insufficient_stock", msg)
+		default:
+			continue
+		}
+		failed = true
+	}
+	if failed {
+		return nil, err
+	}
+	allocs := make(map[string]*allocation, len(demand))
+	for id, d := range demand {
+		take := d
+		if p := promisable(stocks[id]); take > p {
+			take = p
+		}
+		allocs[id] = &allocation{take: take, backorder: d - take}
+	}
+	return allocs, nil
+}
+
+// promisable units of s new orders may take, queued backorders are served first
+func promisable(s *objects.Stock) int {
+	if atp := s.AvailableToPromise(); atp > 0 {
+		return atp
+	}
+	return 0
+}
+
+// newSalesOrder returns the order of in, spreading allocs over its lines in order
+func newSalesOrder(tenantID string, in *objects.CreateSalesOrderRequest, allocs map[string]*allocation, stocks map[string]*objects.Stock, now time.Time) *objects.SalesOrder {
+	order := &objects.SalesOrder{
+		ID:        GenerateUniqueID(),
+		TenantID:  tenantID,
+		Reference: in.Reference,
+		BundleID:  in.BundleID,
+		Status:    objects.SalesOrderAllocated,
+		Lines:     make([]*objects.SalesOrderLine, 0, len(in.Lines)),
+		CreatedOn: now,
+		UpdatedOn: now,
+	}
+	left := make(map[string]int, len(allocs))
+	for id, a := range allocs {
+		left[id] = a.take
+	}
+	for i, l := range in.Lines {
+		take := l.Quantity
+		if take > left[l.StockID] {
+			take = left[l.StockID]
+		}
+		left[l.StockID] -= take
+		line := &objects.SalesOrderLine{
+			OrderID:     order.ID,
+			Line:        i + 1,
+			TenantID:    tenantID,
+			StockID:     l.StockID,
+			Quantity:    l.Quantity,
+			Allocated:   take,
+			Backordered: l.Quantity - take,
+		}
+		if line.Backordered > 0 {
+			line.ExpectedOn = stocks[l.StockID].RestockOn
+			order.Status = objects.SalesOrderBackordered
+		}
+		order.Lines = append(order.Lines, line)
+	}
+	return order
+}
+
+// cancel cancels order and returns, by stock, the units to give back to
+// availability and to remove from the backorders
+func cancel(order *objects.SalesOrder, now time.Time) (map[string]*allocation, error) {
+	if order.Status == objects.SalesOrderCancelled {
+		return nil, errors.ErrInvalidTransition.WithMessage("The sales order is already cancelled")
+	}
+	released := map[string]*allocation{}
+	for _, l := range order.Lines {
+		a, ok := released[l.StockID]
+		if !ok {
+			a = &allocation{}
+			released[l.StockID] = a
+		}
+		a.take += l.Allocated
+		a.backorder += l.Backordered
+		l.Allocated = 0
+		l.Backordered = 0
+		l.ExpectedOn = nil
+	}
+	order.Status = objects.SalesOrderCancelled
+	order.CancelledOn = &now
+	order.UpdatedOn = now
+	return released, nil
+}
+
+// fulfilment the units of a backordered line allocated by a receipt
+type fulfilment struct {
+	line *objects.SalesOrderLine
+	qty  int
+}
+
+// fulfil allocates the availability of s to its backordered lines, given
+// first come first served, and returns the lines it changed
+func fulfil(s *objects.Stock, lines []*objects.SalesOrderLine) []*fulfilment {
+	var changed []*fulfilment
+	for _, l := range lines {
+		if s.Availability <= 0 {
+			break
+		}
+		n := l.Backordered
+		if n > s.Availability {
+			n = s.Availability
+		}
+		l.Allocated += n
+		l.Backordered -= n
+		if l.Backordered == 0 {
+			l.ExpectedOn = nil
+		}
+		s.Availability -= n
+		s.Backordered -= n
+		changed = append(changed, &fulfilment{line: l, qty: n})
+	}
+	return changed
+}
+
+// allocKeys returns the stocks of allocs in order
+func allocKeys(allocs map[string]*allocation) []string {
+	keys := make([]string, 0, len(allocs))
+	for k := range allocs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
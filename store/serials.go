@@ -0,0 +1,197 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+)
+
+// ISerialStore is the database interface for the serial numbers of serialized stocks
+type ISerialStore interface {
+	// GetSerial returns a serial and its movements, oldest first
+	GetSerial(ctx context.Context, in *objects.GetSerialRequest) (*objects.Serial, []*objects.StockMovement, error)
+	ListSerials(ctx context.Context, in *objects.ListSerialsRequest) ([]*objects.Serial, error)
+}
+
+// checkSerialized rejects the changes of in which would break the serial count of old
+func checkSerialized(old *objects.Stock, in *objects.UpdateDetailsRequest) error {
+	if in.Serialized != old.Serialized && (old.Availability != 0 || old.Backordered != 0) {
+		return errors.ErrValidation.WithField("serialized", "stock_not_empty", "only changes while the stock is empty")
+	}
+	if (old.Serialized || in.Serialized) && in.Availability != old.Availability {
+		return errors.ErrSerialsRequired.WithField("availability", "serials_required", "receive or sell serial numbers instead")
+	}
+	return nil
+}
+
+// checkUnserialized rejects changing the availability of s without serial numbers
+func checkUnserialized(s *objects.Stock) error {
+	if s.Serialized {
+		return errors.ErrSerialsRequired.WithMessage("Stock " + s.ID + " is serialized, its units are received with serial numbers")
+	}
+	return nil
+}
+
+// serialMovement returns the movement of s for the line of a document
+func serialMovement(s *objects.Serial, typ string, delta int, reference string, line int, now time.Time) *objects.StockMovement {
+	return &objects.StockMovement{
+		TenantID:          s.TenantID,
+		StockID:           s.StockID,
+		Type:              typ,
+		Quantity:          1,
+		AvailabilityDelta: delta,
+		Reference:         reference,
+		Line:              line,
+		SerialNumber:      s.SerialNumber,
+		CreatedOn:         now,
+	}
+}
+
+// receivedSerials returns the serials received by in on the lines of order, whose
+// stocks are given by id, with their movements. Lines of serialized stocks need one
+// distinct serial number by unit, the other lines none.
+func receivedSerials(order *objects.PurchaseOrder, in *objects.ReceivePurchaseOrderRequest, stocks map[string]*objects.Stock, now time.Time) ([]*objects.Serial, []*objects.StockMovement, error) {
+	byLine := make(map[int]*objects.PurchaseOrderLine, len(order.Lines))
+	for _, l := range order.Lines {
+		byLine[l.Line] = l
+	}
+	var (
+		serials   []*objects.Serial
+		movements []*objects.StockMovement
+	)
+	seen := map[string]bool{}
+	for i, r := range in.Lines {
+		field := fmt.Sprintf("lines[%d].serials", i)
+		l := byLine[r.Line]
+		s, ok := stocks[l.StockID]
+		if !ok {
+			return nil, nil, errors.ErrStockNotFound.WithMessage("Stock " + l.StockID + " not found")
+		}
+		if !s.Serialized {
+			if len(r.Serials) > 0 {
+				return nil, nil, errors.ErrValidation.WithField(field, "not_serialized", "stock "+s.ID+" is not serialized")
+			}
+			continue
+		}
+		if len(r.Serials) != r.Quantity {
+			return nil, nil, errors.ErrSerialsRequired.WithField(field, "serials_required",
+				fmt.Sprintf("%d serial numbers for %d units", len(r.Serials), r.Quantity))
+		}
+		for _, sn := range r.Serials {
+			if sn == "" || seen[sn] {
+				return nil, nil, errors.ErrValidation.WithField(field, "invalid_serial", "serial numbers must be distinct and not empty")
+			}
+			seen[sn] = true
+			serial := &objects.Serial{
+				TenantID:     order.TenantID,
+				SerialNumber: sn,
+				StockID:      s.ID,
+				Status:       objects.SerialInStock,
+				CreatedOn:    now,
+				UpdatedOn:    now,
+			}
+			serials = append(serials, serial)
+			movements = append(movements, serialMovement(serial, objects.MovementSerialReceipt, 1, order.ID, l.Line, now))
+		}
+	}
+	return serials, movements, nil
+}
+
+// serialNumbers returns the serial numbers of serials
+func serialNumbers(serials []*objects.Serial) []string {
+	numbers := make([]string, 0, len(serials))
+	for _, s := range serials {
+		numbers = append(numbers, s.SerialNumber)
+	}
+	return numbers
+}
+
+// requestedSerials returns the serial numbers sold by in
+func requestedSerials(in *objects.CreateSalesOrderRequest) []string {
+	var numbers []string
+	for _, l := range in.Lines {
+		numbers = append(numbers, l.Serials...)
+	}
+	return numbers
+}
+
+// checkSerials fails with errors.ErrAllocationFailed listing the lines of in whose serial
+// numbers do not match their stock, serials holds the known ones by number
+func checkSerials(in *objects.CreateSalesOrderRequest, stocks map[string]*objects.Stock, serials map[string]*objects.Serial) error {
+	err := errors.ErrAllocationFailed
+	failed := false
+	seen := map[string]bool{}
+	for i, l := range in.Lines {
+		field := fmt.Sprintf("lines[%d].serials", i)
+		s, ok := stocks[l.StockID]
+		if !ok {
+			// reported by allocate
+			continue
+		}
+		switch {
+		case !s.Serialized && len(l.Serials) > 0:
+			err = err.WithField(field, "not_serialized", "stock "+s.ID+" is not serialized")
+			failed = true
+		case s.Serialized && len(l.Serials) != l.Quantity:
+			err = err.WithField(field, "serials_required", fmt.Sprintf("%d serial numbers for %d units", len(l.Serials), l.Quantity))
+			failed = true
+		}
+		for _, sn := range l.Serials {
+			serial, ok := serials[sn]
+			if !ok || seen[sn] || serial.StockID != s.ID || serial.Status != objects.SerialInStock {
+				err = err.WithField(field, "serial_unavailable", "serial "+sn+" is not in stock")
+				failed = true
+			}
+			seen[sn] = true
+		}
+	}
+	if failed {
+		return err
+	}
+	return nil
+}
+
+// sellSerials allocates the serials of in to the lines of order and returns their movements
+func sellSerials(order *objects.SalesOrder, in *objects.CreateSalesOrderRequest, serials map[string]*objects.Serial, now time.Time) []*objects.StockMovement {
+	var movements []*objects.StockMovement
+	for i, l := range in.Lines {
+		for _, sn := range l.Serials {
+			s := serials[sn]
+			s.Status = objects.SerialAllocated
+			s.OrderID = order.ID
+			s.Line = i + 1
+			s.UpdatedOn = now
+			movements = append(movements, serialMovement(s, objects.MovementSerialAllocation, -1, order.ID, s.Line, now))
+		}
+	}
+	return movements
+}
+
+// releaseSerials puts the serials of a cancelled order back in stock and returns their movements
+func releaseSerials(serials []*objects.Serial, now time.Time) []*objects.StockMovement {
+	movements := make([]*objects.StockMovement, 0, len(serials))
+	for _, s := range serials {
+		movements = append(movements, serialMovement(s, objects.MovementSerialRelease, 1, s.OrderID, s.Line, now))
+		s.Status = objects.SerialInStock
+		s.OrderID = ""
+		s.Line = 0
+		s.UpdatedOn = now
+	}
+	return movements
+}
+
+// sortedSerials returns serials ordered by number
+func sortedSerials(serials map[string]*objects.Serial) []*objects.Serial {
+	numbers := make(map[string]int, len(serials))
+	for sn := range serials {
+		numbers[sn]++
+	}
+	list := make([]*objects.Serial, 0, len(serials))
+	for _, sn := range sortedKeys(numbers) {
+		list = append(list, serials[sn])
+	}
+	return list
+}
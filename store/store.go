@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"net/http"
 	"time"
 
+	"go-inventory/errors"
 	"go-inventory/objects"
 )
 
@@ -15,6 +17,39 @@ type IStockStore interface {
 	List(ctx context.Context, in *objects.ListRequest) ([]*objects.Stock, error)
 	Create(ctx context.Context, in *objects.CreateRequest) error
 	UpdateDetails(ctx context.Context, in *objects.UpdateDetailsRequest) error
+	// BatchGet returns the stocks found among in.IDs, in the order of in.IDs
+	BatchGet(ctx context.Context, in *objects.BatchGetRequest) ([]*objects.Stock, error)
+	// BatchUpdate applies every item in one transaction and returns one result by item.
+	// In atomic mode the first failure rolls every item back.
+	BatchUpdate(ctx context.Context, in *objects.BatchUpdateRequest) ([]*objects.BatchUpdateResult, error)
+	// ListLow returns the stocks below the in.Level threshold, ordered by id
+	ListLow(ctx context.Context, in *objects.ListLowRequest) ([]*objects.Stock, error)
+	// ListEvents returns the change log of stocks, oldest first
+	ListEvents(ctx context.Context, in *objects.ListEventsRequest) ([]*objects.StockEvent, error)
+	// Transaction runs fn as a unit of work, the changes made through tx are
+	// committed together, with their change log and outbox messages, when fn
+	// returns nil and rolled back otherwise
+	Transaction(ctx context.Context, fn func(tx IStockStore) error) error
+}
+
+// Stores every store of the api, sharing one database so that
+// they can commit together
+type Stores struct {
+	Stocks      IStockStore
+	Webhooks    IWebhookStore
+	Outbox      IOutboxStore
+	Purchasing  IPurchasingStore
+	Sales       ISalesStore
+	Returns     IReturnsStore
+	Movements   IMovementStore
+	Lots        ILotStore
+	Serials     ISerialStore
+	Products    IProductStore
+	Bundles     IBundleStore
+	Units       IUnitStore
+	Identifiers IIdentifierStore
+	Categories  ICategoryStore
+	Counts      ICountStore
 }
 
 func init() {
@@ -30,3 +65,70 @@ func GenerateUniqueID() string {
 	now := time.Now().UTC()
 	return fmt.Sprintf("%010v-%010v-%s", now.Unix(), now.Nanosecond(), string(word))
 }
+
+// inOrder returns the stocks of list in the order of ids, skipping missing ones
+func inOrder(list []*objects.Stock, ids []string) []*objects.Stock {
+	byID := make(map[string]*objects.Stock, len(list))
+	for _, evt := range list {
+		byID[evt.ID] = evt
+	}
+	res := make([]*objects.Stock, 0, len(list))
+	for _, id := range ids {
+		if evt, ok := byID[id]; ok {
+			res = append(res, evt)
+			// report duplicated ids once
+			delete(byID, id)
+		}
+	}
+	return res
+}
+
+// checkNewStock rejects serialized stocks created with availability, variants
+// created without their product and invalid units or tags, it defaults the base
+// unit and normalizes the barcode and the tags
+func checkNewStock(s *objects.Stock) error {
+	if s.ProductID != "" || len(s.Attributes) > 0 {
+		return errors.ErrValidation.WithField("product_id", "use_variants", "variants are created with their product")
+	}
+	if s.Serialized && s.Availability != 0 {
+		return errors.ErrSerialsRequired.WithField("availability", "serials_required", "serialized stocks are created empty")
+	}
+	s.BaseUnit = s.Unit()
+	s.GTIN = gtin14(s.Barcode)
+	tags, err := normalizeTags(s.Tags)
+	if err != nil {
+		return err
+	}
+	s.Tags = tags
+	return checkUnits(s.BaseUnit, s.Units)
+}
+
+// checkQuantity rejects qty unless it is between one unit and objects.MaxQuantity,
+// field names it in errors, stores are called without validation too
+func checkQuantity(qty int, field string) error {
+	if qty < 1 {
+		return errors.ErrValidation.WithField(field, "gt", "must be greater than 0")
+	}
+	if qty > objects.MaxQuantity {
+		return errors.ErrValidation.WithField(field, "lte", fmt.Sprintf("must be less than or equal to %d", objects.MaxQuantity))
+	}
+	return nil
+}
+
+// batchResult returns the result of a batch item which ended with err
+func batchResult(id string, err error) *objects.BatchUpdateResult {
+	if err == nil {
+		return &objects.BatchUpdateResult{ID: id, Status: http.StatusOK}
+	}
+	e := errors.From(err)
+	return &objects.BatchUpdateResult{ID: id, Status: e.StatusCode(), Error: e}
+}
+
+// abortBatch marks every item of in but the failed one as aborted
+func abortBatch(in *objects.BatchUpdateRequest, results []*objects.BatchUpdateResult, failed int) {
+	for i, item := range in.Items {
+		if i != failed {
+			results[i] = batchResult(item.ID, errors.ErrBatchAborted)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+
+	"go-inventory/objects"
+	"go-inventory/util/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+type traced struct {
+	next IStockStore
+}
+
+// NewTracedStockStore wraps store so that every call is recorded as a span
+func NewTracedStockStore(store IStockStore) IStockStore {
+	return &traced{next: store}
+}
+
+func (t *traced) Get(ctx context.Context, in *objects.GetRequest) (*objects.Stock, error) {
+	ctx, span := startSpan(ctx, "IStockStore.Get", attribute.String("stock.id", in.ID))
+	defer span.End()
+	evt, err := t.next.Get(ctx, in)
+	return evt, endSpan(span, err)
+}
+
+func (t *traced) List(ctx context.Context, in *objects.ListRequest) ([]*objects.Stock, error) {
+	ctx, span := startSpan(ctx, "IStockStore.List",
+		attribute.Int("list.limit", in.Limit),
+		attribute.String("list.after", in.After),
+	)
+	defer span.End()
+	list, err := t.next.List(ctx, in)
+	span.SetAttributes(attribute.Int("list.size", len(list)))
+	return list, endSpan(span, err)
+}
+
+func (t *traced) Create(ctx context.Context, in *objects.CreateRequest) error {
+	ctx, span := startSpan(ctx, "IStockStore.Create")
+	defer span.End()
+	err := t.next.Create(ctx, in)
+	if err == nil && in.Stock != nil {
+		span.SetAttributes(attribute.String("stock.id", in.Stock.ID))
+	}
+	return endSpan(span, err)
+}
+
+func (t *traced) UpdateDetails(ctx context.Context, in *objects.UpdateDetailsRequest) error {
+	ctx, span := startSpan(ctx, "IStockStore.UpdateDetails", attribute.String("stock.id", in.ID))
+	defer span.End()
+	return endSpan(span, t.next.UpdateDetails(ctx, in))
+}
+
+func (t *traced) BatchGet(ctx context.Context, in *objects.BatchGetRequest) ([]*objects.Stock, error) {
+	ctx, span := startSpan(ctx, "IStockStore.BatchGet", attribute.Int("batch.size", len(in.IDs)))
+	defer span.End()
+	list, err := t.next.BatchGet(ctx, in)
+	span.SetAttributes(attribute.Int("batch.found", len(list)))
+	return list, endSpan(span, err)
+}
+
+func (t *traced) BatchUpdate(ctx context.Context, in *objects.BatchUpdateRequest) ([]*objects.BatchUpdateResult, error) {
+	ctx, span := startSpan(ctx, "IStockStore.BatchUpdate",
+		attribute.Int("batch.size", len(in.Items)),
+		attribute.Bool("batch.atomic", in.Atomic),
+	)
+	defer span.End()
+	results, err := t.next.BatchUpdate(ctx, in)
+	return results, endSpan(span, err)
+}
+
+func (t *traced) ListLow(ctx context.Context, in *objects.ListLowRequest) ([]*objects.Stock, error) {
+	ctx, span := startSpan(ctx, "IStockStore.ListLow",
+		attribute.String("low.level", in.Level),
+		attribute.Int("list.limit", in.Limit),
+	)
+	defer span.End()
+	list, err := t.next.ListLow(ctx, in)
+	span.SetAttributes(attribute.Int("list.size", len(list)))
+	return list, endSpan(span, err)
+}
+
+func (t *traced) ListEvents(ctx context.Context, in *objects.ListEventsRequest) ([]*objects.StockEvent, error) {
+	ctx, span := startSpan(ctx, "IStockStore.ListEvents", attribute.Int64("events.after", in.After))
+	defer span.End()
+	list, err := t.next.ListEvents(ctx, in)
+	span.SetAttributes(attribute.Int("events.size", len(list)))
+	return list, endSpan(span, err)
+}
+
+func (t *traced) Transaction(ctx context.Context, fn func(tx IStockStore) error) error {
+	ctx, span := startSpan(ctx, "IStockStore.Transaction")
+	defer span.End()
+	return endSpan(span, t.next.Transaction(ctx, func(tx IStockStore) error {
+		return fn(&traced{next: tx})
+	}))
+}
+
+func startSpan(ctx context.Context, name string, kv ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, name, trace.WithAttributes(kv...))
+}
+
+// endSpan records err on span and returns it unchanged
+func endSpan(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// gormTracing is a gorm plugin creating a client span for every statement
+type gormTracing struct{}
+
+func (gormTracing) Name() string { return "tracing" }
+
+func (gormTracing) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		name   string
+		before func(string, func(*gorm.DB)) error
+		after  func(string, func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+	for _, h := range hooks {
+		if err := h.before("tracing:before_"+h.name, beforeStatement(h.name)); err != nil {
+			return err
+		}
+		if err := h.after("tracing:after_"+h.name, afterStatement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func beforeStatement(op string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		ctx, _ = tracing.Tracer().Start(ctx, "gorm."+op,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemPostgreSQL,
+				semconv.DBOperationKey.String(op),
+			),
+		)
+		db.Statement.Context = ctx
+	}
+}
+
+func afterStatement(db *gorm.DB) {
+	span := trace.SpanFromContext(db.Statement.Context)
+	if !span.IsRecording() {
+		return
+	}
+	span.SetAttributes(
+		semconv.DBStatementKey.String(db.Statement.SQL.String()),
+		semconv.DBSQLTableKey.String(db.Statement.Table),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if err := db.Error; err != nil && err != gorm.ErrRecordNotFound {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,152 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+)
+
+// maxFactor largest count of base units in one unit
+const maxFactor = 1000000
+
+// IUnitStore is the database interface for the units of measure of stocks
+type IUnitStore interface {
+	// SetUnits replaces the base unit and the conversion units of a stock
+	SetUnits(ctx context.Context, in *objects.SetUnitsRequest) (*objects.Stock, error)
+}
+
+// checkUnits validates the conversion units of a stock counted in base
+func checkUnits(base string, units map[string]int) error {
+	if len(units) > objects.MaxUnits {
+		return errors.ErrValidation.WithField("units", "too_many_units", fmt.Sprintf("at most %d units", objects.MaxUnits))
+	}
+	for name, factor := range units {
+		field := "units." + name
+		switch {
+		case name == "" || len(name) > 32:
+			return errors.ErrValidation.WithField(field, "invalid_unit", "unit names have 1 to 32 characters")
+		case name == base:
+			return errors.ErrValidation.WithField(field, "base_unit", "the base unit is not converted")
+		case factor < 1 || factor > maxFactor:
+			return errors.ErrValidation.WithField(field, "invalid_factor", fmt.Sprintf("a unit is 1 to %d base units", maxFactor))
+		}
+	}
+	return nil
+}
+
+// setUnits applies in to s, the base unit only changes while s is empty
+func setUnits(s *objects.Stock, in *objects.SetUnitsRequest) error {
+	if err := checkUnits(in.BaseUnit, in.Units); err != nil {
+		return err
+	}
+	if in.BaseUnit != s.Unit() && (s.Availability != 0 || s.Backordered != 0) {
+		return errors.ErrValidation.WithField("base_unit", "stock_not_empty", "only changes while the stock is empty")
+	}
+	s.BaseUnit = in.BaseUnit
+	s.Units = in.Units
+	return nil
+}
+
+// toBase converts qty of unit to base units of s, at most objects.MaxQuantity, field
+// names the quantity in errors
+func toBase(s *objects.Stock, unit string, qty int, field string) (int, error) {
+	factor, ok := s.Factor(unit)
+	if !ok {
+		return 0, errors.ErrValidation.WithField(field+".unit", "unknown_unit", "stock "+s.ID+" has no unit "+unit)
+	}
+	// bounded like the quantities of requests, the product cannot overflow
+	if qty > objects.MaxQuantity/factor {
+		return 0, errors.ErrValidation.WithField(field, "lte", fmt.Sprintf("must be at most %d %s", objects.MaxQuantity, s.Unit()))
+	}
+	return qty * factor, nil
+}
+
+// salesUnits converts the lines of in to base units, lines of missing stocks are left
+// to the allocation, a converted line has no unit anymore
+func salesUnits(in *objects.CreateSalesOrderRequest, stocks map[string]*objects.Stock) error {
+	for i, l := range in.Lines {
+		s, ok := stocks[l.StockID]
+		if !ok || l.Unit == "" {
+			continue
+		}
+		qty, err := toBase(s, l.Unit, l.Quantity, fmt.Sprintf("lines[%d]", i))
+		if err != nil {
+			return err
+		}
+		l.Quantity, l.Unit = qty, ""
+	}
+	return nil
+}
+
+// purchaseUnits converts the lines of in of stocks to base units, unit costs included
+func purchaseUnits(in *objects.CreatePurchaseOrderRequest, stocks map[string]*objects.Stock) error {
+	for i, l := range in.Lines {
+		s, ok := stocks[l.StockID]
+		if !ok || l.Unit == "" {
+			continue
+		}
+		qty, err := toBase(s, l.Unit, l.Quantity, fmt.Sprintf("lines[%d]", i))
+		if err != nil {
+			return err
+		}
+		l.UnitCost = l.UnitCost * float64(l.Quantity) / float64(qty)
+		l.Quantity, l.Unit = qty, ""
+	}
+	return nil
+}
+
+// receivedStockIDs returns the stocks of the lines of order received by in, sorted
+func receivedStockIDs(order *objects.PurchaseOrder, in *objects.ReceivePurchaseOrderRequest) []string {
+	received := map[string]int{}
+	for _, r := range in.Lines {
+		for _, l := range order.Lines {
+			if l.Line == r.Line {
+				received[l.StockID]++
+			}
+		}
+	}
+	return sortedKeys(received)
+}
+
+// receiveUnits converts the lines of in to base units, unknown lines and stocks are
+// left to the receipt
+func receiveUnits(order *objects.PurchaseOrder, in *objects.ReceivePurchaseOrderRequest, stocks map[string]*objects.Stock) error {
+	byLine := make(map[int]*objects.PurchaseOrderLine, len(order.Lines))
+	for _, l := range order.Lines {
+		byLine[l.Line] = l
+	}
+	for i, r := range in.Lines {
+		l, ok := byLine[r.Line]
+		if !ok || r.Unit == "" {
+			continue
+		}
+		s, ok := stocks[l.StockID]
+		if !ok {
+			continue
+		}
+		qty, err := toBase(s, r.Unit, r.Quantity, fmt.Sprintf("lines[%d]", i))
+		if err != nil {
+			return err
+		}
+		r.Quantity, r.Unit = qty, ""
+	}
+	return nil
+}
+
+// returnUnits converts the lines of in of stocks to base units
+func returnUnits(in *objects.CreateReturnRequest, stocks map[string]*objects.Stock) error {
+	for i, l := range in.Lines {
+		s, ok := stocks[l.StockID]
+		if !ok || l.Unit == "" {
+			continue
+		}
+		qty, err := toBase(s, l.Unit, l.Quantity, fmt.Sprintf("lines[%d]", i))
+		if err != nil {
+			return err
+		}
+		l.Quantity, l.Unit = qty, ""
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go-inventory/objects"
+)
+
+// IWebhookStore is the database interface for webhook subscriptions and their deliveries.
+// Deliveries are enqueued by the stock store, in the transaction of the change.
+type IWebhookStore interface {
+	CreateWebhook(ctx context.Context, in *objects.WebhookSubscription) error
+	ListWebhooks(ctx context.Context) ([]*objects.WebhookSubscription, error)
+	DeleteWebhook(ctx context.Context, in *objects.DeleteRequest) error
+	ListDeliveries(ctx context.Context, in *objects.ListDeliveriesRequest) ([]*objects.WebhookDelivery, error)
+	// Redeliver schedules a delivery, usually a dead one, for an immediate new attempt,
+	// with the full retry budget of a new delivery
+	Redeliver(ctx context.Context, in *objects.RedeliverRequest) (*objects.WebhookDelivery, error)
+	// ClaimDeliveries leases up to limit due deliveries of every tenant,
+	// they are not claimed again before the lease expires
+	ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*objects.WebhookDelivery, error)
+	// SaveDelivery records the outcome of an attempt
+	SaveDelivery(ctx context.Context, in *objects.WebhookDelivery) error
+}
+
+// newDeliveries returns the deliveries of events to the matching subscriptions
+func newDeliveries(subs []*objects.WebhookSubscription, events []*objects.StockEvent, now time.Time) []*objects.WebhookDelivery {
+	var res []*objects.WebhookDelivery
+	for _, e := range events {
+		payload, _ := json.Marshal(e)
+		for _, s := range subs {
+			if !s.Matches(e.Type) {
+				continue
+			}
+			res = append(res, &objects.WebhookDelivery{
+				ID:             GenerateUniqueID(),
+				TenantID:       e.TenantID,
+				SubscriptionID: s.ID,
+				EventSeq:       e.Seq,
+				EventType:      e.Type,
+				Payload:        string(payload),
+				Status:         objects.DeliveryPending,
+				NextAttemptOn:  now,
+				CreatedOn:      now,
+				UpdatedOn:      now,
+			})
+		}
+	}
+	return res
+}
+
+// redeliver schedules d for an immediate attempt, forgetting the past ones
+func redeliver(d *objects.WebhookDelivery, now time.Time) {
+	d.Status = objects.DeliveryPending
+	d.Attempts = 0
+	d.LastError = ""
+	d.NextAttemptOn = now
+	d.UpdatedOn = now
+}
@@ -0,0 +1,62 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"go-inventory/auth"
+	"go-inventory/errors"
+)
+
+// Header request header selecting the tenant
+const Header = "X-Tenant-ID"
+
+// DefaultID tenant used when none is resolved, single storefront deployments
+// only ever see this one
+const DefaultID = "default"
+
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx scoped to tenant id
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant of ctx, DefaultID if there is none
+func FromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(contextKey{}).(string); ok && id != "" {
+		return id
+	}
+	return DefaultID
+}
+
+// Middleware resolves the tenant of the request, the one the principal is bound to.
+// Only principals of auth.AnyTenant pick theirs with the `X-Tenant-ID` header, a
+// header contradicting the tenant of any other caller is rejected.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if id != "" && !validID.MatchString(id) {
+			errors.ErrInvalidTenant.Write(w)
+			return
+		}
+		bound := DefaultID
+		if p, ok := auth.FromContext(r.Context()); ok && p.Tenant != "" {
+			bound = p.Tenant
+		}
+		switch {
+		case bound != auth.AnyTenant:
+			if id != "" && id != bound {
+				errors.ErrTenantMismatch.Write(w)
+				return
+			}
+			id = bound
+		case id == "":
+			id = DefaultID
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
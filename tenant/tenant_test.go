@@ -0,0 +1,53 @@
+package tenant
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-inventory/auth"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(FromContext(r.Context())))
+	}))
+	tests := []struct {
+		name      string
+		header    string
+		principal *auth.Principal
+		code      int
+		tenant    string
+	}{
+		{name: "Default", code: http.StatusOK, tenant: DefaultID},
+		{name: "Anonymous header", header: "acme", code: http.StatusForbidden},
+		{name: "Anonymous default", header: DefaultID, code: http.StatusOK, tenant: DefaultID},
+		{name: "Invalid header", header: "a c m e", code: http.StatusBadRequest},
+		{name: "Principal", principal: &auth.Principal{ID: "k", Tenant: "globex"}, code: http.StatusOK, tenant: "globex"},
+		{name: "Matching", header: "globex", principal: &auth.Principal{ID: "k", Tenant: "globex"}, code: http.StatusOK, tenant: "globex"},
+		{name: "Mismatch", header: "acme", principal: &auth.Principal{ID: "k", Tenant: "globex"}, code: http.StatusForbidden},
+		{name: "Unbound principal", header: "acme", principal: &auth.Principal{ID: "k"}, code: http.StatusForbidden},
+		{name: "Unbound principal default", principal: &auth.Principal{ID: "k"}, code: http.StatusOK, tenant: DefaultID},
+		{name: "Switching", header: "acme", principal: &auth.Principal{ID: "k", Tenant: auth.AnyTenant}, code: http.StatusOK, tenant: "acme"},
+		{name: "Switching default", principal: &auth.Principal{ID: "k", Tenant: auth.AnyTenant}, code: http.StatusOK, tenant: DefaultID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(Header, tt.header)
+			}
+			if tt.principal != nil {
+				req = req.WithContext(auth.NewContext(req.Context(), tt.principal))
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(t, tt.code, w.Code)
+			if tt.code == http.StatusOK {
+				assert.Equal(t, tt.tenant, w.Body.String())
+			}
+		})
+	}
+}
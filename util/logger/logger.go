@@ -32,6 +32,11 @@ func NewConsole(isDebug bool) *Logger {
 	return &Logger{logger: &logger}
 }
 
+// From wraps an existing zerolog logger.
+func From(l zerolog.Logger) *Logger {
+	return &Logger{logger: &l}
+}
+
 // Output duplicates the global logger and sets w as its output.
 func (l *Logger) Output(w io.Writer) zerolog.Logger {
 	return l.logger.Output(w)
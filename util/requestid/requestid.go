@@ -0,0 +1,44 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+
+	"go-inventory/errors"
+)
+
+// Header request and response header holding the request id
+const Header = errors.RequestIDHeader
+
+// ids accepted from callers
+var validID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+type contextKey struct{}
+
+// FromContext returns the request id of ctx
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Middleware reuses the caller's `X-Request-ID` or generates one,
+// and echoes it on the response
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !validID.MatchString(id) {
+			id = generate()
+		}
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, id)))
+	})
+}
+
+func generate() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
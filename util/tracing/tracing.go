@@ -0,0 +1,180 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"go-inventory/util/logger"
+	"go-inventory/util/requestid"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName name of the tracer used by the api
+const TracerName = "go-inventory"
+
+// Supported exporters
+const (
+	// ExporterNone disable tracing
+	ExporterNone = "none"
+	// ExporterStdout pretty print spans to stdout
+	ExporterStdout = "stdout"
+	// ExporterOTLP send spans to an OTLP/HTTP collector
+	ExporterOTLP = "otlp"
+	// ExporterMemory keep spans in memory, used in tests
+	ExporterMemory = "memory"
+)
+
+// Config configuration of the tracer provider
+type Config struct {
+	// one of the Exporter* constants
+	Exporter string
+	// OTLP collector endpoint, e.g "localhost:4318"
+	Endpoint string
+}
+
+// Tracer returns the api tracer from the global provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// NewExporter returns the span exporter described by cfg,
+// nil is returned for ExporterNone
+func NewExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithInsecure()}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		return otlptracehttp.New(ctx, opts...)
+	case ExporterMemory:
+		return tracetest.NewInMemoryExporter(), nil
+	}
+	return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+}
+
+// Setup installs a global tracer provider exporting the spans of
+// serviceName to exp, and the W3C trace context propagator.
+// The returned function flushes and stops the provider.
+func Setup(serviceName string, exp sdktrace.SpanExporter) func(context.Context) error {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	if exp == nil {
+		return func(context.Context) error { return nil }
+	}
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceNameKey.String(serviceName))),
+	}
+	if _, ok := exp.(*tracetest.InMemoryExporter); ok {
+		// export synchronously so tests can inspect spans right away
+		opts = append(opts, sdktrace.WithSyncer(exp))
+	} else {
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	}
+	tp := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown
+}
+
+// Middleware starts a span for each incoming request, named after the
+// matched mux route, continuing any trace propagated by the caller.
+// The request is logged with its trace id once served.
+func Middleware(log *logger.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			name, route := routeName(r)
+			ctx, span := Tracer().Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPMethodKey.String(r.Method),
+					semconv.HTTPRouteKey.String(route),
+					semconv.HTTPTargetKey.String(r.URL.RequestURI()),
+				),
+			)
+			defer span.End()
+
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPStatusCodeKey.Int(sw.status))
+			if sw.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(sw.status))
+			}
+			if log != nil {
+				WithTrace(ctx, log).Info().
+					Str("method", r.Method).
+					Str("route", route).
+					Str("request_id", requestid.FromContext(ctx)).
+					Int("status", sw.status).
+					Dur("duration", time.Since(start)).
+					Msg(name)
+			}
+		})
+	}
+}
+
+// WithTrace returns a child logger carrying the trace and span ids of ctx
+func WithTrace(ctx context.Context, log *logger.Logger) *logger.Logger {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return log
+	}
+	l := log.With().
+		Str("trace_id", sc.TraceID().String()).
+		Str("span_id", sc.SpanID().String()).
+		Logger()
+	return logger.From(l)
+}
+
+// routeName returns the span name and the path template of the current route
+func routeName(r *http.Request) (string, string) {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return r.Method + " " + r.URL.Path, r.URL.Path
+	}
+	tpl, _ := route.GetPathTemplate()
+	if name := route.GetName(); name != "" {
+		return name, tpl
+	}
+	return r.Method + " " + tpl, tpl
+}
+
+// statusWriter records the status code written by the handler
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush lets streaming handlers flush through the wrapper
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
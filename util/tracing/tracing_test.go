@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestMiddleware(t *testing.T) {
+	exp, err := NewExporter(context.Background(), Config{Exporter: ExporterMemory})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := exp.(*tracetest.InMemoryExporter)
+	shutdown := Setup("test", exp)
+	defer func() { _ = shutdown(context.Background()) }()
+
+	router := mux.NewRouter()
+	router.Use(Middleware(nil))
+	router.HandleFunc("/stock/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}).Name("GetStock")
+	router.HandleFunc("/stocks", func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name        string
+		path        string
+		traceparent string
+		span        string
+		traceID     string
+	}{
+		{
+			name: "Named",
+			path: "/stock/1",
+			span: "GetStock",
+		},
+		{
+			name: "Unnamed",
+			path: "/stocks",
+			span: "GET /stocks",
+		},
+		{
+			name:        "Propagated",
+			path:        "/stock/2",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			span:        "GetStock",
+			traceID:     "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mem.Reset()
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+			spans := mem.GetSpans()
+			if !assert.Len(t, spans, 1) {
+				return
+			}
+			assert.Equal(t, tt.span, spans[0].Name)
+			if tt.traceID != "" {
+				assert.Equal(t, tt.traceID, spans[0].SpanContext.TraceID().String())
+				assert.True(t, spans[0].Parent.IsRemote())
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go-inventory/errors"
+)
+
+// Tag struct tag holding the rules of a field, e.g `validate:"required,gt=0"`
+const Tag = "validate"
+
+// Supported rules
+const (
+	// non zero value, non blank string
+	RuleRequired = "required"
+	// number strictly greater than the argument
+	RuleGt = "gt"
+	// number greater than or equal to the argument
+	RuleGte = "gte"
+	// number less than or equal to the argument
+	RuleLte = "lte"
+	// string of at most argument characters, slice of at most argument items
+	RuleMaxLen = "maxlen"
+	// empty or a stock keeping unit: letters, digits, '-', '_', '.' and '/'
+	RuleSKU = "sku"
+	// empty or a GTIN-8, UPC-A, EAN-13 or GTIN-14 with a valid check digit
+	RuleGTIN = "gtin"
+	// items of a slice are left to the caller, e.g to report them one by one
+	RuleNoDive = "nodive"
+)
+
+type rule struct {
+	name string
+	arg  float64
+}
+
+type field struct {
+	index []int
+	// json name of the field
+	name  string
+	rules []rule
+	// nested struct to validate, pointer or value
+	nested bool
+	// slice or array of nested structs to validate
+	items bool
+}
+
+// fields of each validated type
+var cache sync.Map
+
+// Validate checks v, a struct or a pointer to one, against the rules of its
+// tags and returns errors.ErrValidation listing every violation, nil if none
+func Validate(v interface{}) error {
+	violations := collect(reflect.ValueOf(v), "")
+	if len(violations) == 0 {
+		return nil
+	}
+	err := errors.ErrValidation
+	for _, f := range violations {
+		err = err.WithField(f.Field, f.Code, f.Message)
+	}
+	return err
+}
+
+func collect(v reflect.Value, prefix string) []errors.FieldError {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	var res []errors.FieldError
+	for _, f := range fieldsOf(v.Type()) {
+		fv := v.FieldByIndex(f.index)
+		name := prefix + f.name
+		for _, r := range f.rules {
+			if msg, ok := check(r, fv); !ok {
+				res = append(res, errors.FieldError{Field: name, Code: r.name, Message: msg})
+				if r.name == RuleRequired {
+					// other rules would only repeat it
+					break
+				}
+			}
+		}
+		if f.nested {
+			res = append(res, collect(fv, name+".")...)
+		}
+		if f.items {
+			for i := 0; i < fv.Len(); i++ {
+				res = append(res, collect(fv.Index(i), fmt.Sprintf("%s[%d].", name, i))...)
+			}
+		}
+	}
+	return res
+}
+
+func fieldsOf(t reflect.Type) []field {
+	if cached, ok := cache.Load(t); ok {
+		return cached.([]field)
+	}
+	var res []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		f := field{index: sf.Index, name: jsonName(sf)}
+		dive := true
+		if tag := sf.Tag.Get(Tag); tag != "" {
+			for _, part := range strings.Split(tag, ",") {
+				r := parseRule(t, sf, part)
+				if r.name == RuleNoDive {
+					dive = false
+					continue
+				}
+				f.rules = append(f.rules, r)
+			}
+		}
+		f.nested = isStruct(sf.Type)
+		if k := sf.Type.Kind(); k == reflect.Slice || k == reflect.Array {
+			f.items = dive && isStruct(sf.Type.Elem())
+		}
+		if len(f.rules) > 0 || f.nested || f.items {
+			res = append(res, f)
+		}
+	}
+	cache.Store(t, res)
+	return res
+}
+
+// isStruct reports whether t is a struct, or a pointer to one, to validate
+func isStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t.PkgPath() != "time"
+}
+
+func parseRule(t reflect.Type, sf reflect.StructField, part string) rule {
+	name, arg, hasArg := strings.Cut(strings.TrimSpace(part), "=")
+	r := rule{name: name}
+	switch name {
+	case RuleRequired, RuleSKU, RuleGTIN, RuleNoDive:
+		return r
+	case RuleGt, RuleGte, RuleLte, RuleMaxLen:
+		n, err := strconv.ParseFloat(arg, 64)
+		if hasArg && err == nil {
+			r.arg = n
+			return r
+		}
+	}
+	// tags are static, a broken one is a programming error
+	panic(fmt.Sprintf("validation: invalid rule %q on %s.%s", part, t.Name(), sf.Name))
+}
+
+// check returns the violation message of r on v, ok when v satisfies it
+func check(r rule, v reflect.Value) (string, bool) {
+	switch r.name {
+	case RuleRequired:
+		switch v.Kind() {
+		case reflect.String:
+			return "is required", strings.TrimSpace(v.String()) != ""
+		case reflect.Slice, reflect.Map:
+			return "is required", v.Len() > 0
+		}
+		return "is required", !v.IsZero()
+	case RuleMaxLen:
+		if v.Kind() == reflect.Slice {
+			return fmt.Sprintf("must have at most %v items", r.arg), float64(v.Len()) <= r.arg
+		}
+		return fmt.Sprintf("must be at most %v characters", r.arg), float64(len([]rune(v.String()))) <= r.arg
+	case RuleSKU:
+		return "must be a sku of at most 64 letters, digits, '-', '_', '.' or '/'", v.String() == "" || ValidSKU(v.String())
+	case RuleGTIN:
+		return "must be a GTIN-8, UPC-A, EAN-13 or GTIN-14 with a valid check digit", v.String() == "" || ValidGTIN(v.String())
+	}
+	n, ok := number(v)
+	if !ok {
+		return "", true
+	}
+	switch r.name {
+	case RuleGt:
+		return fmt.Sprintf("must be greater than %s", decimal(r.arg)), n > r.arg
+	case RuleGte:
+		return fmt.Sprintf("must be greater than or equal to %s", decimal(r.arg)), n >= r.arg
+	case RuleLte:
+		return fmt.Sprintf("must be less than or equal to %s", decimal(r.arg)), n <= r.arg
+	}
+	return "", true
+}
+
+// decimal formats the argument of a rule without exponent
+func decimal(arg float64) string {
+	return strconv.FormatFloat(arg, 'f', -1, 64)
+}
+
+func number(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func jsonName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return sf.Name
+	}
+	return name
+}
+
+// ValidSKU reports whether sku is 1 to 64 letters, digits, '-', '_', '.' or '/'
+func ValidSKU(sku string) bool {
+	if sku == "" || len(sku) > 64 {
+		return false
+	}
+	for _, c := range sku {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == '/':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ValidGTIN reports whether code is 8, 12, 13 or 14 digits ending with
+// their GS1 check digit
+func ValidGTIN(code string) bool {
+	switch len(code) {
+	case 8, 12, 13, 14:
+	default:
+		return false
+	}
+	sum := 0
+	for i := len(code) - 1; i >= 0; i-- {
+		d := int(code[i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+		// weights alternate 1 and 3 from the check digit leftwards
+		if (len(code)-1-i)%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,128 @@
+package validation
+
+import (
+	"testing"
+
+	"go-inventory/errors"
+	"go-inventory/objects"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     interface{}
+		fields []errors.FieldError
+	}{
+		{
+			name: "Valid stock",
+			in:   &objects.Stock{Name: "Meat Ball", Price: 100, Availability: 0},
+		},
+		{
+			name: "Invalid stock",
+			in:   &objects.Stock{Name: "  ", Price: -1, Availability: -5},
+			fields: []errors.FieldError{
+				{Field: "name", Code: RuleRequired, Message: "is required"},
+				{Field: "price", Code: RuleGt, Message: "must be greater than 0"},
+				{Field: "availability", Code: RuleGte, Message: "must be greater than or equal to 0"},
+			},
+		},
+		{
+			name: "Identifiers",
+			in:   &objects.Stock{Name: "Tee", Price: 1, SKU: "TEE RED", Barcode: "4006381333932"},
+			fields: []errors.FieldError{
+				{Field: "sku", Code: RuleSKU, Message: "must be a sku of at most 64 letters, digits, '-', '_', '.' or '/'"},
+				{Field: "barcode", Code: RuleGTIN, Message: "must be a GTIN-8, UPC-A, EAN-13 or GTIN-14 with a valid check digit"},
+			},
+		},
+		{
+			name: "Update",
+			in:   &objects.UpdateDetailsRequest{ID: "1", Name: "Tee", Price: 0, Availability: -1},
+			fields: []errors.FieldError{
+				{Field: "price", Code: RuleGt, Message: "must be greater than 0"},
+				{Field: "availability", Code: RuleGte, Message: "must be greater than or equal to 0"},
+			},
+		},
+		{
+			name: "Nested",
+			in:   &objects.CreateRequest{Stock: &objects.Stock{Name: "Tee"}},
+			fields: []errors.FieldError{
+				{Field: "Stock.price", Code: RuleGt, Message: "must be greater than 0"},
+			},
+		},
+		{
+			name: "Missing nested",
+			in:   &objects.CreateRequest{},
+			fields: []errors.FieldError{
+				{Field: "Stock", Code: RuleRequired, Message: "is required"},
+			},
+		},
+		{
+			name: "Nested lines",
+			in: &objects.CreateSalesOrderRequest{Lines: []*objects.SalesOrderLineRequest{
+				{StockID: "1", Quantity: 1},
+				nil,
+				{StockID: "", Quantity: -5},
+			}},
+			fields: []errors.FieldError{
+				{Field: "lines[2].stock_id", Code: RuleRequired, Message: "is required"},
+				{Field: "lines[2].quantity", Code: RuleGt, Message: "must be greater than 0"},
+			},
+		},
+		{
+			name: "Batch items left to the caller",
+			in:   &objects.BatchUpdateRequest{Items: []*objects.UpdateDetailsRequest{{ID: "1", Price: -1}}},
+		},
+		{
+			name: "Negative limit",
+			in:   &objects.ListRequest{Limit: -1},
+			fields: []errors.FieldError{
+				{Field: "limit", Code: RuleGte, Message: "must be greater than or equal to 0"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.in)
+			if tt.fields == nil {
+				assert.Nil(t, err)
+				return
+			}
+			got, ok := err.(*errors.Error)
+			if assert.True(t, ok) {
+				assert.Equal(t, errors.ErrValidation.ErrorCode, got.ErrorCode)
+				assert.Equal(t, tt.fields, got.Errors)
+			}
+		})
+	}
+}
+
+func TestInvalidRule(t *testing.T) {
+	assert.Panics(t, func() {
+		_ = Validate(struct {
+			N int `validate:"gt=x"`
+		}{})
+	})
+}
+
+func TestValidGTIN(t *testing.T) {
+	tests := []struct {
+		code  string
+		valid bool
+	}{
+		{code: "96385074", valid: true},
+		{code: "036000291452", valid: true},
+		{code: "4006381333931", valid: true},
+		{code: "10012345678902", valid: true},
+		{code: "4006381333932"},
+		{code: "400638133393"},
+		{code: "40063813339a1"},
+		{code: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			assert.Equal(t, tt.valid, ValidGTIN(tt.code))
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-inventory/objects"
+	"go-inventory/store"
+	"go-inventory/util/logger"
+)
+
+// Headers of a delivery request
+const (
+	HeaderID        = "X-Webhook-ID"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// `sha256=` followed by the hex HMAC-SHA256 of `<timestamp>.<body>`
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Config of a Dispatcher, zero values are replaced by the defaults
+type Config struct {
+	// how often due deliveries are claimed
+	PollInterval time.Duration
+	// deliveries claimed at once
+	BatchSize int
+	// attempts before a delivery is dead
+	MaxAttempts int
+	// delay before the first retry, doubled on each following one
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// timeout of a single attempt
+	Timeout time.Duration
+}
+
+// DefaultConfig retries for about 21 minutes before giving up
+var DefaultConfig = Config{
+	PollInterval: time.Second,
+	BatchSize:    50,
+	MaxAttempts:  8,
+	BaseBackoff:  10 * time.Second,
+	MaxBackoff:   time.Hour,
+	Timeout:      10 * time.Second,
+}
+
+// Dispatcher posts the pending deliveries of a store to their subscribers
+type Dispatcher struct {
+	store  store.IWebhookStore
+	client *http.Client
+	cfg    Config
+	log    *logger.Logger
+	now    func() time.Time
+}
+
+// NewDispatcher returns a Dispatcher of the deliveries of st
+func NewDispatcher(st store.IWebhookStore, cfg Config, log *logger.Logger) *Dispatcher {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultConfig.PollInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultConfig.BatchSize
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultConfig.MaxAttempts
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = DefaultConfig.BaseBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultConfig.MaxBackoff
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultConfig.Timeout
+	}
+	return &Dispatcher{
+		store:  st,
+		client: &http.Client{Timeout: cfg.Timeout},
+		cfg:    cfg,
+		log:    log,
+		now:    time.Now,
+	}
+}
+
+// Run dispatches deliveries until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.Dispatch(ctx); err != nil {
+			d.log.Error().Err(err).Msg("webhook dispatch failed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch attempts the due deliveries once and returns how many were attempted
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	// a claim outlives the attempts so that no other dispatcher picks it up meanwhile,
+	// they are made concurrently under a single timeout
+	list, err := d.store.ClaimDeliveries(ctx, d.now(), 2*d.cfg.Timeout, d.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	postCtx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, delivery := range list {
+		wg.Add(1)
+		go func(delivery *objects.WebhookDelivery) {
+			defer wg.Done()
+			d.attempt(postCtx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+	// a failed save leaves the delivery to be claimed again once its lease ran out
+	for _, delivery := range list {
+		if err := d.store.SaveDelivery(ctx, delivery); err != nil {
+			d.log.Error().Err(err).Str("delivery_id", delivery.ID).Msg("webhook delivery not saved")
+		}
+	}
+	return len(list), nil
+}
+
+// attempt posts delivery and records the outcome on it
+func (d *Dispatcher) attempt(ctx context.Context, delivery *objects.WebhookDelivery) {
+	delivery.Attempts++
+	err := d.post(ctx, delivery)
+	if err == nil {
+		delivery.Status = objects.DeliveryDelivered
+		delivery.LastError = ""
+		return
+	}
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= d.cfg.MaxAttempts {
+		delivery.Status = objects.DeliveryDead
+		d.log.Warn().
+			Str("delivery_id", delivery.ID).
+			Str("subscription_id", delivery.SubscriptionID).
+			Err(err).
+			Msg("webhook delivery is dead")
+		return
+	}
+	delivery.NextAttemptOn = d.now().Add(Backoff(d.cfg, delivery.Attempts))
+}
+
+func (d *Dispatcher) post(ctx context.Context, delivery *objects.WebhookDelivery) error {
+	sub := delivery.Subscription
+	if sub == nil {
+		return fmt.Errorf("subscription %s not found", delivery.SubscriptionID)
+	}
+	ts := strconv.FormatInt(d.now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderID, delivery.ID)
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, ts, []byte(delivery.Payload)))
+	res, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("subscriber responded %s", res.Status)
+	}
+	return nil
+}
+
+// Backoff returns the delay after attempt failed attempts
+func Backoff(cfg Config, attempt int) time.Duration {
+	delay := cfg.BaseBackoff
+	for i := 1; i < attempt && delay < cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > cfg.MaxBackoff {
+		delay = cfg.MaxBackoff
+	}
+	return delay
+}
+
+// Sign returns the signature header value of body sent at timestamp
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the one of body sent at timestamp,
+// for subscribers written in go
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
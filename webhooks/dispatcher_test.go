@@ -0,0 +1,128 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-inventory/objects"
+	"go-inventory/store"
+	"go-inventory/util/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatch(t *testing.T) {
+	var (
+		fail, calls int32
+		lastSig     atomic.Value
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		body, _ := io.ReadAll(r.Body)
+		lastSig.Store(Verify("s3cret", r.Header.Get(HeaderTimestamp), body, r.Header.Get(HeaderSignature)))
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	stores := store.NewMemoryStores()
+	ctx := context.Background()
+	sub := &objects.WebhookSubscription{URL: srv.URL, Secret: "s3cret", Events: []string{objects.EventStockCreated}}
+	assert.Nil(t, stores.Webhooks.CreateWebhook(ctx, sub))
+	assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: &objects.Stock{Name: "a", Price: 1}}))
+
+	now := time.Now()
+	d := NewDispatcher(stores.Webhooks, Config{MaxAttempts: 2, BaseBackoff: time.Minute}, logger.New(false))
+	d.now = func() time.Time { return now }
+
+	// delivered
+	n, err := d.Dispatch(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, true, lastSig.Load())
+	list, _ := stores.Webhooks.ListDeliveries(ctx, &objects.ListDeliveriesRequest{Status: objects.DeliveryDelivered})
+	assert.Len(t, list, 1)
+
+	// retried after the backoff, then dead
+	atomic.StoreInt32(&fail, 1)
+	assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: &objects.Stock{Name: "b", Price: 1}}))
+	now = time.Now()
+	n, _ = d.Dispatch(ctx)
+	assert.Equal(t, 1, n)
+	n, _ = d.Dispatch(ctx)
+	assert.Equal(t, 0, n)
+	now = now.Add(time.Minute)
+	n, _ = d.Dispatch(ctx)
+	assert.Equal(t, 1, n)
+	dead, _ := stores.Webhooks.ListDeliveries(ctx, &objects.ListDeliveriesRequest{Status: objects.DeliveryDead})
+	if assert.Len(t, dead, 1) {
+		assert.Equal(t, 2, dead[0].Attempts)
+		assert.Contains(t, dead[0].LastError, "503")
+	}
+
+	// redelivered by hand with a new retry budget
+	redelivered, err := stores.Webhooks.Redeliver(ctx, &objects.RedeliverRequest{ID: dead[0].ID})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, redelivered.Attempts)
+	assert.Empty(t, redelivered.LastError)
+	d.now = time.Now
+	n, _ = d.Dispatch(ctx)
+	assert.Equal(t, 1, n)
+	dead, _ = stores.Webhooks.ListDeliveries(ctx, &objects.ListDeliveriesRequest{Status: objects.DeliveryDead})
+	assert.Empty(t, dead)
+
+	atomic.StoreInt32(&fail, 0)
+	d.now = func() time.Time { return time.Now().Add(time.Minute) }
+	n, _ = d.Dispatch(ctx)
+	assert.Equal(t, 1, n)
+	list, _ = stores.Webhooks.ListDeliveries(ctx, &objects.ListDeliveriesRequest{Status: objects.DeliveryDelivered})
+	assert.Len(t, list, 2)
+	assert.Equal(t, int32(5), atomic.LoadInt32(&calls))
+}
+
+func TestBackoff(t *testing.T) {
+	cfg := Config{BaseBackoff: 10 * time.Second, MaxBackoff: time.Minute}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{4, time.Minute},
+		{20, time.Minute},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Backoff(cfg, tt.attempt))
+	}
+}
+
+func TestDispatchBatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	stores := store.NewMemoryStores()
+	ctx := context.Background()
+	sub := &objects.WebhookSubscription{URL: srv.URL, Secret: "s3cret", Events: []string{objects.EventStockCreated}}
+	assert.Nil(t, stores.Webhooks.CreateWebhook(ctx, sub))
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		assert.Nil(t, stores.Stocks.Create(ctx, &objects.CreateRequest{Stock: &objects.Stock{Name: name, Price: 1}}))
+	}
+
+	// the batch is posted within the timeout of a single attempt
+	d := NewDispatcher(stores.Webhooks, Config{Timeout: 500 * time.Millisecond}, logger.New(false))
+	n, err := d.Dispatch(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, n)
+	list, _ := stores.Webhooks.ListDeliveries(ctx, &objects.ListDeliveriesRequest{Status: objects.DeliveryDelivered})
+	assert.Len(t, list, 5)
+}